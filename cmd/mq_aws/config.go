@@ -33,16 +33,21 @@ type ConfigYCloudwatch struct {
 }
 
 type mqCloudWatchConfig struct {
-	cf cf.Config
-	ci ConfigYCloudwatch
+	cf    cf.Config
+	qmgrs []*cf.Config
+	ci    ConfigYCloudwatch
 }
 
 type mqExporterConfigYaml struct {
-	Global     cf.ConfigYGlobal
-	Connection cf.ConfigYConnection
-	Objects    cf.ConfigYObjects
-	Filters    cf.ConfigYFilters
-	Cloudwatch ConfigYCloudwatch `yaml:"cloudwatch"`
+	Global         cf.ConfigYGlobal
+	Connection     cf.ConfigYConnection
+	Objects        cf.ConfigYObjects
+	Filters        cf.ConfigYFilters
+	Health         cf.ConfigYHealth         `yaml:"health"`
+	RelabelConfigs []cf.ConfigYRelabel      `yaml:"relabelConfigs"`
+	ObjectLabels   []cf.ConfigYObjectLabel  `yaml:"objectLabels"`
+	Cloudwatch     ConfigYCloudwatch        `yaml:"cloudwatch"`
+	QueueManagers  []cf.ConfigYQueueManager `yaml:"queueManagers"`
 }
 
 var config mqCloudWatchConfig
@@ -71,6 +76,9 @@ func initConfig() error {
 			err = cf.ReadConfigFile(config.cf.ConfigFile, &cfy)
 			if err == nil {
 				cf.CopyYamlConfig(&config.cf, cfy.Global, cfy.Connection, cfy.Objects, cfy.Filters)
+				cf.CopyYamlHealthConfig(&config.cf, cfy.Health)
+				cf.CopyYamlRelabelConfig(&config.cf, cfy.RelabelConfigs)
+				cf.CopyYamlObjectLabelConfig(&config.cf, cfy.ObjectLabels)
 				config.ci.Region = cf.CopyParmIfNotSetStr("cloudwatch", "awsregion", cfy.Cloudwatch.Region)
 				config.ci.Namespace = cf.CopyParmIfNotSetStr("cloudwatch", "namespace", cfy.Cloudwatch.Namespace)
 
@@ -103,6 +111,11 @@ func initConfig() error {
 		}
 	}
 
+	// Each queue manager that is to be monitored inherits the main configuration
+	if err == nil {
+		config.qmgrs, err = cf.QueueManagerConfigs(&config.cf, cfy.QueueManagers)
+	}
+
 	if err == nil && config.cf.CC.UseResetQStats {
 		log.Errorln("Warning: Data from 'RESET QSTATS' has been requested.")
 		log.Errorln("Ensure no other monitoring applications are also using that command.")
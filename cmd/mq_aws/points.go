@@ -22,12 +22,12 @@ import (
 	"errors"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
 	_ "github.com/sirupsen/logrus"
-	"strings"
 	"time"
 )
 
-func newPoint(metric string, timestamp time.Time, value float64, tags map[string]string) (*cloudwatch.MetricDatum, error) {
+func newPoint(metric string, timestamp time.Time, value float64, unit string, tags map[string]string) (*cloudwatch.MetricDatum, error) {
 	var dl []*cloudwatch.Dimension
 
 	if metric == "" {
@@ -61,18 +61,21 @@ func newPoint(metric string, timestamp time.Time, value float64, tags map[string
 		dl = append(dl, &dim)
 	}
 
-	unit := aws.String("None")
-	if strings.HasSuffix(metric, "_seconds") {
-		unit = aws.String("Seconds")
-	} else if strings.HasSuffix(metric, "_bytes") {
-		unit = aws.String("Bytes")
+	cwUnit := aws.String(cloudwatch.StandardUnitNone)
+	switch unit {
+	case pipeline.UnitSeconds:
+		cwUnit = aws.String(cloudwatch.StandardUnitSeconds)
+	case pipeline.UnitBytes:
+		cwUnit = aws.String(cloudwatch.StandardUnitBytes)
+	case pipeline.UnitPercent:
+		cwUnit = aws.String(cloudwatch.StandardUnitPercent)
 	}
 
 	return &cloudwatch.MetricDatum{
 		Dimensions: dl,
 		MetricName: aws.String(metric),
 		Timestamp:  aws.Time(timestamp),
-		Unit:       unit,
+		Unit:       cwUnit,
 		Value:      aws.Float64(value),
 	}, nil
 }
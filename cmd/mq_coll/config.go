@@ -25,7 +25,8 @@ import (
 )
 
 type mqTTYConfig struct {
-	cf cf.Config
+	cf    cf.Config
+	qmgrs []*cf.Config
 
 	hostname  string
 	hostlabel string // Used in the output string
@@ -38,11 +39,15 @@ type ConfigYColl struct {
 }
 
 type mqExporterConfigYaml struct {
-	Global     cf.ConfigYGlobal
-	Connection cf.ConfigYConnection
-	Objects    cf.ConfigYObjects
-	Filters    cf.ConfigYFilters
-	Collectd   ConfigYColl `yaml:"collectd"`
+	Global         cf.ConfigYGlobal
+	Connection     cf.ConfigYConnection
+	Objects        cf.ConfigYObjects
+	Filters        cf.ConfigYFilters
+	Health         cf.ConfigYHealth         `yaml:"health"`
+	RelabelConfigs []cf.ConfigYRelabel      `yaml:"relabelConfigs"`
+	ObjectLabels   []cf.ConfigYObjectLabel  `yaml:"objectLabels"`
+	Collectd       ConfigYColl              `yaml:"collectd"`
+	QueueManagers  []cf.ConfigYQueueManager `yaml:"queueManagers"`
 }
 
 var config mqTTYConfig
@@ -65,6 +70,9 @@ func initConfig() error {
 			err = cf.ReadConfigFile(config.cf.ConfigFile, &cfy)
 			if err == nil {
 				cf.CopyYamlConfig(&config.cf, cfy.Global, cfy.Connection, cfy.Objects, cfy.Filters)
+				cf.CopyYamlHealthConfig(&config.cf, cfy.Health)
+				cf.CopyYamlRelabelConfig(&config.cf, cfy.RelabelConfigs)
+				cf.CopyYamlObjectLabelConfig(&config.cf, cfy.ObjectLabels)
 				config.interval = cf.CopyParmIfNotSetStr("collectd", "interval", cfy.Collectd.Interval)
 				config.hostname = cf.CopyParmIfNotSetStr("collectd", "hostname", cfy.Collectd.Hostname)
 			}
@@ -89,6 +97,11 @@ func initConfig() error {
 		}
 	}
 
+	// Each queue manager that is to be monitored inherits the main configuration
+	if err == nil {
+		config.qmgrs, err = cf.QueueManagerConfigs(&config.cf, cfy.QueueManagers)
+	}
+
 	// Don't want to use "localhost" as the tag in the metric printing
 	if config.hostname == "localhost" || config.hostname == "" {
 		config.hostlabel, _ = os.Hostname()
@@ -42,16 +42,21 @@ type ConfigYInflux struct {
 }
 
 type mqInfluxConfig struct {
-	cf cf.Config
-	ci ConfigYInflux
+	cf    cf.Config
+	qmgrs []*cf.Config
+	ci    ConfigYInflux
 }
 
 type mqExporterConfigYaml struct {
-	Global     cf.ConfigYGlobal
-	Connection cf.ConfigYConnection
-	Objects    cf.ConfigYObjects
-	Filters    cf.ConfigYFilters
-	Influx     ConfigYInflux `yaml:"influx"`
+	Global         cf.ConfigYGlobal
+	Connection     cf.ConfigYConnection
+	Objects        cf.ConfigYObjects
+	Filters        cf.ConfigYFilters
+	Health         cf.ConfigYHealth         `yaml:"health"`
+	RelabelConfigs []cf.ConfigYRelabel      `yaml:"relabelConfigs"`
+	ObjectLabels   []cf.ConfigYObjectLabel  `yaml:"objectLabels"`
+	Influx         ConfigYInflux            `yaml:"influx"`
+	QueueManagers  []cf.ConfigYQueueManager `yaml:"queueManagers"`
 }
 
 var config mqInfluxConfig
@@ -82,6 +87,9 @@ func initConfig() error {
 			err = cf.ReadConfigFile(config.cf.ConfigFile, &cfy)
 			if err == nil {
 				cf.CopyYamlConfig(&config.cf, cfy.Global, cfy.Connection, cfy.Objects, cfy.Filters)
+				cf.CopyYamlHealthConfig(&config.cf, cfy.Health)
+				cf.CopyYamlRelabelConfig(&config.cf, cfy.RelabelConfigs)
+				cf.CopyYamlObjectLabelConfig(&config.cf, cfy.ObjectLabels)
 				config.ci.BucketName = cf.CopyParmIfNotSetStr("influx", "bucketName", cfy.Influx.BucketName)
 				//config.ci.DatabaseName = cf.CopyParmIfNotSetStr("influx", "databaseName", cfy.Influx.DatabaseName)
 				config.ci.DatabaseAddress = cf.CopyParmIfNotSetStr("influx", "databaseAddress", cfy.Influx.DatabaseAddress)
@@ -117,6 +125,11 @@ func initConfig() error {
 		}
 	}
 
+	// Each queue manager that is to be monitored inherits the main configuration
+	if err == nil {
+		config.qmgrs, err = cf.QueueManagerConfigs(&config.cf, cfy.QueueManagers)
+	}
+
 	// Process password for Influx connection.
 	// Read password from a file if there is a userid on the command line.
 
@@ -20,12 +20,13 @@ package main
 
 import (
 	"os"
-	"strings"
 	"time"
 
-	"github.com/ibm-messaging/mq-golang/v5/ibmmq"
-	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
 	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	errors "github.com/ibm-messaging/mq-metric-samples/v5/pkg/errors"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/health"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/reload"
 	client "github.com/influxdata/influxdb-client-go/v2"
 	ilog "github.com/influxdata/influxdb-client-go/v2/log"
 
@@ -35,12 +36,13 @@ import (
 var BuildStamp string
 var GitCommit string
 var BuildPlatform string
-var discoverConfig mqmetric.DiscoverConfig
+var group *pipeline.Group
 
 func main() {
 	var err error
 	var c client.Client
 	var d time.Duration
+	var token string
 
 	cf.PrintInfo("IBM MQ metrics exporter for InfluxDB monitoring", BuildStamp, GitCommit, BuildPlatform)
 
@@ -59,85 +61,45 @@ func main() {
 			os.Exit(1)
 		}
 
-		// Connect and open standard queues
-		err = mqmetric.InitConnection(config.cf.QMgrName, config.cf.ReplyQ, config.cf.ReplyQ2, &config.cf.CC)
-	}
-	if err == nil {
-		if config.cf.QMgrName == "" || strings.HasPrefix(config.cf.QMgrName, "*") {
-			qmName := mqmetric.GetResolvedQMgrName()
-			log.Infoln("Resolving blank/default qmgr name to ", qmName)
-			config.cf.QMgrName = qmName
-		}
-		log.Infoln("Connected to queue manager ", config.cf.QMgrName)
-	} else {
-		if mqe, ok := err.(mqmetric.MQMetricError); ok {
-			mqrc := mqe.MQReturn.MQRC
-			mqcc := mqe.MQReturn.MQCC
-			if mqrc == ibmmq.MQRC_STANDBY_Q_MGR {
-				log.Errorln(err)
-				os.Exit(30) // This is the same as the strmqm return code for "active instance running elsewhere"
-			} else if mqcc == ibmmq.MQCC_WARNING {
-				log.Infoln("Connected to queue manager ", config.cf.QMgrName)
-				// Report the error but allow it to continue
-				log.Errorln(err)
-				err = nil
-			}
-		}
-	}
-
-	if err == nil {
-		defer mqmetric.EndConnection()
-	}
-
-	// What metrics can the queue manager provide? Find out, and
-	// subscribe.
-
-	if err == nil {
-		discoverConfig.MonitoredQueues.ObjectNames = config.cf.MonitoredQueues
-		discoverConfig.MonitoredQueues.UseWildcard = true
-		discoverConfig.MonitoredQueues.SubscriptionSelector = strings.ToUpper(config.cf.QueueSubscriptionSelector)
-		discoverConfig.MetaPrefix = config.cf.MetaPrefix
-		err = mqmetric.DiscoverAndSubscribe(discoverConfig)
-		mqmetric.RediscoverAttributes(ibmmq.MQOT_CHANNEL, config.cf.MonitoredChannels)
-		mqmetric.RediscoverAttributes(mqmetric.OT_CHANNEL_AMQP, config.cf.MonitoredAMQPChannels)
+		cf.ExitIfPrinting(&config.cf, config.qmgrs)
+		cf.ExitIfChecking(&config.cf)
 
+		// Connect to each queue manager, and find out what metrics it can provide
+		group = pipeline.NewGroup(config.qmgrs)
+		health.Start(&config.cf, group, d)
+		reload.Start(&config.cf, group)
+		err = group.Connect()
 	}
-
-	if err == nil {
-		var compCode int32
-		compCode, err = mqmetric.VerifyConfig()
-		// We could choose to fail after a warning, but instead will continue for now
-		if compCode == ibmmq.MQCC_WARNING {
-			log.Println(err)
-			err = nil
-		}
+	if err != nil {
+		errors.ExitIfStandby(err)
 	}
 
 	if err == nil {
-		mqmetric.ChannelInitAttributes()
-		mqmetric.QueueInitAttributes()
-		mqmetric.TopicInitAttributes()
-		mqmetric.SubInitAttributes()
-		mqmetric.QueueManagerInitAttributes()
-		mqmetric.UsageInitAttributes()
-		mqmetric.ClusterInitAttributes()
-		mqmetric.ChannelAMQPInitAttributes()
-
+		defer group.Disconnect()
 	}
 
 	// Go into main loop for sending data to database
 	// Creating the client does not return an error; the error will
 	// come during the write of the data.
 	if err == nil {
-		if config.ci.ApiToken == "" {
-			config.ci.ApiToken = config.ci.Userid + ":" + config.ci.Password
-		}
-		c = client.NewClientWithOptions(config.ci.DatabaseAddress, config.ci.ApiToken,
-			client.DefaultOptions().SetPrecision(time.Millisecond))
-		defer c.Close()
+		token, err = influxToken()
+	}
+	if err == nil {
+		c = newInfluxClient(token)
+		defer func() { c.Close() }()
 		ilog.Log = nil
 		for {
 			Collect(c)
+
+			// Pick up any change to credentials that are kept elsewhere
+			if t, e := influxToken(); e != nil {
+				log.Errorf("Cannot get credentials for InfluxDB: %v", e)
+			} else if t != token {
+				log.Infof("Credentials for InfluxDB have changed")
+				c.Close()
+				token = t
+				c = newInfluxClient(token)
+			}
 			time.Sleep(d)
 		}
 
@@ -149,3 +111,18 @@ func main() {
 
 	os.Exit(0)
 }
+
+// The token, or the database password, can be a reference to a secret that's kept elsewhere.
+// It's looked up again once the secretTTL has passed.
+func influxToken() (string, error) {
+	if config.ci.ApiToken != "" {
+		return cf.ResolveSecret(config.ci.ApiToken, config.cf.SecretTTLDuration)
+	}
+	pw, err := cf.ResolveSecret(config.ci.Password, config.cf.SecretTTLDuration)
+	return config.ci.Userid + ":" + pw, err
+}
+
+func newInfluxClient(token string) client.Client {
+	return client.NewClientWithOptions(config.ci.DatabaseAddress, token,
+		client.DefaultOptions().SetPrecision(time.Millisecond))
+}
@@ -24,6 +24,7 @@ import (
 
 type mqTTYConfig struct {
 	cf        cf.Config
+	qmgrs     []*cf.Config
 	interval  string
 	oneline   bool
 	recordmax int
@@ -36,11 +37,15 @@ type ConfigYJson struct {
 }
 
 type mqExporterConfigYaml struct {
-	Global     cf.ConfigYGlobal
-	Connection cf.ConfigYConnection
-	Objects    cf.ConfigYObjects
-	Filters    cf.ConfigYFilters
-	JSON       ConfigYJson `yaml:"json"`
+	Global         cf.ConfigYGlobal
+	Connection     cf.ConfigYConnection
+	Objects        cf.ConfigYObjects
+	Filters        cf.ConfigYFilters
+	Health         cf.ConfigYHealth         `yaml:"health"`
+	RelabelConfigs []cf.ConfigYRelabel      `yaml:"relabelConfigs"`
+	ObjectLabels   []cf.ConfigYObjectLabel  `yaml:"objectLabels"`
+	JSON           ConfigYJson              `yaml:"json"`
+	QueueManagers  []cf.ConfigYQueueManager `yaml:"queueManagers"`
 }
 
 var config mqTTYConfig
@@ -65,6 +70,9 @@ func initConfig() error {
 			err = cf.ReadConfigFile(config.cf.ConfigFile, &cfy)
 			if err == nil {
 				cf.CopyYamlConfig(&config.cf, cfy.Global, cfy.Connection, cfy.Objects, cfy.Filters)
+				cf.CopyYamlHealthConfig(&config.cf, cfy.Health)
+				cf.CopyYamlRelabelConfig(&config.cf, cfy.RelabelConfigs)
+				cf.CopyYamlObjectLabelConfig(&config.cf, cfy.ObjectLabels)
 				config.interval = cf.CopyParmIfNotSetStr("json", "interval", cfy.JSON.Interval)
 				config.oneline = cf.CopyParmIfNotSetBool("json", "oneline", cfy.JSON.OneLine)
 				config.recordmax = cf.CopyParmIfNotSetInt("json", "recordmax", cfy.JSON.RecordMax)
@@ -90,6 +98,11 @@ func initConfig() error {
 		}
 	}
 
+	// Each queue manager that is to be monitored inherits the main configuration
+	if err == nil {
+		config.qmgrs, err = cf.QueueManagerConfigs(&config.cf, cfy.QueueManagers)
+	}
+
 	return err
 
 }
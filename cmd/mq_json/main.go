@@ -20,19 +20,21 @@ package main
 
 import (
 	"os"
-	"strings"
 	"time"
 
-	ibmmq "github.com/ibm-messaging/mq-golang/v5/ibmmq"
-	mqmetric "github.com/ibm-messaging/mq-golang/v5/mqmetric"
 	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	errors "github.com/ibm-messaging/mq-metric-samples/v5/pkg/errors"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/health"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/reload"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/sinks/jsonsink"
 	log "github.com/sirupsen/logrus"
 )
 
 var BuildStamp string
 var GitCommit string
 var BuildPlatform string
-var discoverConfig mqmetric.DiscoverConfig
+var group *pipeline.Group
 
 // Print this via the logger rather than direct to stdout so it can be
 // avoided if someone is using the stdout stream as the JSON input to a parser
@@ -59,7 +61,7 @@ func main() {
 
 	printInfo("Starting IBM MQ metrics exporter for JSON", BuildStamp, GitCommit, BuildPlatform)
 
-	if err == nil && config.cf.QMgrName == "" {
+	if err == nil && config.cf.QMgrName == "" && len(cfy.QueueManagers) == 0 {
 		log.Errorln("Must provide a queue manager name to connect to.")
 		os.Exit(72)
 	}
@@ -70,76 +72,32 @@ func main() {
 			os.Exit(1)
 		}
 
+		cf.ExitIfPrinting(&config.cf, config.qmgrs)
+		cf.ExitIfChecking(&config.cf)
+
 		if config.cf.CC.UseResetQStats {
 			log.Warnln("Warning: Data from 'RESET QSTATS' has been requested. Ensure no other monitoring applications are also using that command.")
 		}
 
 		log.Infoln("Starting IBM MQ metrics exporter for JSON")
 
-		// Connect and open standard queues
-		err = mqmetric.InitConnection(config.cf.QMgrName, config.cf.ReplyQ, config.cf.ReplyQ2, &config.cf.CC)
-	}
-
-	if err == nil {
-		if config.cf.QMgrName == "" || strings.HasPrefix(config.cf.QMgrName, "*") {
-			qmName := mqmetric.GetResolvedQMgrName()
-			log.Infoln("Resolving blank/default qmgr name to ", qmName)
-			config.cf.QMgrName = qmName
-		}
-		log.Infoln("Connected to queue manager ", config.cf.QMgrName)
-	} else {
-		if mqe, ok := err.(mqmetric.MQMetricError); ok {
-			mqrc := mqe.MQReturn.MQRC
-			mqcc := mqe.MQReturn.MQRC
-
-			if mqrc == ibmmq.MQRC_STANDBY_Q_MGR {
-				log.Errorln(err)
-				os.Exit(30) // This is the same as the strmqm return code for "active instance running elsewhere"
-			} else if mqcc == ibmmq.MQCC_WARNING {
-				log.Infoln("Connected to queue manager ", config.cf.QMgrName)
-				// Report the error but allow it to continue
-				log.Errorln(err)
-				err = nil
-			}
-		}
+		// Connect to each queue manager, and find out what metrics it can provide
+		group = pipeline.NewGroup(config.qmgrs)
+		health.Start(&config.cf, group, d)
+		reload.Start(&config.cf, group)
+		err = group.Connect()
 	}
 
-	if err == nil {
-		defer mqmetric.EndConnection()
+	if err != nil {
+		errors.ExitIfStandby(err)
 	}
 
 	if err == nil {
-		mqmetric.ChannelInitAttributes()
-		mqmetric.QueueInitAttributes()
-		mqmetric.TopicInitAttributes()
-		mqmetric.SubInitAttributes()
-		mqmetric.QueueManagerInitAttributes()
-		mqmetric.UsageInitAttributes()
-		mqmetric.ClusterInitAttributes()
-		mqmetric.ChannelAMQPInitAttributes()
-
+		defer group.Disconnect()
 	}
 
-	// What metrics can the queue manager provide? Find out, and
-	// subscribe.
 	if err == nil {
-		// Do we need to expand wildcarded queue names
-		// or use the wildcard as-is in the subscriptions
-		wildcardResource := true
-		if config.cf.MetaPrefix != "" {
-			wildcardResource = false
-		}
-		discoverConfig.MonitoredQueues.ObjectNames = config.cf.MonitoredQueues
-		discoverConfig.MonitoredQueues.UseWildcard = wildcardResource
-		discoverConfig.MetaPrefix = config.cf.MetaPrefix
-		discoverConfig.MonitoredQueues.SubscriptionSelector = strings.ToUpper(config.cf.QueueSubscriptionSelector)
-
-		err = mqmetric.DiscoverAndSubscribe(discoverConfig)
-		// Also get the static attributes for any configured channels
-		mqmetric.RediscoverAttributes(ibmmq.MQOT_CHANNEL, config.cf.MonitoredChannels)
-		// And do it again for AMQP channels
-		mqmetric.RediscoverAttributes(mqmetric.OT_CHANNEL_AMQP, config.cf.MonitoredAMQPChannels)
-
+		sink = jsonsink.New(os.Stdout, config.oneline, config.recordmax)
 	}
 
 	// Go into main loop for sending data to stdout
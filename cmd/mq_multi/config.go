@@ -0,0 +1,176 @@
+package main
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+type mqMultiConfig struct {
+	cf      cf.Config
+	qmgrs   []*cf.Config
+	outputs []cf.ConfigYOutput
+}
+
+type mqExporterConfigYaml struct {
+	Global         cf.ConfigYGlobal
+	Connection     cf.ConfigYConnection
+	Objects        cf.ConfigYObjects
+	Filters        cf.ConfigYFilters
+	Health         cf.ConfigYHealth         `yaml:"health"`
+	RelabelConfigs []cf.ConfigYRelabel      `yaml:"relabelConfigs"`
+	ObjectLabels   []cf.ConfigYObjectLabel  `yaml:"objectLabels"`
+	Outputs        []cf.ConfigYOutput       `yaml:"outputs"`
+	QueueManagers  []cf.ConfigYQueueManager `yaml:"queueManagers"`
+}
+
+const (
+	outputPrometheus = "prometheus"
+	outputOTel       = "otel"
+	outputJSON       = "json"
+
+	defaultPort       = "9157"
+	defaultNamespace  = "ibmmq"
+	defaultMetricPath = "/metrics"
+	defaultRecordMax  = 100
+)
+
+var config mqMultiConfig
+var cfy mqExporterConfigYaml
+
+/*
+initConfig parses the command line parameters. The list of outputs can only
+come from the YAML file, so that is required for this collector.
+*/
+func initConfig() error {
+	var err error
+
+	cf.InitConfig(&config.cf)
+
+	err = cf.ParseParms()
+
+	if err == nil {
+		if config.cf.ConfigFile == "" {
+			err = fmt.Errorf("a configuration file must be provided to define the outputs")
+		} else {
+			err = cf.ReadConfigFile(config.cf.ConfigFile, &cfy)
+			if err == nil {
+				cf.CopyYamlConfig(&config.cf, cfy.Global, cfy.Connection, cfy.Objects, cfy.Filters)
+				cf.CopyYamlHealthConfig(&config.cf, cfy.Health)
+				cf.CopyYamlRelabelConfig(&config.cf, cfy.RelabelConfigs)
+				cf.CopyYamlObjectLabelConfig(&config.cf, cfy.ObjectLabels)
+				config.outputs = cfy.Outputs
+			}
+		}
+	}
+
+	if err == nil {
+		cf.InitLog(config.cf)
+	}
+
+	if err == nil {
+		err = verifyOutputs(config.outputs)
+	}
+
+	if err == nil {
+		err = cf.VerifyConfig(&config.cf, config)
+	}
+
+	if err == nil {
+		if config.cf.CC.UserId != "" && config.cf.CC.Password == "" {
+			if config.cf.PasswordFile == "" {
+				config.cf.CC.Password = cf.GetPasswordFromStdin("Enter password for MQ: ")
+			} else {
+				config.cf.CC.Password, err = cf.GetPasswordFromFile(config.cf.PasswordFile, false)
+			}
+		}
+	}
+
+	// Each queue manager that is to be monitored inherits the main configuration
+	if err == nil {
+		config.qmgrs, err = cf.QueueManagerConfigs(&config.cf, cfy.QueueManagers)
+	}
+
+	if err == nil && config.cf.CC.UseResetQStats {
+		log.Warnln("Warning: Data from 'RESET QSTATS' has been requested. Ensure no other monitoring applications are also using that command.")
+	}
+
+	return err
+}
+
+// Check that each output is one we know about, and fill in defaults
+func verifyOutputs(outputs []cf.ConfigYOutput) error {
+	var err error
+
+	if len(outputs) == 0 {
+		return fmt.Errorf("no outputs have been configured")
+	}
+
+	promCount := 0
+	for i := range outputs {
+		o := &outputs[i]
+		o.Type = strings.ToLower(o.Type)
+		switch o.Type {
+		case outputPrometheus:
+			promCount++
+			if o.Port == "" {
+				o.Port = defaultPort
+			}
+			if o.MetricsPath == "" {
+				o.MetricsPath = defaultMetricPath
+			}
+			if o.Namespace == "" {
+				o.Namespace = defaultNamespace
+			}
+		case outputOTel:
+		case outputJSON:
+			if o.RecordMax == 0 {
+				o.RecordMax = defaultRecordMax
+			}
+		default:
+			err = fmt.Errorf("output %d has unknown type \"%s\"", i, o.Type)
+		}
+
+		if err == nil && o.Interval != "" {
+			var d time.Duration
+			d, err = time.ParseDuration(o.Interval)
+			if err == nil && d.Seconds() <= 1 {
+				err = fmt.Errorf("output %d interval \"%s\" is too short", i, o.Interval)
+			} else if err != nil {
+				err = fmt.Errorf("output %d has invalid interval: %v", i, err)
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	// Each Prometheus output would need its own listener, and there's no good reason
+	// to have more than one.
+	if err == nil && promCount > 1 {
+		err = fmt.Errorf("only one prometheus output can be configured")
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+package main
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This collector makes a single connection to each queue manager, and a single set of
+subscriptions, but sends the data to several different outputs. Each output has its
+own interval; collection is done at the shortest of those intervals.
+*/
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	errors "github.com/ibm-messaging/mq-metric-samples/v5/pkg/errors"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/health"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/reload"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/sinks/jsonsink"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/sinks/otelsink"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/sinks/promsink"
+	log "github.com/sirupsen/logrus"
+)
+
+var BuildStamp string
+var GitCommit string
+var BuildPlatform string
+var group *pipeline.Group
+
+func main() {
+	var err error
+	var outputs []*pipeline.Output
+
+	cf.PrintInfo("IBM MQ metrics exporter for multiple outputs", BuildStamp, GitCommit, BuildPlatform)
+
+	err = initConfig()
+
+	if err == nil {
+		cf.ExitIfPrinting(&config.cf, config.qmgrs)
+		cf.ExitIfChecking(&config.cf)
+
+		// Connect to each queue manager, and find out what metrics it can provide
+		group = pipeline.NewGroup(config.qmgrs)
+		err = group.Connect()
+	}
+
+	if err != nil {
+		errors.ExitIfStandby(err)
+	}
+
+	if err == nil {
+		defer group.Disconnect()
+	}
+
+	if err == nil {
+		ctx, stop := signal.NotifyContext(context.TODO(), os.Interrupt)
+		defer stop()
+
+		outputs, err = newOutputs(ctx, config.outputs)
+	}
+
+	// Go into main loop. This program runs forever
+	if err == nil {
+		fanout := pipeline.NewFanout(group, outputs)
+		health.Start(&config.cf, group, fanout.Interval())
+		reload.Start(&config.cf, group)
+		log.Infof("Collecting every %v for %d output(s)", fanout.Interval(), len(outputs))
+		for {
+			errors.HandleCollection(fanout.Collect())
+			time.Sleep(fanout.Interval())
+		}
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	os.Exit(0)
+}
+
+// Create the Sinks for each configured output. The configuration has already been checked.
+func newOutputs(ctx context.Context, cfOutputs []cf.ConfigYOutput) ([]*pipeline.Output, error) {
+	var err error
+	var outputs []*pipeline.Output
+
+	for _, o := range cfOutputs {
+		var s pipeline.Sink
+		var d time.Duration
+
+		if o.Interval != "" {
+			d, _ = time.ParseDuration(o.Interval)
+		}
+
+		switch o.Type {
+		case outputPrometheus:
+			ps := promsink.New(o.Namespace, false)
+			startServer(o, ps)
+			s = ps
+		case outputOTel:
+			// The override value will turn all metrics into Gauges instead of a mixture of Gauge and Counter
+			// when sending across GRPC.
+			overrideCType := false
+			if o.Endpoint != "" {
+				overrideCType = cf.AsBool(o.OverrideCType, false)
+			}
+			s, err = otelsink.New(ctx, otelsink.DefaultResource(), o.Endpoint, cf.AsBool(o.Insecure, false), overrideCType)
+		case outputJSON:
+			var w io.Writer = os.Stdout
+			if o.File != "" {
+				w, err = os.OpenFile(o.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+			}
+			if err == nil {
+				s = jsonsink.New(w, cf.AsBool(o.OneLine, false), o.RecordMax)
+			}
+		}
+
+		if err != nil {
+			break
+		}
+		log.Infof("Created %s output with interval %v", s.Name(), d)
+		outputs = append(outputs, pipeline.NewOutput(s, d))
+	}
+
+	return outputs, err
+}
+
+// The Prometheus output needs a web server for the scrapes. It runs until the program ends.
+func startServer(o cf.ConfigYOutput, ps *promsink.Sink) {
+	mux := http.NewServeMux()
+	mux.Handle(o.MetricsPath, ps.Handler())
+
+	addr := net.JoinHostPort(o.Host, o.Port)
+	server := &http.Server{Addr: addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Infoln("Listening on http address", addr)
+		if err := server.ListenAndServe(); err != nil {
+			log.Fatalf("Metrics Error: %v", err)
+		}
+	}()
+}
@@ -35,16 +35,21 @@ type ConfigYOpenTSDB struct {
 }
 
 type mqOpenTSDBConfig struct {
-	cf cf.Config
-	ci ConfigYOpenTSDB
+	cf    cf.Config
+	qmgrs []*cf.Config
+	ci    ConfigYOpenTSDB
 }
 
 type mqExporterConfigYaml struct {
-	Global     cf.ConfigYGlobal
-	Connection cf.ConfigYConnection
-	Objects    cf.ConfigYObjects
-	Filters    cf.ConfigYFilters
-	OpenTSDB   ConfigYOpenTSDB `yaml:"opentsdb"`
+	Global         cf.ConfigYGlobal
+	Connection     cf.ConfigYConnection
+	Objects        cf.ConfigYObjects
+	Filters        cf.ConfigYFilters
+	Health         cf.ConfigYHealth         `yaml:"health"`
+	RelabelConfigs []cf.ConfigYRelabel      `yaml:"relabelConfigs"`
+	ObjectLabels   []cf.ConfigYObjectLabel  `yaml:"objectLabels"`
+	OpenTSDB       ConfigYOpenTSDB          `yaml:"opentsdb"`
+	QueueManagers  []cf.ConfigYQueueManager `yaml:"queueManagers"`
 }
 
 var config mqOpenTSDBConfig
@@ -71,6 +76,9 @@ func initConfig() error {
 			err = cf.ReadConfigFile(config.cf.ConfigFile, &cfy)
 			if err == nil {
 				cf.CopyYamlConfig(&config.cf, cfy.Global, cfy.Connection, cfy.Objects, cfy.Filters)
+				cf.CopyYamlHealthConfig(&config.cf, cfy.Health)
+				cf.CopyYamlRelabelConfig(&config.cf, cfy.RelabelConfigs)
+				cf.CopyYamlObjectLabelConfig(&config.cf, cfy.ObjectLabels)
 				config.ci.DatabaseAddress = cf.CopyParmIfNotSetStr("opentsdb", "databaseAddress", cfy.OpenTSDB.DatabaseAddress)
 				config.ci.Interval = cf.CopyParmIfNotSetStr("opentsdb", "interval", cfy.OpenTSDB.Interval)
 				config.ci.MaxErrors = cf.CopyParmIfNotSetInt("opentsdb", "maxErrors", cfy.OpenTSDB.MaxErrors)
@@ -102,6 +110,11 @@ func initConfig() error {
 		}
 	}
 
+	// Each queue manager that is to be monitored inherits the main configuration
+	if err == nil {
+		config.qmgrs, err = cf.QueueManagerConfigs(&config.cf, cfy.QueueManagers)
+	}
+
 	if err == nil && config.cf.CC.UseResetQStats {
 		log.Errorln("Warning: Data from 'RESET QSTATS' has been requested.")
 		log.Errorln("Ensure no other monitoring applications are also using that command.")
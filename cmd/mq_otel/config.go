@@ -36,16 +36,21 @@ type ConfigYOTel struct {
 
 type mqOTelConfig struct {
 	cf            cf.Config
+	qmgrs         []*cf.Config
 	ci            ConfigYOTel
 	overrideCType bool
 }
 
 type mqExporterConfigYaml struct {
-	Global     cf.ConfigYGlobal
-	Connection cf.ConfigYConnection
-	Objects    cf.ConfigYObjects
-	Filters    cf.ConfigYFilters
-	OTel       ConfigYOTel `yaml:"otel"`
+	Global         cf.ConfigYGlobal
+	Connection     cf.ConfigYConnection
+	Objects        cf.ConfigYObjects
+	Filters        cf.ConfigYFilters
+	Health         cf.ConfigYHealth         `yaml:"health"`
+	RelabelConfigs []cf.ConfigYRelabel      `yaml:"relabelConfigs"`
+	ObjectLabels   []cf.ConfigYObjectLabel  `yaml:"objectLabels"`
+	OTel           ConfigYOTel              `yaml:"otel"`
+	QueueManagers  []cf.ConfigYQueueManager `yaml:"queueManagers"`
 }
 
 var config mqOTelConfig
@@ -71,6 +76,9 @@ func initConfig() error {
 			err = cf.ReadConfigFile(config.cf.ConfigFile, &cfy)
 			if err == nil {
 				cf.CopyYamlConfig(&config.cf, cfy.Global, cfy.Connection, cfy.Objects, cfy.Filters)
+				cf.CopyYamlHealthConfig(&config.cf, cfy.Health)
+				cf.CopyYamlRelabelConfig(&config.cf, cfy.RelabelConfigs)
+				cf.CopyYamlObjectLabelConfig(&config.cf, cfy.ObjectLabels)
 				config.ci.Endpoint = cf.CopyParmIfNotSetStr("otel", "endpoint", cfy.OTel.Endpoint)
 				config.ci.Interval = cf.CopyParmIfNotSetStr("otel", "interval", cfy.OTel.Interval)
 				config.ci.MaxErrors = cf.CopyParmIfNotSetInt("otel", "maxErrors", cfy.OTel.MaxErrors)
@@ -114,6 +122,11 @@ func initConfig() error {
 		}
 	}
 
+	// Each queue manager that is to be monitored inherits the main configuration
+	if err == nil {
+		config.qmgrs, err = cf.QueueManagerConfigs(&config.cf, cfy.QueueManagers)
+	}
+
 	if err == nil && config.cf.CC.UseResetQStats {
 		log.Errorln("Warning: Data from 'RESET QSTATS' has been requested.")
 		log.Errorln("Ensure no other monitoring applications are also using that command.")
@@ -23,22 +23,47 @@ import (
 	"time"
 
 	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/remotewrite"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/webconfig"
+	log "github.com/sirupsen/logrus"
 )
 
 type mqExporterConfig struct {
-	cf cf.Config // Common configuration attributes for all collectors
-
-	httpListenPort            string
-	httpListenHost            string
-	httpMetricPath            string
-	namespace                 string
-	httpsCertFile             string
-	httpsKeyFile              string
-	keepRunning               bool
-	reconnectIntervalDuration time.Duration
-	reconnectInterval         string
-	overrideCType             string
-	overrideCTypeBool         bool
+	cf    cf.Config // Common configuration attributes for all collectors
+	qmgrs []*cf.Config
+
+	httpListenPort           string
+	httpListenHost           string
+	httpMetricPath           string
+	namespace                string
+	httpsCertFile            string
+	httpsKeyFile             string
+	webConfigFile            string
+	web                      *webconfig.Server
+	overrideCType            string
+	overrideCTypeBool        bool
+	openMetrics              string
+	openMetricsBool          bool
+	probeIdleTimeout         string
+	probeIdleTimeoutDuration time.Duration
+	probeMaxTargets          int
+	collectInterval          string
+	collectIntervalDuration  time.Duration
+
+	remoteWrite remoteWriteConfig
+	rwConfig    remotewrite.Config
+	rw          *remotewrite.Writer
+}
+
+// Where to push the metrics, for when Prometheus cannot scrape us
+type remoteWriteConfig struct {
+	url          string
+	bearerToken  string
+	user         string
+	password     string
+	timeout      string
+	queueSize    int
+	walDirectory string
 }
 
 type ConfigYProm struct {
@@ -48,24 +73,50 @@ type ConfigYProm struct {
 	Namespace         string
 	HttpsCertFile     string `yaml:"httpsCertFile"`
 	HttpsKeyFile      string `yaml:"httpsKeyFile"`
-	KeepRunning       bool   `yaml:"keepRunning"`
+	WebConfigFile     string `yaml:"webConfigFile"`
+	KeepRunning       string `yaml:"keepRunning" default:"true"`
 	ReconnectInterval string `yaml:"reconnectInterval"`
 	OverrideCType     string `yaml:"overrideCType"`
+	OpenMetrics       string `yaml:"openMetrics"`
+	ProbeIdleTimeout  string `yaml:"probeIdleTimeout"`
+	ProbeMaxTargets   int    `yaml:"probeMaxTargets"`
+	CollectInterval   string `yaml:"collectInterval"`
+
+	RemoteWrite ConfigYRemoteWrite `yaml:"remoteWrite"`
+}
+
+type ConfigYRemoteWrite struct {
+	Url          string
+	BearerToken  string `yaml:"bearerToken"`
+	User         string
+	Password     string
+	Timeout      string
+	QueueSize    int    `yaml:"queueSize"`
+	WalDirectory string `yaml:"walDirectory"`
 }
 
 type mqExporterConfigYaml struct {
-	Global     cf.ConfigYGlobal
-	Connection cf.ConfigYConnection
-	Objects    cf.ConfigYObjects
-	Filters    cf.ConfigYFilters
-	Prometheus ConfigYProm
+	Global         cf.ConfigYGlobal
+	Connection     cf.ConfigYConnection
+	Objects        cf.ConfigYObjects
+	Filters        cf.ConfigYFilters
+	Health         cf.ConfigYHealth        `yaml:"health"`
+	RelabelConfigs []cf.ConfigYRelabel     `yaml:"relabelConfigs"`
+	ObjectLabels   []cf.ConfigYObjectLabel `yaml:"objectLabels"`
+	Prometheus     ConfigYProm
+	QueueManagers  []cf.ConfigYQueueManager `yaml:"queueManagers"`
 }
 
 const (
-	defaultPort              = "9157" // Reserved in the prometheus wiki for MQ
-	defaultNamespace         = "ibmmq"
-	defaultReconnectInterval = "5s"
-	defaultMetricPath        = "/metrics"
+	defaultPort             = "9157" // Reserved in the prometheus wiki for MQ
+	defaultNamespace        = "ibmmq"
+	defaultMetricPath       = "/metrics"
+	defaultProbeIdleTimeout = "5m"
+	defaultProbeMaxTargets  = 20
+
+	defaultRemoteWriteInterval  = 60 * time.Second
+	defaultRemoteWriteTimeout   = "30s"
+	defaultRemoteWriteQueueSize = 100
 )
 
 var config mqExporterConfig
@@ -87,13 +138,24 @@ func initConfig() error {
 	cf.AddParm(&config.httpListenPort, defaultPort, cf.CP_STR, "ibmmq.httpListenPort", "prometheus", "port", "HTTP(S) Listener Port")
 	cf.AddParm(&config.httpListenHost, "", cf.CP_STR, "ibmmq.httpListenHost", "prometheus", "host", "HTTP(S) Listener Host")
 	cf.AddParm(&config.httpMetricPath, defaultMetricPath, cf.CP_STR, "ibmmq.httpMetricPath", "prometheus", "metricsPath", "Path to exporter metrics")
-	cf.AddParm(&config.keepRunning, true, cf.CP_BOOL, "ibmmq.keepRunning", "prometheus", "keepRunning", "Continue running after queue manager disconnection")
-	cf.AddParm(&config.reconnectInterval, defaultReconnectInterval, cf.CP_STR, "ibmmq.reconnectInterval", "prometheus", "reconnectInterval", "How fast to validate connection attempts")
 	cf.AddParm(&config.httpsCertFile, "", cf.CP_STR, "ibmmq.httpsCertFile", "prometheus", "httpsCertFile", "TLS public certificate file")
 	cf.AddParm(&config.httpsKeyFile, "", cf.CP_STR, "ibmmq.httpsKeyFile", "prometheus", "httpsKeyFile", "TLS private key file")
+	cf.AddParm(&config.webConfigFile, "", cf.CP_STR, "ibmmq.webConfigFile", "prometheus", "webConfigFile", "File with TLS and authentication settings for the HTTP server")
 
 	cf.AddParm(&config.namespace, defaultNamespace, cf.CP_STR, "namespace", "prometheus", "namespace", "Namespace for metrics")
 	cf.AddParm(&config.overrideCType, "", cf.CP_STR, "ibmmq.otelOverrideCType", "prometheus", "overrideCType", "Override default data types to give mixture of Counters and Gauges")
+	cf.AddParm(&config.openMetrics, "", cf.CP_STR, "ibmmq.openMetrics", "prometheus", "openMetrics", "Use OpenMetrics names, units and types for the metrics")
+	cf.AddParm(&config.probeIdleTimeout, defaultProbeIdleTimeout, cf.CP_STR, "ibmmq.probeIdleTimeout", "prometheus", "probeIdleTimeout", "How long to keep an unused probe connection")
+	cf.AddParm(&config.probeMaxTargets, defaultProbeMaxTargets, cf.CP_INT, "ibmmq.probeMaxTargets", "prometheus", "probeMaxTargets", "Most queue managers that can be probed at the same time")
+	cf.AddParm(&config.collectInterval, "", cf.CP_STR, "ibmmq.collectInterval", "prometheus", "collectInterval", "Collect in the background at this interval instead of on each scrape")
+
+	cf.AddParm(&config.remoteWrite.url, "", cf.CP_STR, "ibmmq.remoteWriteUrl", "prometheus", "remoteWriteUrl", "Push the metrics to this remote-write URL")
+	cf.AddParm(&config.remoteWrite.bearerToken, "", cf.CP_STR, "ibmmq.remoteWriteBearerToken", "prometheus", "remoteWriteBearerToken", "Bearer token, or a reference to one, for the remote-write server")
+	cf.AddParm(&config.remoteWrite.user, "", cf.CP_STR, "ibmmq.remoteWriteUser", "prometheus", "remoteWriteUser", "User for basic authentication to the remote-write server")
+	cf.AddParm(&config.remoteWrite.password, "", cf.CP_STR, "ibmmq.remoteWritePassword", "prometheus", "remoteWritePassword", "Password, or a reference to one, for the remote-write server")
+	cf.AddParm(&config.remoteWrite.timeout, defaultRemoteWriteTimeout, cf.CP_STR, "ibmmq.remoteWriteTimeout", "prometheus", "remoteWriteTimeout", "Timeout for each remote-write request")
+	cf.AddParm(&config.remoteWrite.queueSize, defaultRemoteWriteQueueSize, cf.CP_INT, "ibmmq.remoteWriteQueueSize", "prometheus", "remoteWriteQueueSize", "Number of remote-write requests kept for retrying")
+	cf.AddParm(&config.remoteWrite.walDirectory, "", cf.CP_STR, "ibmmq.remoteWriteWalDirectory", "prometheus", "remoteWriteWalDirectory", "Directory to keep unsent remote-write requests across restarts")
 
 	err = cf.ParseParms()
 
@@ -102,7 +164,18 @@ func initConfig() error {
 
 			err = cf.ReadConfigFile(config.cf.ConfigFile, &cfy)
 			if err == nil {
+				// The reconnection options used to be specific to this collector. They are still
+				// accepted here, if they are not in the connection section.
+				if cfy.Connection.KeepRunning == "" {
+					cfy.Connection.KeepRunning = cfy.Prometheus.KeepRunning
+				}
+				if cfy.Connection.Reconnect == "" {
+					cfy.Connection.Reconnect = cfy.Prometheus.ReconnectInterval
+				}
 				cf.CopyYamlConfig(&config.cf, cfy.Global, cfy.Connection, cfy.Objects, cfy.Filters)
+				cf.CopyYamlHealthConfig(&config.cf, cfy.Health)
+				cf.CopyYamlRelabelConfig(&config.cf, cfy.RelabelConfigs)
+				cf.CopyYamlObjectLabelConfig(&config.cf, cfy.ObjectLabels)
 				config.httpListenPort = cf.CopyParmIfNotSetStr("prometheus", "port", cfy.Prometheus.Port)
 				config.httpListenHost = cf.CopyParmIfNotSetStr("prometheus", "host", cfy.Prometheus.Host)
 				config.httpMetricPath = cf.CopyParmIfNotSetStr("prometheus", "MetricsPath", cfy.Prometheus.MetricsPath)
@@ -110,13 +183,34 @@ func initConfig() error {
 
 				config.httpsCertFile = cf.CopyParmIfNotSetStr("prometheus", "httpsCertFile", cfy.Prometheus.HttpsCertFile)
 				config.httpsKeyFile = cf.CopyParmIfNotSetStr("prometheus", "httpsKeyFile", cfy.Prometheus.HttpsKeyFile)
+				config.webConfigFile = cf.CopyParmIfNotSetStr("prometheus", "webConfigFile", cfy.Prometheus.WebConfigFile)
 
-				config.keepRunning = cf.CopyParmIfNotSetBool("prometheus", "keepRunning", cfy.Prometheus.KeepRunning)
-				if cfy.Prometheus.ReconnectInterval == "" {
-					cfy.Prometheus.ReconnectInterval = defaultReconnectInterval
-				}
-				config.reconnectInterval = cf.CopyParmIfNotSetStr("prometheus", "reconnectInterval", cfy.Prometheus.ReconnectInterval)
 				config.overrideCType = cf.CopyParmIfNotSetStr("prometheus", "overrideCType", cfy.Prometheus.OverrideCType)
+				config.openMetrics = cf.CopyParmIfNotSetStr("prometheus", "openMetrics", cfy.Prometheus.OpenMetrics)
+				if cfy.Prometheus.ProbeIdleTimeout == "" {
+					cfy.Prometheus.ProbeIdleTimeout = defaultProbeIdleTimeout
+				}
+				config.probeIdleTimeout = cf.CopyParmIfNotSetStr("prometheus", "probeIdleTimeout", cfy.Prometheus.ProbeIdleTimeout)
+				if cfy.Prometheus.ProbeMaxTargets == 0 {
+					cfy.Prometheus.ProbeMaxTargets = defaultProbeMaxTargets
+				}
+				config.probeMaxTargets = cf.CopyParmIfNotSetInt("prometheus", "probeMaxTargets", cfy.Prometheus.ProbeMaxTargets)
+				config.collectInterval = cf.CopyParmIfNotSetStr("prometheus", "collectInterval", cfy.Prometheus.CollectInterval)
+
+				rw := cfy.Prometheus.RemoteWrite
+				if rw.Timeout == "" {
+					rw.Timeout = defaultRemoteWriteTimeout
+				}
+				config.remoteWrite.url = cf.CopyParmIfNotSetStr("prometheus", "remoteWriteUrl", rw.Url)
+				config.remoteWrite.bearerToken = cf.CopyParmIfNotSetStr("prometheus", "remoteWriteBearerToken", rw.BearerToken)
+				config.remoteWrite.user = cf.CopyParmIfNotSetStr("prometheus", "remoteWriteUser", rw.User)
+				config.remoteWrite.password = cf.CopyParmIfNotSetStr("prometheus", "remoteWritePassword", rw.Password)
+				config.remoteWrite.timeout = cf.CopyParmIfNotSetStr("prometheus", "remoteWriteTimeout", rw.Timeout)
+				if rw.QueueSize == 0 {
+					rw.QueueSize = defaultRemoteWriteQueueSize
+				}
+				config.remoteWrite.queueSize = cf.CopyParmIfNotSetInt("prometheus", "remoteWriteQueueSize", rw.QueueSize)
+				config.remoteWrite.walDirectory = cf.CopyParmIfNotSetStr("prometheus", "remoteWriteWalDirectory", rw.WalDirectory)
 
 			}
 		}
@@ -138,6 +232,8 @@ func initConfig() error {
 	if err == nil {
 		// This preserves a degree of compatibility with the mq_prometheus collector in this repo and any dashboards.
 		config.overrideCTypeBool = cf.AsBool(config.overrideCType, false)
+		// The original names stay the default, so that existing dashboards keep working
+		config.openMetricsBool = cf.AsBool(config.openMetrics, false)
 	}
 
 	if err == nil {
@@ -145,10 +241,54 @@ func initConfig() error {
 	}
 
 	if err == nil {
-		if config.reconnectInterval == "" {
-			config.reconnectInterval = defaultReconnectInterval
+		if config.probeIdleTimeout == "" {
+			config.probeIdleTimeout = defaultProbeIdleTimeout
+		}
+		config.probeIdleTimeoutDuration, err = time.ParseDuration(config.probeIdleTimeout)
+		if err == nil && config.probeIdleTimeoutDuration <= 0 {
+			err = fmt.Errorf("probeIdleTimeout must be a positive duration")
+		}
+		if err == nil && config.probeMaxTargets < 0 {
+			err = fmt.Errorf("probeMaxTargets cannot be negative")
+		}
+	}
+
+	// The certificates and users are checked now, so that any problems stop us before connecting.
+	// Nothing is created until initClients, as we might only be checking the configuration.
+	if err == nil {
+		err = webconfig.VerifyConfig(config.webConfigFile, config.httpsCertFile, config.httpsKeyFile)
+	}
+
+	// Without a collectInterval, each scrape does its own collection
+	if err == nil && config.collectInterval != "" {
+		config.collectIntervalDuration, err = time.ParseDuration(config.collectInterval)
+		if err == nil && config.collectIntervalDuration < 0 {
+			err = fmt.Errorf("collectInterval cannot be negative")
+		}
+	}
+
+	// Pushing needs something to drive it, so there is always a background collection
+	if err == nil && config.remoteWrite.url != "" {
+		if config.collectIntervalDuration == 0 {
+			config.collectIntervalDuration = defaultRemoteWriteInterval
+			log.Infof("Using a collectInterval of %v for remote write, as none was set", config.collectIntervalDuration)
+		}
+		timeout, err2 := time.ParseDuration(config.remoteWrite.timeout)
+		if err2 != nil {
+			err = fmt.Errorf("remoteWriteTimeout: %v", err2)
+		} else {
+			config.rwConfig = remotewrite.Config{
+				URL:          config.remoteWrite.url,
+				BearerToken:  config.remoteWrite.bearerToken,
+				User:         config.remoteWrite.user,
+				Password:     config.remoteWrite.password,
+				Timeout:      timeout,
+				QueueSize:    config.remoteWrite.queueSize,
+				WALDirectory: config.remoteWrite.walDirectory,
+				SecretTTL:    config.cf.SecretTTLDuration,
+			}
+			err = remotewrite.VerifyConfig(config.rwConfig)
 		}
-		config.reconnectIntervalDuration, err = time.ParseDuration(config.reconnectInterval)
 	}
 
 	if err == nil {
@@ -161,6 +301,11 @@ func initConfig() error {
 		}
 	}
 
+	// Each queue manager that is to be monitored inherits the main configuration
+	if err == nil {
+		config.qmgrs, err = cf.QueueManagerConfigs(&config.cf, cfy.QueueManagers)
+	}
+
 	if err == nil && config.cf.CC.UseResetQStats {
 		fmt.Println("Warning: Data from 'RESET QSTATS' has been requested.")
 		fmt.Println("Ensure no other monitoring applications are also using that command.")
@@ -168,3 +313,18 @@ func initConfig() error {
 
 	return err
 }
+
+/*
+initClients sets up the web server's settings and the remote writer, once we know that we are
+really going to run. They look up secrets, which might run commands, and the remote writer
+creates its WAL directory, none of which should happen when only checking the configuration.
+*/
+func initClients() error {
+	var err error
+
+	config.web, err = webconfig.New(config.webConfigFile, config.httpsCertFile, config.httpsKeyFile, config.cf.SecretTTLDuration)
+	if err == nil && config.rwConfig.URL != "" {
+		config.rw, err = remotewrite.New(config.rwConfig)
+	}
+	return err
+}
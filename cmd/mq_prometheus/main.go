@@ -20,33 +20,35 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
 	"net/http"
 	"os"
 
-	"strings"
 	"sync"
 	"time"
 
-	"github.com/ibm-messaging/mq-golang/v5/ibmmq"
-	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
 	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/errors"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/health"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/reload"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/sinks/promsink"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 )
 
 var (
-	BuildStamp     string
-	GitCommit      string
-	BuildPlatform  string
-	discoverConfig mqmetric.DiscoverConfig
-	usingTLS       = false
-	server         *http.Server
-	startChannel   = make(chan bool)
-	collector      prometheus.Collector
-	mutex          sync.RWMutex
-	retryCount     = 0 // Might use this with a maxRetry to force a quit out of collector
+	BuildStamp    string
+	GitCommit     string
+	BuildPlatform string
+	usingTLS      = false
+	server        *http.Server
+	startChannel  = make(chan bool)
+	collector     prometheus.Collector
+	group         *pipeline.Group
+	collectors    []*pipeline.Collector
+	mutex         sync.RWMutex
+	retryCount    = 0 // Might use this with a maxRetry to force a quit out of collector
 )
 
 func main() {
@@ -67,133 +69,101 @@ func main() {
 	if err != nil {
 		log.Error(err)
 	} else {
+		cf.ExitIfPrinting(&config.cf, config.qmgrs)
+		cf.ExitIfChecking(&config.cf)
+
+		// Only now that we're really going to run, set up anything that has side effects
+		err = initClients()
+		if err != nil {
+			log.Error(err)
+			os.Exit(10)
+		}
+
 		setConnectedOnce(false)
-		setConnectedQMgr(false)
 		setCollectorEnd(false)
-		setFirstCollection(false)
-		setCollectorSilent(false)
+
+		sink = newSink()
+
+		group = pipeline.NewGroup(config.qmgrs)
+		collectors = group.Collectors
+
+		// Collections are driven by the scrapes unless there's a collectInterval, so there may be
+		// no fixed interval for the health checks. They are always on the main server once it
+		// starts, and can also have their own port.
+		health.Start(&config.cf, group, config.collectIntervalDuration)
+		reload.Start(&config.cf, group)
 
 		// Start the webserver in a separate thread
 		go startServer()
 
-		// This is the main loop that tries to keep the collector connected to a queue manager
-		// even after a failure.
+		// This is the main loop that tries to keep the collector connected to the queue managers
+		// even after a failure. Each queue manager is reconnected independently.
 		for !isCollectorEnd() {
-			log.Debugf("In main loop: qMgrConnected=%v", isConnectedQMgr())
-			err = nil // Start clean on each loop
+			log.Debugf("In main loop")
 
-			// The callback will set this flag to false if there's an error while
+			// The callback will mark a collector as disconnected if there's an error while
 			// processing the messages.
-			if !isConnectedQMgr() {
-				mutex.Lock()
-				if err == nil {
-					mqmetric.EndConnection()
-					// Connect and open standard queues. If we're going to manage reconnection from
-					// this collector, then turn off the MQ client automatic option
-					if config.keepRunning {
-						config.cf.CC.SingleConnect = true
-					} else {
-						config.cf.CC.SingleConnect = false
-					}
-					err = mqmetric.InitConnection(config.cf.QMgrName, config.cf.ReplyQ, config.cf.ReplyQ2, &config.cf.CC)
-					if err == nil {
-						if config.cf.QMgrName == "" || strings.HasPrefix(config.cf.QMgrName, "*") {
-							qmName := mqmetric.GetResolvedQMgrName()
-							log.Infoln("Resolving blank/default qmgr name to ", qmName)
-							config.cf.QMgrName = qmName
-						}
-						log.Infoln("Connected to queue manager " + config.cf.QMgrName)
-					} else {
-						if mqe, ok := err.(mqmetric.MQMetricError); ok {
-							mqrc := mqe.MQReturn.MQRC
-							mqcc := mqe.MQReturn.MQCC
-							if mqrc == ibmmq.MQRC_STANDBY_Q_MGR {
-								log.Errorln(err)
-								os.Exit(30) // This is the same as the strmqm return code for "active instance running elsewhere"
-							} else if mqcc == ibmmq.MQCC_WARNING {
-								log.Infoln("Connected to queue manager " + config.cf.QMgrName)
-								log.Errorln(err)
-								err = nil
-							}
-						}
-					}
+			mutex.Lock()
+			newConnection := false
+			for _, c := range collectors {
+				if c.Connected() || !c.ReconnectDue() || c.ReplayFinished() {
+					continue
 				}
-
+				err = c.Connect()
 				if err == nil {
 					retryCount = 0
-					defer mqmetric.EndConnection()
-				}
-
-				// What metrics can the queue manager provide? Find out, and subscribe.
-				if err == nil {
-					// Do we need to expand wildcarded queue names
-					// or use the wildcard as-is in the subscriptions
-					wildcardResource := true
-					if config.cf.MetaPrefix != "" {
-						wildcardResource = false
+					newConnection = true
+				} else {
+					// An active instance running elsewhere is only a reason to stop if it's the only
+					// queue manager we are looking at
+					if len(collectors) == 1 {
+						errors.ExitIfStandby(err)
 					}
-
-					mqmetric.SetLocale(config.cf.Locale)
-					discoverConfig.MonitoredQueues.ObjectNames = config.cf.MonitoredQueues
-					discoverConfig.MonitoredQueues.SubscriptionSelector = strings.ToUpper(config.cf.QueueSubscriptionSelector)
-					discoverConfig.MonitoredQueues.UseWildcard = wildcardResource
-					discoverConfig.MetaPrefix = config.cf.MetaPrefix
-					err = mqmetric.DiscoverAndSubscribe(discoverConfig)
-					e := mqmetric.RediscoverAttributes(ibmmq.MQOT_CHANNEL, config.cf.MonitoredChannels)
-					e = mqmetric.RediscoverAttributes(mqmetric.OT_CHANNEL_MQTT, config.cf.MonitoredMQTTChannels)
-
-					log.Debugf("Returned from RediscoverAttributes with error %v  tempErr %v", err, e)
-				}
-
-				if err == nil {
-					var compCode int32
-					compCode, err = mqmetric.VerifyConfig()
-
-					// We could choose to fail after a warning, but instead will continue for now
-					if compCode == ibmmq.MQCC_WARNING {
-						log.Println(err)
-						err = nil
+					log.Errorf("Connection to %s has failed. %v", c.QMgrName(), err)
+					if !c.Config().KeepRunning || (len(collectors) == 1 && !c.ConnectedOnce()) {
+						// If we've never successfully connected, then exit instead
+						// of retrying as it probably means a config error
+						setCollectorEnd(true)
 					}
 				}
+			}
 
-				// Once everything has been discovered, and the subscriptions
-				// created, allocate the Prometheus gauges for each resource. If this is
-				// a reconnect, then we clean up and create a new collector with new gauges
-				if err == nil {
-					allocateAllGauges()
-
-					if collector != nil {
-						setCollectorSilent(true)
-						prometheus.Unregister(collector)
-						setCollectorSilent(false)
-					}
-
+			// Once everything has been discovered, and the subscriptions
+			// created, the collector can be registered and the web server started.
+			// The metrics come from each collection, so nothing needs to be done
+			// after a reconnect.
+			if newConnection && !isCollectorEnd() {
+				err = nil
+				if !isConnectedOnce() {
 					collector = newExporter()
-					setFirstCollection(true)
 					prometheus.MustRegister(collector)
-					setConnectedQMgr(true)
-
-					if !isConnectedOnce() {
-						startChannel <- true
-						setConnectedOnce(true)
-					}
-				} else {
-					if !isConnectedOnce() || !config.keepRunning {
-						// If we've never successfully connected, then exit instead
-						// of retrying as it probably means a config error
-						log.Errorf("Connection to %s has failed. %v", config.cf.QMgrName, err)
-						setCollectorEnd(true)
-					} else {
-						log.Debugf("Sleeping a bit after a failure: %d", retryCount)
-						retryCount++
-						time.Sleep(config.reconnectIntervalDuration)
+					if config.collectIntervalDuration > 0 {
+						go runCollections(config.collectIntervalDuration)
 					}
+					startChannel <- true
+					setConnectedOnce(true)
 				}
-				mutex.Unlock()
-			} else {
-				log.Debug("Sleeping a bit while connected")
-				time.Sleep(config.reconnectIntervalDuration)
+			} else if !isConnectedOnce() {
+				// None of the queue managers could be reached on the first attempt
+				setCollectorEnd(true)
+			} else if group.ReplayFinished() {
+				log.Infoln("Replay has finished")
+				setCollectorEnd(true)
 			}
+			mutex.Unlock()
+
+			if !isCollectorEnd() {
+				log.Debugf("Sleeping a bit: %d", retryCount)
+				retryCount++
+				time.Sleep(config.cf.ReconnectIntervalDuration)
+			}
+		}
+
+		for _, c := range collectors {
+			c.Disconnect()
+		}
+		if config.rw != nil {
+			config.rw.Close()
 		}
 	}
 	log.Info("Done.")
@@ -206,46 +176,55 @@ func main() {
 }
 
 func startServer() {
-	var err error
 	// This function starts a new thread to handle the web server that will then run
 	// permanently and drive the exporter callback that processes the metric messages
 
-	// Need to wait until signalled by the main thread that it's setup the gauges
+	// The health checks are there from the start, so that a liveness probe doesn't fail
+	// while we wait for the queue manager. /readyz says that it's not yet connected. A
+	// container platform can't give credentials for its probes, so the users or tokens in
+	// the web config file are needed for every page apart from these.
+	mux := http.NewServeMux()
+	health.NewChecker(group, config.collectIntervalDuration, config.cf.HealthStaleIntervals, config.cf.HealthReadyWhen).Register(mux)
+	mux.Handle("/", config.web.Wrap(http.DefaultServeMux))
+	go serve(mux)
+
+	// Need to wait until signalled by the main thread that it's connected before
+	// there are any metrics to give
 	log.Debug("HTTP server - waiting until MQ connection ready")
 	<-startChannel
 
-	http.Handle(config.httpMetricPath, promhttp.Handler())
+	// The promhttp handler can't give the units that are part of the OpenMetrics scheme
+	if config.openMetricsBool {
+		http.Handle(config.httpMetricPath, metricsHandler(promsink.OpenMetricsHandler(prometheus.DefaultGatherer)))
+	} else {
+		http.Handle(config.httpMetricPath, metricsHandler(promhttp.Handler()))
+	}
+	http.HandleFunc("/probe", probeHandler)
+	go expireProbeTargets()
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write(landingPage())
 	})
+}
+
+// serve runs the web server until it fails. The handlers can be added while it's running.
+func serve(handler http.Handler) {
+	var err error
 
 	address := config.httpListenHost + ":" + config.httpListenPort
-	if config.httpsKeyFile == "" && config.httpsCertFile == "" {
-		usingTLS = false
-	} else {
-		usingTLS = true
-	}
+	usingTLS = config.web.UsingTLS()
 
 	if usingTLS {
 		// TLS has been enabled for the collector (which is acting as a TLS Server)
 		// So we setup the TLS configuration from the keystores and let Prometheus
-		// contact us over the https protocol.
-		cert, err := tls.LoadX509KeyPair(config.httpsCertFile, config.httpsKeyFile)
-		if err == nil {
-			server = &http.Server{Addr: address,
-				Handler: nil,
-				// More fields could be added here for further control of the connection
-				TLSConfig: &tls.Config{
-					Certificates: []tls.Certificate{cert},
-					MinVersion:   tls.VersionTLS12,
-				},
-			}
-		} else {
-			log.Fatal(err)
+		// contact us over the https protocol. The certificates are read again when they
+		// change, and client certificates can be required.
+		server = &http.Server{Addr: address,
+			Handler:   handler,
+			TLSConfig: config.web.TLSConfig(),
 		}
 	} else {
 		server = &http.Server{Addr: address,
-			Handler: nil,
+			Handler: handler,
 		}
 	}
 
@@ -286,7 +265,9 @@ func landingPage() []byte {
 <head><title>IBM MQ metrics exporter for Prometheus</title></head>
 <body>
 <h1>IBM MQ metrics exporter for Prometheus</h1>
-<p><a href='` + config.httpMetricPath + `'>Metrics</a></p>
+<p><a href='` + config.httpMetricPath + `'>Metrics</a>, or some of them with parameters such as <code>?collect[]=queue&amp;name[]=APP.*</code></p>
+<p>Other queue managers can be reached through the CCDT with <code>/probe?target=QMNAME</code></p>
+<p><a href='/healthz'>Health</a> and <a href='/readyz'>readiness</a> checks</p>
 </body>
 </html>
 `)
@@ -0,0 +1,207 @@
+package main
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This file handles the "/probe?target=QMNAME" requests, following the pattern of the
+snmp and blackbox exporters. The target is found through the configured CCDT, so the
+list of queue managers can be managed in the Prometheus scrape configuration rather than
+by running a collector for each of them.
+
+Each target gets its own connection, which is kept between scrapes and dropped when
+it has not been used for a while. As any name that looks like a queue manager's is accepted,
+the number of targets is limited by probeMaxTargets; a value of 0 means no limit. The data
+for a probe always comes from a collection made during that request. The first collection
+after connecting is always empty, so a collection is made and thrown away when connecting.
+Even so, the first scrape after connecting might only have a few published metrics as the
+subscriptions are still being set up.
+*/
+
+import (
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/sinks/promsink"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+type probeTarget struct {
+	mutex    sync.Mutex
+	c        *pipeline.Collector
+	sink     *promsink.Sink
+	lastUsed time.Time
+}
+
+var (
+	probeMutex   sync.Mutex
+	probeTargets = make(map[string]*probeTarget)
+
+	// The characters permitted in a queue manager name
+	qMgrNameRegexp = regexp.MustCompile(`^[A-Za-z0-9._%/]{1,48}$`)
+)
+
+func probeHandler(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "'target' parameter must be specified", http.StatusBadRequest)
+		return
+	}
+	if !qMgrNameRegexp.MatchString(target) {
+		http.Error(w, "'target' parameter is not a valid queue manager name", http.StatusBadRequest)
+		return
+	}
+	if config.cf.CC.CcdtUrl == "" {
+		http.Error(w, "Probes need a CCDT to be configured with 'ccdtUrl'", http.StatusBadRequest)
+		return
+	}
+
+	pt := getProbeTarget(target)
+	if pt == nil {
+		http.Error(w, "Too many probe targets are already in use", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Concurrent scrapes of the same target are done one at a time
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+
+	probeStartTime := time.Now()
+	success := pt.collect()
+
+	successGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: config.namespace,
+		Name:      "probe_success",
+		Help:      "Whether the probe of the queue manager succeeded",
+	})
+	durationGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: config.namespace,
+		Name:      "probe_duration_seconds",
+		Help:      "How long the probe took to complete in seconds",
+	})
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(successGauge)
+	reg.MustRegister(durationGauge)
+
+	if success {
+		successGauge.Set(1)
+		reg.MustRegister(pt.sink)
+	}
+	durationGauge.Set(time.Since(probeStartTime).Seconds())
+
+	registryHandler(reg).ServeHTTP(w, r)
+}
+
+// Find the cached connection for a target, or create a new entry for it. If there are
+// already too many targets, there is no entry.
+func getProbeTarget(target string) *probeTarget {
+	probeMutex.Lock()
+	defer probeMutex.Unlock()
+
+	pt, ok := probeTargets[target]
+	if !ok {
+		if config.probeMaxTargets > 0 && len(probeTargets) >= config.probeMaxTargets {
+			log.Warnf("Cannot probe %s as there are already %d probe targets", target, len(probeTargets))
+			return nil
+		}
+
+		// The target inherits the main configuration, but the connection details
+		// have to come from the CCDT
+		cm := new(cf.Config)
+		*cm = config.cf
+		cm.QMgrName = target
+		cm.CC.ConnName = ""
+		cm.CC.Channel = ""
+		cm.CC.ClientMode = true
+		cm.CC.SingleConnect = true
+
+		pt = &probeTarget{
+			c:    pipeline.NewCollector(cm),
+			sink: newSink(),
+		}
+		probeTargets[target] = pt
+	}
+	pt.lastUsed = time.Now()
+	return pt
+}
+
+// Connect if necessary, and update the sink with a new collection. The return value
+// says whether there is any data to report.
+func (pt *probeTarget) collect() bool {
+	c := pt.c
+	if !c.Connected() {
+		// Don't keep trying a failed queue manager on every scrape
+		if !c.ReconnectDue() {
+			return false
+		}
+		if err := c.Connect(); err != nil {
+			log.Errorf("Probe connection to %s has failed: %v", c.QMgrName(), err)
+			return false
+		}
+		// There's nothing in the first collection, and this scrape ought to get something
+		if batch, err := c.Collect(); batch == nil {
+			log.Errorf("Probe of %s has failed: %v", c.QMgrName(), err)
+			c.Disconnect()
+			return false
+		}
+	}
+
+	batch, err := c.Collect()
+	if batch == nil {
+		log.Errorf("Probe of %s has failed: %v", c.QMgrName(), err)
+		c.Disconnect()
+		return false
+	}
+	if err != nil {
+		log.Debugf("Probe of %s had status error %v", c.QMgrName(), err)
+	}
+	_ = pt.sink.Write(batch)
+	return true
+}
+
+// Drop the connections that have not been used recently. This runs for the life of the process.
+func expireProbeTargets() {
+	for {
+		time.Sleep(config.probeIdleTimeoutDuration / 2)
+
+		// New scrapes can carry on with the other targets while waiting for these
+		var expired []*probeTarget
+		probeMutex.Lock()
+		for target, pt := range probeTargets {
+			if time.Since(pt.lastUsed) > config.probeIdleTimeoutDuration {
+				log.Infof("Disconnecting idle probe target %s", target)
+				delete(probeTargets, target)
+				expired = append(expired, pt)
+			}
+		}
+		probeMutex.Unlock()
+
+		// Wait for any scrape that is still using the connection
+		for _, pt := range expired {
+			pt.mutex.Lock()
+			pt.c.Disconnect()
+			pt.mutex.Unlock()
+		}
+	}
+}
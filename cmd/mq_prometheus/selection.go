@@ -0,0 +1,78 @@
+package main
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+A scrape of the metrics path can ask for part of the data, with parameters such as
+"collect[]=queue&collect[]=channel" for the object types, and "name[]=PAY.*" for the
+objects. Different Prometheus jobs can then get different subsets at different intervals.
+Without the parameters, the scrape gets everything as usual.
+
+When the scrapes drive the collection, only the status that was asked for is polled. With
+a collectInterval, the background collection is not affected and the parameters just
+choose what is reported.
+*/
+
+import (
+	"net/http"
+
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/sinks/promsink"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// metricsHandler serves the scrapes that have a selection, and passes the others to the full handler
+func metricsHandler(full http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if len(q["collect[]"]) == 0 && len(q["name[]"]) == 0 {
+			full.ServeHTTP(w, r)
+			return
+		}
+
+		sel, err := pipeline.NewSelection(q["collect[]"], q["name[]"])
+		if err != nil {
+			http.Error(w, "'collect[]' parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if config.collectIntervalDuration == 0 {
+			mutex.Lock()
+			log.Debugf("IBMMQ Collect started for selection %v %v", q["collect[]"], q["name[]"])
+			for _, c := range collectors {
+				collectQMgr(c, sel)
+			}
+			mutex.Unlock()
+		}
+
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(sink.Selected(sel))
+		registryHandler(reg).ServeHTTP(w, r)
+	})
+}
+
+// The promhttp handler can't give the units that are part of the OpenMetrics scheme
+func registryHandler(g prometheus.Gatherer) http.Handler {
+	if config.openMetricsBool {
+		return promsink.OpenMetricsHandler(g)
+	}
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
@@ -26,36 +26,22 @@ map between integer values internally and boolean state which is all we really c
 
 import (
 	"sync/atomic"
+	"time"
 )
 
 // These are really booleans but I'm using atomic updates
 // to avoid potential races (though they'd likely be harmless) between
 // the main code and the callbacks
 type status struct {
-	connectedOnce   int32
-	connectedQMgr   int32
-	collectorEnd    int32
-	collectorSilent int32
-	firstCollection int32
+	connectedOnce int32
+	collectorEnd  int32
+	lastSnapshot  int64 // When the most recent collection from all the queue managers finished
 }
 
 var (
 	st status
 )
 
-func isConnectedQMgr() bool {
-	b := atomic.LoadInt32(&st.connectedQMgr)
-	return b != 0
-}
-
-func setConnectedQMgr(b bool) {
-	if b {
-		atomic.StoreInt32(&st.connectedQMgr, 1)
-	} else {
-		atomic.StoreInt32(&st.connectedQMgr, 0)
-	}
-}
-
 func isCollectorEnd() bool {
 	b := atomic.LoadInt32(&st.collectorEnd)
 	return b != 0
@@ -69,32 +55,6 @@ func setCollectorEnd(b bool) {
 	}
 }
 
-func isFirstCollection() bool {
-	b := atomic.LoadInt32(&st.firstCollection)
-	return b != 0
-}
-
-func setFirstCollection(b bool) {
-	if b {
-		atomic.StoreInt32(&st.firstCollection, 1)
-	} else {
-		atomic.StoreInt32(&st.firstCollection, 0)
-	}
-}
-
-func isCollectorSilent() bool {
-	b := atomic.LoadInt32(&st.collectorSilent)
-	return b != 0
-}
-
-func setCollectorSilent(b bool) {
-	if b {
-		atomic.StoreInt32(&st.collectorSilent, 1)
-	} else {
-		atomic.StoreInt32(&st.collectorSilent, 0)
-	}
-}
-
 func setConnectedOnce(b bool) {
 	if b {
 		atomic.StoreInt32(&st.connectedOnce, 1)
@@ -107,3 +67,16 @@ func isConnectedOnce() bool {
 	b := atomic.LoadInt32(&st.connectedOnce)
 	return b != 0
 }
+
+func setLastSnapshot(t time.Time) {
+	atomic.StoreInt64(&st.lastSnapshot, t.UnixNano())
+}
+
+// The zero time is returned if there has not yet been a complete collection
+func getLastSnapshot() time.Time {
+	ns := atomic.LoadInt64(&st.lastSnapshot)
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
@@ -0,0 +1,287 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+Running a collector with -checkConfig reads and verifies all of its configuration, and then
+exits without connecting to MQ. The exit code is 0 if everything is OK.
+
+The YAML file is normally parsed leniently, so that a misspelt key is simply ignored. When
+checking, the file is parsed strictly instead. Every unknown key is reported, with a suggestion
+of what might have been meant, as are values that are the wrong type. Booleans and numbers that
+are held as strings in the YAML structures are recognised by the "default" tag on their fields,
+and checked as well. The rest of the checks, such as for durations and object patterns, are the
+same ones that are always made by VerifyConfig and by each collector.
+*/
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The format of the yaml package's error for an unknown key
+var unknownKeyRegexp = regexp.MustCompile(`^line (\d+): field (\S+) not found in type (\S+)$`)
+
+// What we know about the YAML structure: the keys in each struct type, and where that struct
+// is first found in the file
+type yamlModel struct {
+	types    []string
+	keys     map[string][]string
+	sections map[string]string
+	ignore   map[string]bool // Top-level keys that are not part of the structure
+}
+
+// Whether we've been asked to only check the configuration. The ReadConfigFile caller does
+// not pass the main configuration, so look at the parameter directly.
+func checkingConfig() bool {
+	if p, ok := configParms[envVarKey("global", "checkConfig")]; ok {
+		return *(p.loc).(*bool)
+	}
+	return false
+}
+
+/*
+ExitIfChecking is called by each collector once its configuration has been completely read
+and verified. If it was only asked to check that configuration, then it's done.
+*/
+func ExitIfChecking(cm *Config) {
+	if !cm.CheckConfig {
+		return
+	}
+	if cm.ConfigFile != "" {
+		fmt.Fprintf(os.Stderr, "Configuration in %s is valid\n", cm.ConfigFile)
+	} else {
+		fmt.Fprintf(os.Stderr, "Configuration is valid\n")
+	}
+	os.Exit(0)
+}
+
+// Parse a file's contents strictly, and return every problem that's found. Syntax errors
+// have already been reported when the file was first read.
+func (m *yamlModel) check(data []byte, cmy interface{}) []string {
+	var problems []string
+
+	err := yaml.UnmarshalStrict(data, cmy)
+	if te, ok := err.(*yaml.TypeError); ok {
+		for _, e := range te.Errors {
+			if p := m.explain(e); p != "" {
+				problems = append(problems, p)
+			}
+		}
+	} else if err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
+
+// Print all the problems, and turn them into a single error
+func reportProblems(f string, problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	return fmt.Errorf("Found %d problem(s) in configuration %s", len(problems), f)
+}
+
+func newYamlModel(t reflect.Type) *yamlModel {
+	m := &yamlModel{
+		keys:     make(map[string][]string),
+		sections: make(map[string]string),
+		ignore:   make(map[string]bool),
+	}
+	m.add(t, "")
+	return m
+}
+
+// Walk the structure, noting the keys that are valid at each level
+func (m *yamlModel) add(t reflect.Type, path string) {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		m.add(t.Elem(), path)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	name := t.String()
+	if _, ok := m.keys[name]; ok {
+		return
+	}
+	m.types = append(m.types, name)
+	m.sections[name] = path
+
+	keys := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := yamlKey(f)
+		if key == "" {
+			continue
+		}
+		keys = append(keys, key)
+		m.add(f.Type, joinPath(path, key))
+	}
+	m.keys[name] = keys
+}
+
+// Turn the yaml package's message about an unknown key into something more helpful. An
+// empty string means that it's not really a problem.
+func (m *yamlModel) explain(e string) string {
+	match := unknownKeyRegexp.FindStringSubmatch(e)
+	if match == nil {
+		return e
+	}
+	line, key, typeName := match[1], match[2], match[3]
+	if m.sections[typeName] == "" && m.ignore[key] {
+		return ""
+	}
+
+	section := "the top level"
+	if s := m.sections[typeName]; s != "" {
+		section = "the " + s + " section"
+	}
+	msg := fmt.Sprintf("line %s: unknown key %s in %s", line, key, section)
+
+	if best := closest(key, m.keys[typeName]); best != "" {
+		return msg + ". Did you mean " + best + "?"
+	}
+
+	// Perhaps it's a good key, but in the wrong place
+	for _, t := range m.types {
+		for _, k := range m.keys[t] {
+			if strings.EqualFold(k, key) && m.sections[t] != "" {
+				return msg + ". Did you mean " + m.sections[t] + "." + k + "?"
+			}
+		}
+	}
+	return msg
+}
+
+/*
+Some booleans and numbers are held as strings in the YAML structures, so that a missing
+value can be told apart from an explicit false or 0. A value that does not parse would
+otherwise be quietly replaced by the default.
+*/
+func checkStringValues(v reflect.Value, path string) []string {
+	var problems []string
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			problems = checkStringValues(v.Elem(), path)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			problems = append(problems, checkStringValues(v.Index(i), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			key := yamlKey(f)
+			if key == "" {
+				continue
+			}
+			fieldPath := joinPath(path, key)
+			fv := v.Field(i)
+
+			def, hasDefault := f.Tag.Lookup("default")
+			if f.Type.Kind() == reflect.String && hasDefault && fv.String() != "" {
+				if _, err := strconv.ParseBool(def); err == nil {
+					if _, err := strconv.ParseBool(fv.String()); err != nil {
+						problems = append(problems, fmt.Sprintf("%s: value \"%s\" is not true or false", fieldPath, fv.String()))
+					}
+				} else if _, err := strconv.Atoi(def); err == nil {
+					if _, err := strconv.Atoi(fv.String()); err != nil {
+						problems = append(problems, fmt.Sprintf("%s: value \"%s\" is not a number", fieldPath, fv.String()))
+					}
+				}
+			} else {
+				problems = append(problems, checkStringValues(fv, fieldPath)...)
+			}
+		}
+	}
+	return problems
+}
+
+// The yaml package uses the tag if there is one, or the lowercased field name
+func yamlKey(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+	key := strings.Split(f.Tag.Get("yaml"), ",")[0]
+	if key == "-" {
+		return ""
+	}
+	if key == "" {
+		key = strings.ToLower(f.Name)
+	}
+	return key
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Find the key that's most like the unknown one, if any of them are close enough
+// to be a plausible typo
+func closest(key string, keys []string) string {
+	best := ""
+	limit := max(2, len(key)/4)
+	for _, k := range keys {
+		d := editDistance(strings.ToLower(key), strings.ToLower(k))
+		if d <= limit {
+			best = k
+			limit = d - 1
+		}
+	}
+	return best
+}
+
+// The Levenshtein distance between two strings
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
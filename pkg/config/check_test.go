@@ -0,0 +1,171 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The same shape as a collector's YAML structure
+type checkTestConfig struct {
+	Global     ConfigYGlobal
+	Connection ConfigYConnection
+	Objects    ConfigYObjects
+	Health     ConfigYHealth `yaml:"health"`
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		problems []string // Each problem must contain the string
+	}{
+		{
+			name: "valid",
+			yaml: "global:\n  pollInterval: 10s\nconnection:\n  queueManager: QM1\n  tls:\n    cipherSpec: ANY\n",
+		},
+		{
+			name:     "misspelt key",
+			yaml:     "connection:\n  queueManger: QM1\n",
+			problems: []string{"line 2: unknown key queueManger in the connection section. Did you mean queueManager?"},
+		},
+		{
+			name:     "case of key",
+			yaml:     "global:\n  pollinterval: 10s\n",
+			problems: []string{"unknown key pollinterval in the global section. Did you mean pollInterval?"},
+		},
+		{
+			name:     "nested section",
+			yaml:     "connection:\n  tls:\n    cipherSpc: ANY\n",
+			problems: []string{"line 3: unknown key cipherSpc in the connection.tls section. Did you mean cipherSpec?"},
+		},
+		{
+			name:     "key in the wrong section",
+			yaml:     "global:\n  queueManager: QM1\n",
+			problems: []string{"unknown key queueManager in the global section. Did you mean connection.queueManager?"},
+		},
+		{
+			name:     "unknown section",
+			yaml:     "globl:\n  pollInterval: 10s\n",
+			problems: []string{"unknown key globl in the top level. Did you mean global?"},
+		},
+		{
+			name:     "nothing like it",
+			yaml:     "connection:\n  xyzzy: 1\n",
+			problems: []string{"line 2: unknown key xyzzy in the connection section"},
+		},
+		{
+			name:     "wrong type",
+			yaml:     "health:\n  staleIntervals: often\n",
+			problems: []string{"cannot unmarshal !!str `often` into int"},
+		},
+		{
+			name: "every problem",
+			yaml: "connection:\n  queueManger: QM1\n  chanel: X\n",
+			problems: []string{
+				"line 2: unknown key queueManger",
+				"line 3: unknown key chanel in the connection section. Did you mean channel?",
+			},
+		},
+	}
+
+	m := newYamlModel(reflect.TypeOf(&checkTestConfig{}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.check([]byte(tt.yaml), &checkTestConfig{})
+			if len(got) != len(tt.problems) {
+				t.Fatalf("Problems are %q, expected %q", got, tt.problems)
+			}
+			for i, p := range tt.problems {
+				if !strings.Contains(got[i], p) {
+					t.Errorf("Problem %q does not contain %q", got[i], p)
+				}
+			}
+		})
+	}
+}
+
+// The booleans and numbers that are held as strings are checked once the file is parsed
+func TestCheckStringValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      checkTestConfig
+		problems []string
+	}{
+		{name: "empty"},
+		{
+			name: "good values",
+			cfg: checkTestConfig{
+				Global:     ConfigYGlobal{UseObjectStatus: "false", SimulateQueues: "20"},
+				Connection: ConfigYConnection{TLS: ConfigYTLS{OCSPCheckExtensions: "true"}},
+			},
+		},
+		{
+			name:     "bad boolean",
+			cfg:      checkTestConfig{Global: ConfigYGlobal{UseObjectStatus: "maybe"}},
+			problems: []string{`global.useObjectStatus: value "maybe" is not true or false`},
+		},
+		{
+			name:     "bad number",
+			cfg:      checkTestConfig{Global: ConfigYGlobal{SimulateQueues: "ten"}},
+			problems: []string{`global.simulateQueues: value "ten" is not a number`},
+		},
+		{
+			name:     "nested",
+			cfg:      checkTestConfig{Connection: ConfigYConnection{TLS: ConfigYTLS{CDPCheckExtensions: "no"}}},
+			problems: []string{`connection.tls.cdpCheckExtensions: value "no" is not true or false`},
+		},
+		{
+			name:     "no default",
+			cfg:      checkTestConfig{Global: ConfigYGlobal{LogLevel: "loud"}},
+			problems: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkStringValues(reflect.ValueOf(&tt.cfg), "")
+			if !reflect.DeepEqual(got, tt.problems) {
+				t.Errorf("Problems are %q, expected %q", got, tt.problems)
+			}
+		})
+	}
+}
+
+func TestClosest(t *testing.T) {
+	keys := []string{"queueManager", "queues", "channels", "tls"}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "queueManger", want: "queueManager"},
+		{key: "QUEUES", want: "queues"},
+		{key: "queue", want: "queues"},
+		{key: "chanels", want: "channels"},
+		{key: "tsl", want: "tls"},
+		{key: "topics", want: ""},
+	}
+	for _, tt := range tests {
+		if got := closest(tt.key, keys); got != tt.want {
+			t.Errorf("closest(%q) is %q, expected %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -36,7 +37,9 @@ import (
 
 // Configuration attributes shared by all the monitor sample programs
 type Config struct {
-	ConfigFile string
+	ConfigFile  string
+	CheckConfig bool
+	PrintConfig string
 
 	QMgrName string
 	ReplyQ   string
@@ -61,6 +64,19 @@ type Config struct {
 	MonitoredSubscriptionsFile string
 	QueueSubscriptionSelector  string
 
+	// Patterns of metric names to report or to drop, and the filter that's built from them
+	MetricsInclude string
+	MetricsExclude string
+	MetricFilter   *MetricFilter
+
+	// Rules to change the names and labels of the metrics, from the YAML file
+	RelabelConfigs []ConfigYRelabel
+	Relabel        RelabelRules
+
+	// Labels taken from the attributes of each queue or channel, from the YAML file
+	ObjectLabelConfigs []ConfigYObjectLabel
+	ObjectLabels       ObjectLabels
+
 	metadataTags        string
 	metadataValues      string
 	MetadataTagsArray   []string
@@ -72,6 +88,10 @@ type Config struct {
 	pollInterval         string
 	PollIntervalDuration time.Duration
 
+	// Different polling intervals for some of the objects, from the YAML file
+	PollTierConfigs []ConfigYPollTier
+	PollTiers       []PollTier
+
 	// How frequently should we redrive the list of known queues from the wildcards
 	rediscoverInterval string
 	RediscoverDuration time.Duration
@@ -79,6 +99,46 @@ type Config struct {
 	// Might be mounted into a container
 	PasswordFile string
 
+	// Where the password comes from, if CC.Password was given as a reference such as
+	// "exec:/usr/local/bin/getpw QM1". It's looked up again each time we connect, and
+	// kept for the secretTTL.
+	PasswordRef       string
+	secretTTL         string
+	SecretTTLDuration time.Duration
+
+	// What to do when the queue manager connection fails. The interval between attempts to
+	// reconnect doubles after each failure, up to the maximum.
+	KeepRunning                  bool
+	reconnectInterval            string
+	ReconnectIntervalDuration    time.Duration
+	reconnectMaxInterval         string
+	ReconnectMaxIntervalDuration time.Duration
+
+	// An optional HTTP server for liveness and readiness probes. It is not started unless
+	// the port is set.
+	HealthHost           string
+	HealthPort           string
+	HealthStaleIntervals int
+	HealthReadyWhen      string // "any" or "all" of the queue managers have to be ready
+
+	// Save the data from each collection, or use saved data instead of connecting to a queue manager
+	RecordFile string
+	ReplayFile string
+	ReplayLoop bool
+
+	// Generate made-up data instead of connecting to a queue manager
+	Simulate         bool
+	SimulateQueues   int
+	SimulateChannels int
+	SimulateReplicas int
+
+	// How often to check whether the configuration file has changed, so it can be reloaded
+	reloadInterval         string
+	ReloadIntervalDuration time.Duration
+
+	// Settings for a TLS client connection, so that a CCDT is not needed
+	TLS TLSConfig
+
 	CC mqmetric.ConnectionConfig
 }
 
@@ -87,6 +147,8 @@ type ConfigParm struct {
 	defaultValue interface{}
 	parmType     int
 	userSet      bool
+	fromEnv      bool
+	fromCli      bool
 
 	cliName    string
 	envSection string // These also match the YAML elements
@@ -99,14 +161,24 @@ const (
 	defaultPollInterval       = "0s"
 	defaultTZOffset           = "0h"
 	defaultRediscoverInterval = "1h"
+	defaultReconnectInterval  = "5s"
+	defaultReconnectMax       = "5m"
+	defaultReloadInterval     = "0s"
+	defaultSecretTTL          = "5m"
 	defaultWaitInterval       = 3   // seconds
 	defaultWaitIntervalStr    = "3" // seconds
+	defaultStaleIntervals     = 3
+	defaultHealthReadyWhen    = "any"
+	defaultSimulateQueues     = 10
+	defaultSimulateChannels   = 5
+	defaultSimulateReplicas   = 2
 )
 
 const (
-	CP_STR  = 0
-	CP_INT  = 1
-	CP_BOOL = 2
+	CP_STR      = 0
+	CP_INT      = 1
+	CP_BOOL     = 2
+	CP_STR_LIST = 3 // A string flag that can be repeated, giving a comma-separated list
 )
 
 var configParms map[string]*ConfigParm
@@ -193,6 +265,8 @@ func InitConfig(cm *Config) {
 	AddParm(&cm.MonitoredSubscriptionsFile, "", CP_STR, "ibmmq.monitoredSubscriptionsFile", "objects", "subscriptionsFile", "File with patterns of subscriptions to monitor")
 	AddParm(&cm.QueueSubscriptionSelector, "", CP_STR, "ibmmq.queueSubscriptionSelector", "filters", "queueSubscriptionSelector", "Resource topic selection for queues")
 	AddParm(&cm.CC.ShowInactiveChannels, false, CP_BOOL, "ibmmq.showInactiveChannels", "filters", "showInactiveChannels", "Show inactive channels (not just stopped ones)")
+	AddParm(&cm.MetricsInclude, "", CP_STR, "ibmmq.metricsInclude", "filters", "metricsInclude", "Patterns of metric names to report")
+	AddParm(&cm.MetricsExclude, "", CP_STR, "ibmmq.metricsExclude", "filters", "metricsExclude", "Patterns of metric names not to report")
 
 	AddParm(&cm.CC.HideSvrConnJobname, false, CP_BOOL, "ibmmq.hideSvrConnJobname", "filters", "hideSvrConnJobname", "Don't create multiple instances of SVRCONN information")
 	AddParm(&cm.CC.HideAMQPClientId, false, CP_BOOL, "ibmmq.hideAMQPClientId", "filters", "hideAMQPClientId", "Don't create multiple instances of ClientID information")
@@ -211,6 +285,32 @@ func InitConfig(cm *Config) {
 
 	AddParm(&cm.CC.ClientMode, false, CP_BOOL, "ibmmq.client", "connection", "clientConnection", "Connect as MQ client")
 
+	AddParm(&cm.TLS.KeyRepository, "", CP_STR, "ibmmq.tlsKeyRepository", "connection", "tlsKeyRepository", "Key repository for a TLS client connection")
+	AddParm(&cm.TLS.KeyRepositoryPassword, "", CP_STR, "ibmmq.tlsKeyRepositoryPassword", "connection", "tlsKeyRepositoryPassword", "Password for the key repository. The stash file is used if this is not set")
+	AddParm(&cm.TLS.CipherSpec, "", CP_STR, "ibmmq.tlsCipherSpec", "connection", "tlsCipherSpec", "CipherSpec for a TLS client connection")
+	AddParm(&cm.TLS.CertificateLabel, "", CP_STR, "ibmmq.tlsCertificateLabel", "connection", "tlsCertificateLabel", "Label of the client certificate in the key repository")
+	AddParm(&cm.TLS.PeerName, "", CP_STR, "ibmmq.tlsPeerName", "connection", "tlsPeerName", "Distinguished name pattern that the queue manager's certificate must match")
+	AddParm(&cm.TLS.RevocationCheck, "", CP_STR, "ibmmq.tlsRevocationCheck", "connection", "tlsRevocationCheck", "Certificate revocation checks - required, optional or disabled")
+	AddParm(&cm.TLS.OCSPCheckExtensions, true, CP_BOOL, "ibmmq.tlsOcspCheckExtensions", "connection", "tlsOcspCheckExtensions", "Use OCSP servers named in certificates")
+	AddParm(&cm.TLS.CDPCheckExtensions, true, CP_BOOL, "ibmmq.tlsCdpCheckExtensions", "connection", "tlsCdpCheckExtensions", "Use CRL distribution points named in certificates")
+
+	AddParm(&cm.KeepRunning, true, CP_BOOL, "ibmmq.keepRunning", "connection", "keepRunning", "Continue running after queue manager disconnection")
+	AddParm(&cm.reconnectInterval, defaultReconnectInterval, CP_STR, "ibmmq.reconnectInterval", "connection", "reconnectInterval", "How long to wait before the first attempt to reconnect")
+	AddParm(&cm.reconnectMaxInterval, defaultReconnectMax, CP_STR, "ibmmq.reconnectMaxInterval", "connection", "reconnectMaxInterval", "Longest wait between attempts to reconnect")
+
+	AddParm(&cm.HealthHost, "0.0.0.0", CP_STR, "ibmmq.healthHost", "health", "host", "Address for the health check server")
+	AddParm(&cm.HealthPort, "", CP_STR, "ibmmq.healthPort", "health", "port", "Port for the health check server. Not started if empty")
+	AddParm(&cm.HealthStaleIntervals, defaultStaleIntervals, CP_INT, "ibmmq.healthStaleIntervals", "health", "staleIntervals", "Collection intervals without success before the collector is not ready")
+	AddParm(&cm.HealthReadyWhen, defaultHealthReadyWhen, CP_STR, "ibmmq.healthReadyWhen", "health", "readyWhen", "Whether any or all of the queue managers must be ready for the collector to be ready")
+
+	AddParm(&cm.RecordFile, "", CP_STR, "ibmmq.recordFile", "global", "recordFile", "File to save the data from each collection")
+	AddParm(&cm.ReplayFile, "", CP_STR, "ibmmq.replayFile", "global", "replayFile", "File of saved data to use instead of connecting to the queue manager")
+	AddParm(&cm.ReplayLoop, false, CP_BOOL, "ibmmq.replayLoop", "global", "replayLoop", "Start again at the end of the replay file")
+	AddParm(&cm.Simulate, false, CP_BOOL, "ibmmq.simulate", "global", "simulate", "Generate simulated data instead of connecting to the queue manager")
+	AddParm(&cm.SimulateQueues, defaultSimulateQueues, CP_INT, "ibmmq.simulateQueues", "global", "simulateQueues", "Number of queues to simulate")
+	AddParm(&cm.SimulateChannels, defaultSimulateChannels, CP_INT, "ibmmq.simulateChannels", "global", "simulateChannels", "Number of channels to simulate")
+	AddParm(&cm.SimulateReplicas, defaultSimulateReplicas, CP_INT, "ibmmq.simulateReplicas", "global", "simulateReplicas", "Number of NativeHA replicas to simulate")
+
 	AddParm(&cm.TZOffsetString, defaultTZOffset, CP_STR, "ibmmq.tzOffset", "global", "tzOffset", "Time difference between collector and queue manager")
 	AddParm(&cm.pollInterval, defaultPollInterval, CP_STR, "pollInterval", "global", "pollInterval", "Frequency of issuing object status checks")
 	AddParm(&cm.rediscoverInterval, defaultRediscoverInterval, CP_STR, "rediscoverInterval", "global", "rediscoverInterval", "Frequency of expanding wildcards for monitored queues")
@@ -218,9 +318,13 @@ func InitConfig(cm *Config) {
 	// parameter for now to aid testing, to override, and to ensure it's given in the MQ-known format
 	// such as "Fr_FR"
 	AddParm(&cm.Locale, "", CP_STR, "locale", "global", "locale", "Locale for translated metric descriptions")
+	AddParm(&cm.reloadInterval, defaultReloadInterval, CP_STR, "reloadInterval", "global", "reloadInterval", "Frequency of checking for changes to the configuration file. 0 means only reload on SIGHUP")
+	AddParm(&cm.secretTTL, defaultSecretTTL, CP_STR, "secretTTL", "global", "secretTTL", "How long to keep a password or token that was given as an env:, file: or exec: reference")
 
 	// A YAML configuration file can be used instead of all the preceding parameters
-	AddParm(&cm.ConfigFile, "", CP_STR, "f", "global", "configurationFile", "Configuration file")
+	AddParm(&cm.ConfigFile, "", CP_STR_LIST, "f", "global", "configurationFile", "Configuration file. Several files are merged in order if they are comma-separated or the flag is repeated")
+	AddParm(&cm.CheckConfig, false, CP_BOOL, "checkConfig", "global", "checkConfig", "Check the configuration and exit without connecting to MQ")
+	AddParm(&cm.PrintConfig, "", CP_STR, "printConfig", "global", "printConfig", "Print the resolved configuration as yaml or json, and exit without connecting to MQ")
 
 	AddParm(&cfMoved.QueueSubscriptionSelector, "", CP_STR, "removed.queueSubscriptionSelector", "objects", "queueSubscriptionSelector", "Moved to FILTERS section")
 	AddParm(&cfMoved.ShowInactiveChannels, "", CP_STR, "removed.showInactiveChannels", "objects", "showInactiveChannels", "Moved to FILTERS section")
@@ -252,6 +356,7 @@ func ParseParms() error {
 		//fmt.Printf("Env var for %s is '%s' (string)\n", envVarName, envValue)
 		if envValue != "" {
 			p.userSet = true
+			p.fromEnv = true
 		}
 
 		switch p.parmType {
@@ -271,6 +376,12 @@ func ParseParms() error {
 				p.defaultValue, _ = strconv.ParseBool(envValue)
 			}
 			flag.BoolVar((p.loc).(*bool), p.cliName, (p.defaultValue).(bool), p.usage)
+		case CP_STR_LIST:
+			if envValue != "" {
+				p.defaultValue = envValue
+			}
+			*(p.loc).(*string) = (p.defaultValue).(string)
+			flag.Var(&listFlag{loc: (p.loc).(*string)}, p.cliName, p.usage)
 		}
 
 	}
@@ -287,6 +398,7 @@ func ParseParms() error {
 		if found {
 			//fmt.Printf("Flag %s was set on cmd line\n", n)
 			p.userSet = true
+			p.fromCli = true
 		} else {
 			//fmt.Printf("Flag %s was NOT set on cmd line\n", n)
 		}
@@ -423,6 +535,21 @@ func VerifyConfig(cm *Config, fullCf interface{}) error {
 		}
 	}
 
+	if err == nil {
+		cm.MetricFilter, err = newMetricFilter(cm.MetricsInclude, cm.MetricsExclude)
+		if err != nil {
+			err = fmt.Errorf("Invalid value for metrics filter: %v", err)
+		}
+	}
+
+	if err == nil {
+		cm.Relabel, err = newRelabelRules(cm.RelabelConfigs)
+	}
+
+	if err == nil {
+		cm.ObjectLabels, err = newObjectLabels(cm.ObjectLabelConfigs)
+	}
+
 	// Do not use VerifyPatterns for monitoredTopics or Subs as they follow a very different style
 	if err == nil {
 		if cm.TZOffsetString == "" {
@@ -446,6 +573,10 @@ func VerifyConfig(cm *Config, fullCf interface{}) error {
 		}
 	}
 
+	if err == nil {
+		cm.PollTiers, err = newPollTiers(cm.PollTierConfigs)
+	}
+
 	if err == nil {
 		if cm.rediscoverInterval == "" {
 			cm.rediscoverInterval = defaultRediscoverInterval
@@ -456,6 +587,97 @@ func VerifyConfig(cm *Config, fullCf interface{}) error {
 		}
 	}
 
+	if err == nil {
+		if cm.reconnectInterval == "" {
+			cm.reconnectInterval = defaultReconnectInterval
+		}
+		cm.ReconnectIntervalDuration, err = time.ParseDuration(cm.reconnectInterval)
+		if err != nil {
+			err = fmt.Errorf("Invalid value for reconnect interval parameter: %v", err)
+		}
+	}
+
+	if err == nil {
+		if cm.reconnectMaxInterval == "" {
+			cm.reconnectMaxInterval = defaultReconnectMax
+		}
+		cm.ReconnectMaxIntervalDuration, err = time.ParseDuration(cm.reconnectMaxInterval)
+		if err != nil {
+			err = fmt.Errorf("Invalid value for maximum reconnect interval parameter: %v", err)
+		} else if cm.ReconnectMaxIntervalDuration < cm.ReconnectIntervalDuration {
+			cm.ReconnectMaxIntervalDuration = cm.ReconnectIntervalDuration
+		}
+	}
+
+	if err == nil {
+		if cm.reloadInterval == "" {
+			cm.reloadInterval = defaultReloadInterval
+		}
+		cm.ReloadIntervalDuration, err = time.ParseDuration(cm.reloadInterval)
+		if err != nil {
+			err = fmt.Errorf("Invalid value for reload interval parameter: %v", err)
+		}
+	}
+
+	if err == nil {
+		if cm.secretTTL == "" {
+			cm.secretTTL = defaultSecretTTL
+		}
+		cm.SecretTTLDuration, err = time.ParseDuration(cm.secretTTL)
+		if err != nil {
+			err = fmt.Errorf("Invalid value for secret TTL parameter: %v", err)
+		}
+	}
+
+	if err == nil {
+		err = verifyTLS(cm)
+	}
+
+	// A password given as a reference is looked up when connecting. Until then the reference
+	// stays in the password field, so that nobody is prompted for one. An escaped literal
+	// value goes the same way, to have the escape taken off.
+	if err == nil {
+		cm.PasswordRef = ""
+		if IsLiteralSecret(cm.CC.Password) {
+			cm.PasswordRef = cm.CC.Password
+		} else if IsSecretRef(cm.CC.Password) {
+			err = CheckSecretRef(cm.CC.Password)
+			if err == nil {
+				cm.PasswordRef = cm.CC.Password
+			} else {
+				err = fmt.Errorf("Invalid value for password parameter: %v", err)
+			}
+		}
+	}
+
+	if err == nil {
+		if cm.RecordFile != "" && cm.ReplayFile != "" {
+			err = fmt.Errorf("Cannot both record and replay collection data")
+		} else if cm.Simulate && cm.ReplayFile != "" {
+			err = fmt.Errorf("Cannot both simulate and replay collection data")
+		}
+	}
+
+	if err == nil {
+		if cm.SimulateQueues < 0 || cm.SimulateChannels < 0 || cm.SimulateReplicas < 0 {
+			err = fmt.Errorf("Number of simulated objects cannot be negative")
+		}
+	}
+
+	if err == nil {
+		if cm.PrintConfig != "" && !strings.EqualFold(cm.PrintConfig, "yaml") && !strings.EqualFold(cm.PrintConfig, "json") {
+			err = fmt.Errorf("Invalid value %s for printConfig. Use yaml or json", cm.PrintConfig)
+		}
+	}
+
+	if err == nil {
+		if cm.HealthStaleIntervals < 1 {
+			err = fmt.Errorf("Health check staleIntervals must be at least 1")
+		} else if !strings.EqualFold(cm.HealthReadyWhen, "any") && !strings.EqualFold(cm.HealthReadyWhen, "all") {
+			err = fmt.Errorf("Invalid value %s for health check readyWhen. Use any or all", cm.HealthReadyWhen)
+		}
+	}
+
 	if err == nil {
 		if cfMoved.QueueSubscriptionSelector != "" {
 			err = fmt.Errorf("QueueSubscriptionSelector has moved to filters section of configuration")
@@ -478,7 +700,9 @@ func VerifyConfig(cm *Config, fullCf interface{}) error {
 		}
 	}
 
-	log.Debugf("VerifyConfig Config: %+v", fullCf)
+	if log.IsLevelEnabled(log.DebugLevel) {
+		log.Debugf("VerifyConfig Config: %s", maskSecrets(reflect.ValueOf(fullCf)))
+	}
 	if err != nil {
 		log.Debugf("VerifyConfig Error : %+v", err)
 	}
@@ -23,7 +23,7 @@ package config
 // Settings in that file can be overridden on the command line or via environment variable
 import (
 	"fmt"
-	"os"
+	"reflect"
 	"strconv"
 
 	"gopkg.in/yaml.v2"
@@ -35,10 +35,20 @@ type ConfigYGlobal struct {
 	UsePublications    string `yaml:"usePublications" default:"true"`
 	LogLevel           string `yaml:"logLevel"`
 	MetaPrefix         string
-	PollInterval       string `yaml:"pollInterval"`
-	RediscoverInterval string `yaml:"rediscoverInterval"`
-	TZOffset           string `yaml:"tzOffset"`
+	PollInterval       string            `yaml:"pollInterval"`
+	PollTiers          []ConfigYPollTier `yaml:"pollTiers"`
+	RediscoverInterval string            `yaml:"rediscoverInterval"`
+	TZOffset           string            `yaml:"tzOffset"`
 	Locale             string
+	RecordFile         string `yaml:"recordFile"`
+	ReplayFile         string `yaml:"replayFile"`
+	ReplayLoop         string `yaml:"replayLoop" default:"false"`
+	Simulate           string `yaml:"simulate" default:"false"`
+	SimulateQueues     string `yaml:"simulateQueues" default:"10"`
+	SimulateChannels   string `yaml:"simulateChannels" default:"5"`
+	SimulateReplicas   string `yaml:"simulateReplicas" default:"2"`
+	ReloadInterval     string `yaml:"reloadInterval"`
+	SecretTTL          string `yaml:"secretTTL"`
 }
 type ConfigYConnection struct {
 	QueueManager     string `yaml:"queueManager"`
@@ -52,11 +62,32 @@ type ConfigYConnection struct {
 	CcdtUrl          string            `yaml:"ccdtUrl"`
 	ConnName         string            `yaml:"connName"`
 	Channel          string            `yaml:"channel"`
-	WaitInterval     string            `yaml:"waitInterval"`
+	WaitInterval     string            `yaml:"waitInterval" default:"3"`
+	KeepRunning      string            `yaml:"keepRunning" default:"true"`
+	Reconnect        string            `yaml:"reconnectInterval"`
+	ReconnectMax     string            `yaml:"reconnectMaxInterval"`
 	MetadataTags     []string          `yaml:"metadataTags"`
 	MetadataValues   []string          `yaml:"metadataValues"`
 	MetadataMap      map[string]string `yaml:"metadataMap"`
+	TLS              ConfigYTLS        `yaml:"tls"`
 }
+type ConfigYTLS struct {
+	KeyRepository         string `yaml:"keyRepository"`
+	KeyRepositoryPassword string `yaml:"keyRepositoryPassword"`
+	CipherSpec            string `yaml:"cipherSpec"`
+	CertificateLabel      string `yaml:"certificateLabel"`
+	PeerName              string `yaml:"peerName"`
+	RevocationCheck       string `yaml:"revocationCheck"`
+	OCSPCheckExtensions   string `yaml:"ocspCheckExtensions" default:"true"`
+	CDPCheckExtensions    string `yaml:"cdpCheckExtensions" default:"true"`
+}
+type ConfigYHealth struct {
+	Host           string
+	Port           string
+	StaleIntervals int    `yaml:"staleIntervals"`
+	ReadyWhen      string `yaml:"readyWhen"`
+}
+
 type ConfigYObjects struct {
 	Queues       []string
 	Channels     []string
@@ -72,11 +103,40 @@ type ConfigYObjects struct {
 }
 
 type ConfigYFilters struct {
-	HideSvrConnJobname        string   `yaml:"hideSvrConnJobname" default:"false"`
-	HideAMQPClientId          string   `yaml:"hideAMQPClientId" default:"false"`
-	HideMQTTClientId          string   `yaml:"hideMQTTClientId" default:"false"`
-	ShowInactiveChannels      string   `yaml:"showInactiveChannels" default:"false"`
-	QueueSubscriptionSelector []string `yaml:"queueSubscriptionSelector"`
+	HideSvrConnJobname        string         `yaml:"hideSvrConnJobname" default:"false"`
+	HideAMQPClientId          string         `yaml:"hideAMQPClientId" default:"false"`
+	HideMQTTClientId          string         `yaml:"hideMQTTClientId" default:"false"`
+	ShowInactiveChannels      string         `yaml:"showInactiveChannels" default:"false"`
+	QueueSubscriptionSelector []string       `yaml:"queueSubscriptionSelector"`
+	Metrics                   ConfigYMetrics `yaml:"metrics"`
+}
+
+// Patterns of metric names, applied to every collector
+type ConfigYMetrics struct {
+	Include []string
+	Exclude []string
+}
+
+// A collector that can send its data to several places at once has a list of outputs,
+// each with its own type and interval. The fields used depend on the type - for example
+// "file" only makes sense for JSON. There are no command-line or env var equivalents for
+// these; they only come from the YAML file.
+type ConfigYOutput struct {
+	Type          string
+	Interval      string
+	OverrideCType string `yaml:"overrideCType"`
+	// Prometheus
+	Port        string
+	Host        string
+	MetricsPath string `yaml:"metricsPath"`
+	Namespace   string
+	// OpenTelemetry
+	Endpoint string
+	Insecure string `default:"false"`
+	// JSON
+	File      string
+	OneLine   string `yaml:"oneline" default:"false"`
+	RecordMax int    `yaml:"recordmax"`
 }
 
 type ConfigMoved struct {
@@ -86,13 +146,34 @@ type ConfigMoved struct {
 
 var cfMoved ConfigMoved
 
+/*
+ReadConfigFile fills in the YAML structure from the configuration. The name can be a
+comma-separated list of files, which are merged in order, along with anything that they
+include. When we are only checking the configuration, the files are parsed strictly and
+all the problems are reported.
+*/
 func ReadConfigFile(f string, cmy interface{}) error {
+	var data []byte
+	var problems []string
 
-	data, e2 := os.ReadFile(f)
+	layers, e2 := readLayers(f)
+	if e2 == nil && checkingConfig() {
+		problems = checkLayers(layers, reflect.TypeOf(cmy))
+	}
+	if e2 == nil {
+		data, e2 = mergeLayers(layers)
+	}
 	if e2 == nil {
 		// fmt.Printf("Unparsed Data is\n %s\n", string(data))
 		e2 = yaml.Unmarshal(data, cmy)
 	}
+	if e2 == nil && checkingConfig() {
+		problems = append(problems, checkStringValues(reflect.ValueOf(cmy), "")...)
+		e2 = reportProblems(f, problems)
+	}
+	if e2 == nil {
+		noteYamlKeys(layers)
+	}
 
 	return e2
 }
@@ -125,6 +206,23 @@ func asInt(s string, def int) int {
 	}
 }
 
+// The health check server is configured in its own section, which is optional for every collector
+func CopyYamlHealthConfig(cm *Config, cyh ConfigYHealth) {
+	tmpHost := CopyParmIfNotSetStr("health", "host", cyh.Host)
+	if tmpHost != "" {
+		cm.HealthHost = tmpHost
+	}
+	cm.HealthPort = CopyParmIfNotSetStr("health", "port", cyh.Port)
+	tmpInt := CopyParmIfNotSetInt("health", "staleIntervals", cyh.StaleIntervals)
+	if tmpInt != 0 {
+		cm.HealthStaleIntervals = tmpInt
+	}
+	tmpReady := CopyParmIfNotSetStr("health", "readyWhen", cyh.ReadyWhen)
+	if tmpReady != "" {
+		cm.HealthReadyWhen = tmpReady
+	}
+}
+
 // This handles the configuration parameters that are common to all the collectors. The individual
 // collectors call similar code for their own specific attributes
 func CopyYamlConfig(cm *Config, cyg ConfigYGlobal, cyc ConfigYConnection, cyo ConfigYObjects, cyf ConfigYFilters) {
@@ -139,13 +237,25 @@ func CopyYamlConfig(cm *Config, cyg ConfigYGlobal, cyc ConfigYConnection, cyo Co
 	cm.CC.HideMQTTClientId = CopyParmIfNotSetBool("filters", "hideMQTTClientId", AsBool(cyf.HideMQTTClientId, false))
 
 	cm.QueueSubscriptionSelector = CopyParmIfNotSetStrArray("filters", "queueSubscriptionSelector", cyf.QueueSubscriptionSelector)
+	cm.MetricsInclude = CopyParmIfNotSetStrArray("filters", "metricsInclude", cyf.Metrics.Include)
+	cm.MetricsExclude = CopyParmIfNotSetStrArray("filters", "metricsExclude", cyf.Metrics.Exclude)
 
 	cm.LogLevel = CopyParmIfNotSetStr("global", "logLevel", cyg.LogLevel)
 	cm.MetaPrefix = CopyParmIfNotSetStr("global", "metaprefix", cyg.MetaPrefix)
 	cm.pollInterval = CopyParmIfNotSetStr("global", "pollInterval", cyg.PollInterval)
+	cm.PollTierConfigs = cyg.PollTiers
 	cm.rediscoverInterval = CopyParmIfNotSetStr("global", "rediscoverInterval", cyg.RediscoverInterval)
 	cm.TZOffsetString = CopyParmIfNotSetStr("global", "tzOffset", cyg.TZOffset)
 	cm.Locale = CopyParmIfNotSetStr("global", "locale", cyg.Locale)
+	cm.reloadInterval = CopyParmIfNotSetStr("global", "reloadInterval", cyg.ReloadInterval)
+	cm.secretTTL = CopyParmIfNotSetStr("global", "secretTTL", cyg.SecretTTL)
+	cm.RecordFile = CopyParmIfNotSetStr("global", "recordFile", cyg.RecordFile)
+	cm.ReplayFile = CopyParmIfNotSetStr("global", "replayFile", cyg.ReplayFile)
+	cm.ReplayLoop = CopyParmIfNotSetBool("global", "replayLoop", AsBool(cyg.ReplayLoop, false))
+	cm.Simulate = CopyParmIfNotSetBool("global", "simulate", AsBool(cyg.Simulate, false))
+	cm.SimulateQueues = CopyParmIfNotSetInt("global", "simulateQueues", asInt(cyg.SimulateQueues, defaultSimulateQueues))
+	cm.SimulateChannels = CopyParmIfNotSetInt("global", "simulateChannels", asInt(cyg.SimulateChannels, defaultSimulateChannels))
+	cm.SimulateReplicas = CopyParmIfNotSetInt("global", "simulateReplicas", asInt(cyg.SimulateReplicas, defaultSimulateReplicas))
 
 	cm.QMgrName = CopyParmIfNotSetStr("connection", "queueManager", cyc.QueueManager)
 	cm.CC.CcdtUrl = CopyParmIfNotSetStr("connection", "ccdtUrl", cyc.CcdtUrl)
@@ -156,6 +266,20 @@ func CopyYamlConfig(cm *Config, cyg ConfigYGlobal, cyc ConfigYConnection, cyo Co
 	cm.CC.Password = CopyParmIfNotSetStr("connection", "password", cyc.Password)
 	cm.PasswordFile = CopyParmIfNotSetStr("connection", "passwordFile", cyc.PasswordFile)
 
+	// The tls settings are nested in the YAML, but not in the flag and environment variable names
+	cm.TLS.KeyRepository = CopyParmIfNotSetStr("connection", "tlsKeyRepository", cyc.TLS.KeyRepository)
+	cm.TLS.KeyRepositoryPassword = CopyParmIfNotSetStr("connection", "tlsKeyRepositoryPassword", cyc.TLS.KeyRepositoryPassword)
+	cm.TLS.CipherSpec = CopyParmIfNotSetStr("connection", "tlsCipherSpec", cyc.TLS.CipherSpec)
+	cm.TLS.CertificateLabel = CopyParmIfNotSetStr("connection", "tlsCertificateLabel", cyc.TLS.CertificateLabel)
+	cm.TLS.PeerName = CopyParmIfNotSetStr("connection", "tlsPeerName", cyc.TLS.PeerName)
+	cm.TLS.RevocationCheck = CopyParmIfNotSetStr("connection", "tlsRevocationCheck", cyc.TLS.RevocationCheck)
+	cm.TLS.OCSPCheckExtensions = CopyParmIfNotSetBool("connection", "tlsOcspCheckExtensions", AsBool(cyc.TLS.OCSPCheckExtensions, true))
+	cm.TLS.CDPCheckExtensions = CopyParmIfNotSetBool("connection", "tlsCdpCheckExtensions", AsBool(cyc.TLS.CDPCheckExtensions, true))
+
+	cm.KeepRunning = CopyParmIfNotSetBool("connection", "keepRunning", AsBool(cyc.KeepRunning, true))
+	cm.reconnectInterval = CopyParmIfNotSetStr("connection", "reconnectInterval", cyc.Reconnect)
+	cm.reconnectMaxInterval = CopyParmIfNotSetStr("connection", "reconnectMaxInterval", cyc.ReconnectMax)
+
 	tmpInt := CopyParmIfNotSetStr("connection", "waitInterval", cyc.WaitInterval)
 	cm.CC.WaitInterval = asInt(tmpInt, defaultWaitInterval)
 
@@ -0,0 +1,147 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+The values in the YAML file can refer to environment variables and to other files, so that
+the same file can be used in several environments. These forms are replaced in each value:
+
+	${VAR}          The value of the environment variable. It is an error if it is not set.
+	${VAR:-value}   The value of the environment variable, or the given value if it is not set or is empty
+	${file:/path}   The contents of the file, without any trailing newline
+	$${             A literal "${"
+
+The replacement is done after the file has been parsed, on the values only, so it works in
+every section, including the ones for the individual collectors. What is put in is never
+parsed as YAML, and so it can contain any characters, including newlines. Keys and comments
+are left alone. A value that is nothing but a reference becomes a number or boolean if that
+is exactly what it is replaced by, so that it can be used for the fields that need one.
+*/
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var interpolateRegexp = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// Replace all the references in the values of a parsed file, working down through the
+// mappings and lists. The path says where any error was found.
+func interpolate(v interface{}, path string) (interface{}, error) {
+	var err error
+
+	switch val := v.(type) {
+	case yaml.MapSlice:
+		for i := range val {
+			val[i].Value, err = interpolate(val[i].Value, joinPath(path, fmt.Sprint(val[i].Key)))
+			if err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i := range val {
+			val[i], err = interpolate(val[i], fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+		}
+	case string:
+		return interpolateString(val, path)
+	}
+	return v, nil
+}
+
+func interpolateString(s string, path string) (interface{}, error) {
+	var err error
+
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	whole := false
+	if loc := interpolateRegexp.FindStringIndex(s); loc[0] == 0 && loc[1] == len(s) && s != "$${" {
+		whole = true
+	}
+
+	val := interpolateRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		if ref == "$${" {
+			return "${"
+		}
+		v, e := lookupReference(ref[2 : len(ref)-1])
+		if e != nil {
+			err = fmt.Errorf("%s: %v", path, e)
+		}
+		return v
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if whole {
+		return scalarValue(val), nil
+	}
+	return val, nil
+}
+
+// Give a replaced value the type it would have had if it had been written in the file, as
+// long as nothing is lost by doing that. Anything else, such as "007" or "yes", stays as a string.
+func scalarValue(s string) interface{} {
+	var v interface{}
+
+	if strings.ContainsAny(s, "#\r\n") || yaml.Unmarshal([]byte(s), &v) != nil {
+		return s
+	}
+	switch v.(type) {
+	case int, int64, uint64, float64, bool:
+		if fmt.Sprint(v) == s {
+			return v
+		}
+	}
+	return s
+}
+
+func lookupReference(ref string) (string, error) {
+	if path, ok := strings.CutPrefix(ref, "file:"); ok {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("cannot read file for ${%s}: %v", ref, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+
+	name, def, hasDefault := strings.Cut(ref, ":-")
+	if name == "" {
+		return "", fmt.Errorf("missing environment variable name in ${%s}", ref)
+	}
+	val, ok := os.LookupEnv(name)
+	if hasDefault && val == "" {
+		return def, nil
+	}
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return val, nil
+}
@@ -0,0 +1,210 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+type interpolateTestConnection struct {
+	QueueManager string `yaml:"queueManager"`
+	ConnName     string `yaml:"connName"`
+	Password     string `yaml:"password"`
+}
+
+type interpolateTestGlobal struct {
+	MaxErrors int      `yaml:"maxErrors"`
+	OneLine   bool     `yaml:"oneLine"`
+	Labels    []string `yaml:"labels"`
+}
+
+type interpolateTestConfig struct {
+	Connection interpolateTestConnection
+	Global     interpolateTestGlobal
+}
+
+func TestInterpolate(t *testing.T) {
+	dir := t.TempDir()
+	pwFile := filepath.Join(dir, "pw")
+	if err := os.WriteFile(pwFile, []byte("a: b # c\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MQ_TEST_QMGR", "QM1")
+	t.Setenv("MQ_TEST_SPECIAL", "x: y #z")
+	t.Setenv("MQ_TEST_LINES", "one\ntwo")
+	t.Setenv("MQ_TEST_NUMBER", "42")
+	t.Setenv("MQ_TEST_ZEROS", "007")
+	t.Setenv("MQ_TEST_EMPTY", "")
+
+	tests := []struct {
+		name string
+		yaml string
+		want func(c *interpolateTestConfig) interface{}
+		val  interface{}
+		err  string
+	}{
+		{
+			name: "variable",
+			yaml: "connection:\n  queueManager: ${MQ_TEST_QMGR}\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Connection.QueueManager },
+			val:  "QM1",
+		},
+		{
+			name: "part of a value",
+			yaml: "connection:\n  connName: \"${MQ_TEST_HOST:-localhost}(${MQ_TEST_PORT:-1414})\"\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Connection.ConnName },
+			val:  "localhost(1414)",
+		},
+		{
+			name: "default for empty variable",
+			yaml: "connection:\n  queueManager: ${MQ_TEST_EMPTY:-QM2}\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Connection.QueueManager },
+			val:  "QM2",
+		},
+		{
+			name: "special characters",
+			yaml: "connection:\n  password: ${MQ_TEST_SPECIAL}\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Connection.Password },
+			val:  "x: y #z",
+		},
+		{
+			name: "file contents",
+			yaml: "connection:\n  password: ${file:" + pwFile + "}\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Connection.Password },
+			val:  "a: b # c",
+		},
+		{
+			name: "newline",
+			yaml: "connection:\n  password: ${MQ_TEST_LINES}\n  queueManager: QM3\n",
+			want: func(c *interpolateTestConfig) interface{} {
+				return c.Connection.Password + "|" + c.Connection.QueueManager
+			},
+			val: "one\ntwo|QM3",
+		},
+		{
+			name: "number",
+			yaml: "global:\n  maxErrors: ${MQ_TEST_NUMBER}\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Global.MaxErrors },
+			val:  42,
+		},
+		{
+			name: "boolean",
+			yaml: "global:\n  oneLine: ${MQ_TEST_BOOL:-true}\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Global.OneLine },
+			val:  true,
+		},
+		{
+			name: "leading zeros kept",
+			yaml: "connection:\n  password: ${MQ_TEST_ZEROS}\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Connection.Password },
+			val:  "007",
+		},
+		{
+			name: "list element",
+			yaml: "global:\n  labels:\n  - ${MQ_TEST_QMGR}\n  - other\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Global.Labels },
+			val:  []string{"QM1", "other"},
+		},
+		{
+			name: "escaped",
+			yaml: "connection:\n  password: \"$${MQ_TEST_QMGR}x${MQ_TEST_QMGR}\"\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Connection.Password },
+			val:  "${MQ_TEST_QMGR}xQM1",
+		},
+		{
+			name: "comments and keys left alone",
+			yaml: "# ${MQ_TEST_NOT_SET}\nconnection:\n  queueManager: QM4 # ${MQ_TEST_NOT_SET}\n",
+			want: func(c *interpolateTestConfig) interface{} { return c.Connection.QueueManager },
+			val:  "QM4",
+		},
+		{
+			name: "unset variable",
+			yaml: "connection:\n  queueManager: QM1\n  password: ${MQ_TEST_NOT_SET}\n",
+			err:  "connection.password: environment variable MQ_TEST_NOT_SET is not set",
+		},
+		{
+			name: "missing file",
+			yaml: "connection:\n  password: ${file:" + filepath.Join(dir, "none") + "}\n",
+			err:  "cannot read file",
+		},
+		{
+			name: "missing variable name",
+			yaml: "global:\n  labels:\n  - ${:-x}\n",
+			err:  "global.labels[0]: missing environment variable name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(f, []byte(tt.yaml), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			layers, err := readLayers(f)
+			var data []byte
+			if err == nil {
+				data, err = mergeLayers(layers)
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var c interpolateTestConfig
+			if err := yaml.Unmarshal(data, &c); err != nil {
+				t.Fatalf("Cannot parse the result: %v\n%s", err, data)
+			}
+			if got := tt.want(&c); !reflect.DeepEqual(got, tt.val) {
+				t.Errorf("Value is %#v, expected %#v", got, tt.val)
+			}
+		})
+	}
+}
+
+// The strict check is made on the file as it was written, so a reference in a field
+// that needs a number isn't a problem, but a misspelt key still is, on the right line
+func TestCheckInterpolatedLayer(t *testing.T) {
+	t.Setenv("MQ_TEST_NUMBER", "42")
+	f := filepath.Join(t.TempDir(), "config.yaml")
+	text := "# A comment\n\nglobal:\n  maxErrors: ${MQ_TEST_NUMBER}\n  oneLin: true\n"
+	if err := os.WriteFile(f, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	layers, err := readLayers(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := checkLayers(layers, reflect.TypeOf(&interpolateTestConfig{}))
+	if len(problems) != 1 || !strings.Contains(problems[0], "line 5: unknown key oneLin") {
+		t.Errorf("Problems are %q", problems)
+	}
+}
@@ -0,0 +1,342 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+The YAML configuration can be split across several files, which are merged in order. That
+lets a shared base, a per-site overlay and a per-queue manager overlay be kept separately
+instead of being concatenated into one file. The files can be given as a comma-separated list,
+or by repeating the -f flag. A file can also have an "include" key at the top level, with a
+file or list of files that it builds on; those are merged first, and the including file is
+applied on top of them. Relative names are relative to the including file.
+
+Mappings are merged key by key, at every level. Any other value in a later file replaces the
+earlier one, including lists. To add to a list instead, put a "+" after the key:
+
+	objects:
+	  queues+:
+	  - "APP.*"
+
+Where there is no earlier list, the "+" is just dropped. Using it on anything other than a
+list is an error. A single file with no includes or "+" keys is used exactly as it is.
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const includeKey = "include"
+
+// One of the files that make up the configuration. The data has had any references
+// replaced, while the raw text is kept so that checking can report the right line numbers.
+type configLayer struct {
+	file string
+	raw  []byte
+	data []byte
+}
+
+// The files that were read for the current configuration, in the order they were merged
+var configLayers []string
+
+// Turns "key+:" back into "key:" so that a single layer can be checked strictly without
+// changing its line numbers
+var appendKeyRegexp = regexp.MustCompile(`(?m)^(\s*(?:-\s+)?[A-Za-z0-9_]+)\+:`)
+
+// A flag that can be given more than once, building a comma-separated list
+type listFlag struct {
+	loc *string
+	set bool
+}
+
+func (lf *listFlag) String() string {
+	if lf.loc == nil {
+		return ""
+	}
+	return *lf.loc
+}
+
+func (lf *listFlag) Set(s string) error {
+	if lf.set && *lf.loc != "" {
+		*lf.loc += "," + s
+	} else {
+		*lf.loc = s
+	}
+	lf.set = true
+	return nil
+}
+
+/*
+ConfigFiles returns the names of all the files that were read for the configuration,
+including any that were included by others. It's empty if there is no configuration file.
+*/
+func ConfigFiles() []string {
+	return configLayers
+}
+
+// Read each of the files in order, along with anything they include
+func readLayers(f string) ([]configLayer, error) {
+	var layers []configLayer
+
+	for _, name := range strings.Split(f, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		l, err := readLayer(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l...)
+	}
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("No configuration file was given")
+	}
+	return layers, nil
+}
+
+// Read a single file, with the files that it includes coming before it in the returned list.
+// The chain of files being read is passed down to catch a loop of includes.
+func readLayer(f string, chain []string) ([]configLayer, error) {
+	var layers []configLayer
+	var top yaml.MapSlice
+
+	for _, c := range chain {
+		if c == f {
+			return nil, fmt.Errorf("Configuration file %s includes itself", f)
+		}
+	}
+
+	raw, err := os.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(raw, &top)
+
+	// A file without any references is used exactly as it is
+	data := raw
+	if err == nil && bytes.Contains(raw, []byte("${")) {
+		var v interface{}
+		v, err = interpolate(top, "")
+		if err == nil {
+			top = v.(yaml.MapSlice)
+			data, err = yaml.Marshal(top)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Configuration file %s: %v", f, err)
+	}
+
+	for _, item := range top {
+		if item.Key != includeKey {
+			continue
+		}
+		includes, err := includedFiles(f, item.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, inc := range includes {
+			l, err := readLayer(inc, append(chain, f))
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, l...)
+		}
+	}
+
+	return append(layers, configLayer{file: f, raw: raw, data: data}), nil
+}
+
+func includedFiles(f string, v interface{}) ([]string, error) {
+	var names []string
+
+	switch val := v.(type) {
+	case string:
+		names = []string{val}
+	case []interface{}:
+		for _, n := range val {
+			s, ok := n.(string)
+			if !ok {
+				return nil, fmt.Errorf("Configuration file %s: include must be a file name or list of file names", f)
+			}
+			names = append(names, s)
+		}
+	default:
+		return nil, fmt.Errorf("Configuration file %s: include must be a file name or list of file names", f)
+	}
+
+	for i, n := range names {
+		if !filepath.IsAbs(n) {
+			names[i] = filepath.Join(filepath.Dir(f), n)
+		}
+	}
+	return names, nil
+}
+
+// Combine the layers into a single YAML document
+func mergeLayers(layers []configLayer) ([]byte, error) {
+	var merged yaml.MapSlice
+
+	if len(layers) == 1 && !appendKeyRegexp.Match(layers[0].data) && !hasInclude(layers[0].data) {
+		return layers[0].data, nil
+	}
+
+	for _, l := range layers {
+		var top yaml.MapSlice
+		if err := yaml.Unmarshal(l.data, &top); err != nil {
+			return nil, fmt.Errorf("Configuration file %s: %v", l.file, err)
+		}
+		var err error
+		merged, err = mergeMaps(merged, top, "")
+		if err != nil {
+			return nil, fmt.Errorf("Configuration file %s: %v", l.file, err)
+		}
+	}
+
+	return yaml.Marshal(merged)
+}
+
+func hasInclude(data []byte) bool {
+	var top yaml.MapSlice
+	if yaml.Unmarshal(data, &top) != nil {
+		return false
+	}
+	for _, item := range top {
+		if item.Key == includeKey {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply one mapping on top of another
+func mergeMaps(base yaml.MapSlice, overlay yaml.MapSlice, path string) (yaml.MapSlice, error) {
+	for _, item := range overlay {
+		key := fmt.Sprint(item.Key)
+		if path == "" && key == includeKey {
+			continue
+		}
+
+		appending := false
+		if k, ok := strings.CutSuffix(key, "+"); ok {
+			key = k
+			appending = true
+		}
+		keyPath := joinPath(path, key)
+
+		newList, isList := item.Value.([]interface{})
+		if appending && !isList {
+			return nil, fmt.Errorf("%s+ can only be used to add to a list", keyPath)
+		}
+
+		var old interface{}
+		i := indexOf(base, key)
+		if i >= 0 {
+			old = base[i].Value
+		}
+		oldMap, ok1 := old.(yaml.MapSlice)
+		newMap, ok2 := item.Value.(yaml.MapSlice)
+
+		var v interface{}
+		var err error
+		switch {
+		case appending && i >= 0:
+			oldList, ok := old.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s+ can only be used to add to a list", keyPath)
+			}
+			v, err = normaliseValue(newList, keyPath)
+			if err == nil {
+				v = append(append([]interface{}{}, oldList...), v.([]interface{})...)
+			}
+		case ok1 && ok2:
+			v, err = mergeMaps(append(yaml.MapSlice{}, oldMap...), newMap, keyPath)
+		default:
+			// Anything new, or replaced, may still have "+" keys inside it
+			v, err = normaliseValue(item.Value, keyPath)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if i < 0 {
+			base = append(base, yaml.MapItem{Key: key, Value: v})
+		} else {
+			base[i].Value = v
+		}
+	}
+	return base, nil
+}
+
+// Take the "+" off any keys in a value that has nothing to be merged with. There's no
+// earlier list to add to, so the list is used as it is.
+func normaliseValue(v interface{}, path string) (interface{}, error) {
+	switch val := v.(type) {
+	case yaml.MapSlice:
+		return mergeMaps(nil, val, path)
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, elem := range val {
+			e, err := normaliseValue(elem, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = e
+		}
+		return list, nil
+	}
+	return v, nil
+}
+
+func indexOf(m yaml.MapSlice, key string) int {
+	for i, item := range m {
+		if fmt.Sprint(item.Key) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// When checking, each file is checked on its own, as it was written, so that the line numbers
+// in any errors are correct. The include key, and the "+" on keys, are not part of the
+// configuration structure. A reference might not be the right type until it has been
+// replaced; any problem with the replaced value is found when the merged files are parsed.
+func checkLayers(layers []configLayer, t reflect.Type) []string {
+	var problems []string
+
+	m := newYamlModel(t)
+	m.ignore[includeKey] = true
+	for _, l := range layers {
+		data := appendKeyRegexp.ReplaceAll(l.raw, []byte("$1:"))
+		for _, p := range m.check(data, reflect.New(t.Elem()).Interface()) {
+			if strings.Contains(p, "`${") {
+				continue
+			}
+			problems = append(problems, l.file+": "+p)
+		}
+	}
+	return problems
+}
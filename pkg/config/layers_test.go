@@ -0,0 +1,189 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMergeLayers(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers []string
+		want   string // Empty when an error is expected
+		err    string
+	}{
+		{
+			name:   "single file unchanged",
+			layers: []string{"objects:\n  queues:\n  - A.*\n"},
+			want:   "objects:\n  queues:\n  - A.*\n",
+		},
+		{
+			name:   "single file with +",
+			layers: []string{"objects:\n  queues+:\n  - A.*\n"},
+			want:   "objects:\n  queues:\n  - A.*\n",
+		},
+		{
+			name:   "maps merged by key",
+			layers: []string{"connection:\n  queueManager: QM1\n  clientConnection: true\n", "connection:\n  queueManager: QM2\n"},
+			want:   "connection:\n  queueManager: QM2\n  clientConnection: true\n",
+		},
+		{
+			name:   "list replaced",
+			layers: []string{"objects:\n  queues:\n  - A.*\n", "objects:\n  queues:\n  - B.*\n"},
+			want:   "objects:\n  queues:\n  - B.*\n",
+		},
+		{
+			name:   "list added to",
+			layers: []string{"objects:\n  queues:\n  - A.*\n", "objects:\n  queues+:\n  - B.*\n"},
+			want:   "objects:\n  queues:\n  - A.*\n  - B.*\n",
+		},
+		{
+			name:   "section missing from the base",
+			layers: []string{"connection:\n  queueManager: QM1\n", "objects:\n  queues+:\n  - B.*\n"},
+			want:   "connection:\n  queueManager: QM1\nobjects:\n  queues:\n  - B.*\n",
+		},
+		{
+			name:   "key missing from the base",
+			layers: []string{"objects:\n  channels:\n  - SYSTEM.*\n", "objects:\n  queues+:\n  - B.*\n"},
+			want:   "objects:\n  channels:\n  - SYSTEM.*\n  queues:\n  - B.*\n",
+		},
+		{
+			name:   "inside a list",
+			layers: []string{"global:\n  pollInterval: 10s\n", "queueManagers:\n- connection:\n    queueManager: QM1\n  objects:\n    queues+:\n    - B.*\n"},
+			want:   "global:\n  pollInterval: 10s\nqueueManagers:\n- connection:\n    queueManager: QM1\n  objects:\n    queues:\n    - B.*\n",
+		},
+		{
+			name:   "inside a replaced value",
+			layers: []string{"objects: none\n", "objects:\n  queues+:\n  - B.*\n"},
+			want:   "objects:\n  queues:\n  - B.*\n",
+		},
+		{
+			name:   "+ on a map",
+			layers: []string{"connection+:\n  queueManager: QM1\n"},
+			err:    "connection+ can only be used to add to a list",
+		},
+		{
+			name:   "+ on a scalar",
+			layers: []string{"global:\n  pollInterval: 10s\n", "global:\n  pollInterval+: 20s\n"},
+			err:    "global.pollInterval+ can only be used to add to a list",
+		},
+		{
+			name:   "+ on a list over a scalar",
+			layers: []string{"objects:\n  queues: A.*\n", "objects:\n  queues+:\n  - B.*\n"},
+			err:    "objects.queues+ can only be used to add to a list",
+		},
+		{
+			name:   "+ on a map inside a list",
+			layers: []string{"queueManagers:\n- objects+:\n    queues:\n    - B.*\n"},
+			err:    "queueManagers[0].objects+ can only be used to add to a list",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var layers []configLayer
+			for i, l := range tt.layers {
+				layers = append(layers, configLayer{file: "layer" + string(rune('1'+i)), data: []byte(l)})
+			}
+
+			got, err := mergeLayers(layers)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Merged to\n%s\nexpected\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// A "+" that's left on a key would be ignored by the unmarshalling, so the setting would be lost
+func TestMergeLayersUnmarshal(t *testing.T) {
+	var cfg struct {
+		Objects struct {
+			Queues []string `yaml:"queues"`
+		} `yaml:"objects"`
+	}
+
+	data, err := mergeLayers([]configLayer{
+		{file: "base", data: []byte("connection:\n  queueManager: QM1\n")},
+		{file: "site", data: []byte("objects:\n  queues+:\n  - APP.*\n")},
+	})
+	if err == nil {
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Objects.Queues) != 1 || cfg.Objects.Queues[0] != "APP.*" {
+		t.Errorf("Queues are %v, expected [APP.*]", cfg.Objects.Queues)
+	}
+}
+
+func TestReadLayersIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data string) string {
+		f := filepath.Join(dir, name)
+		if err := os.WriteFile(f, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	write("base.yaml", "objects:\n  queues:\n  - A.*\n")
+	write("site.yaml", "include: base.yaml\nobjects:\n  queues+:\n  - B.*\n")
+	top := write("qm.yaml", "include:\n- site.yaml\nconnection:\n  queueManager: QM1\n")
+	write("loop.yaml", "include: loop.yaml\n")
+
+	layers, err := readLayers(top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, l := range layers {
+		files = append(files, filepath.Base(l.file))
+	}
+	if strings.Join(files, ",") != "base.yaml,site.yaml,qm.yaml" {
+		t.Errorf("Layers are %v", files)
+	}
+
+	got, err := mergeLayers(layers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "objects:\n  queues:\n  - A.*\n  - B.*\nconnection:\n  queueManager: QM1\n"
+	if string(got) != want {
+		t.Errorf("Merged to\n%s\nexpected\n%s", got, want)
+	}
+
+	if _, err = readLayers(filepath.Join(dir, "loop.yaml")); err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Errorf("Expected an include loop error, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+Individual metrics can be dropped with the filters.metrics section. The patterns are matched
+against the normalised name of the metric, which is the object type and the metric name
+joined by "_", such as "queue_avoided_bytes" or "channel_messages". That's the name used by
+the Prometheus collector, without its namespace, and the other collectors use the same
+names for their own series or fields.
+
+A pattern is a glob, where "*" matches any sequence of characters and "?" matches one
+character, unless it starts and ends with "/" when it is a regular expression. Both have to
+match the whole name.
+
+	filters:
+	  metrics:
+	    include:
+	    - "queue_*"
+	    - "qmgr_*"
+	    exclude:
+	    - "queue_avoided_*"
+	    - "/queue_(get|put)_.+_bytes/"
+
+If there are any include patterns, then a metric has to match one of them. A metric that
+matches an exclude pattern is always dropped. As the lists are held in the same
+comma-separated form as the object lists, a pattern cannot contain a comma.
+*/
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MetricFilter decides which metrics are reported. A nil MetricFilter allows everything.
+type MetricFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// Build the filter from the configured lists. There's no filter at all if both are empty.
+func newMetricFilter(include string, exclude string) (*MetricFilter, error) {
+	var err error
+
+	if include == "" && exclude == "" {
+		return nil, nil
+	}
+
+	f := &MetricFilter{}
+	f.include, err = compileMetricPatterns(include)
+	if err == nil {
+		f.exclude, err = compileMetricPatterns(exclude)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func compileMetricPatterns(patterns string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+
+	for _, p := range strings.Split(patterns, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		var expr string
+		if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			expr = "^(?:" + p[1:len(p)-1] + ")$"
+		} else {
+			expr = "^" + strings.ReplaceAll(strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*"), `\?`, ".") + "$"
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid metric pattern %s: %v", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// Allows says whether a metric, given by its normalised name, should be reported
+func (f *MetricFilter) Allows(name string) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(f.include) > 0 && !matchesAny(f.include, name) {
+		return false
+	}
+	return !matchesAny(f.exclude, name)
+}
+
+func matchesAny(res []*regexp.Regexp, name string) bool {
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
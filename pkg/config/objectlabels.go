@@ -0,0 +1,251 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+Labels can be added to each queue or channel from the attributes of the object itself, so that
+information kept in MQ - such as the application that owns a queue - can be used to group
+the series. They are set up in the objectLabels section of the YAML file:
+
+	objectLabels:
+	- label: app
+	  attribute: descr
+	  key: app
+	- label: owner
+	  attribute: custom
+	  key: OWNER
+	- label: persistent
+	  attribute: defpsist
+	- label: team
+	  objectType: channel
+	  attribute: descr
+	  regex: "team-(\\w+)"
+	  default: unknown
+
+The attributes are:
+
+	descr     The description of a queue or channel
+	custom    The CUSTOM attribute of a queue
+	cluster   The cluster that a queue is in
+	usage     NORMAL or XMITQ for a queue
+	deftype   How a queue was defined, such as Predefined or PermDyn
+	defpsist  The default persistence of a queue, as YES or NO
+
+A key picks one item from the attribute. In a description, items are written as "key=value",
+separated by ";" or ","; in the CUSTOM attribute they are written as "KEY(value)" separated
+by spaces, which is how MQ itself uses that attribute. The keys are not case-sensitive. A
+regex is then matched against what's left, and the first group is used if there is one or
+else the whole match. When there's nothing to use, the default is the value, and the label
+is left off completely if there's no default either. The objectType is queue or channel, and
+is queue by default; the channel attributes are only available for MQ channels, not AMQP or
+MQTT. There are no command-line or env var equivalents for these settings.
+*/
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+)
+
+// The object attributes that can be used for labels
+const (
+	ObjectAttrDescr    = "descr"
+	ObjectAttrCustom   = "custom"
+	ObjectAttrCluster  = "cluster"
+	ObjectAttrUsage    = "usage"
+	ObjectAttrDefType  = "deftype"
+	ObjectAttrDefPsist = "defpsist"
+
+	objectTypeQueue   = "queue"
+	objectTypeChannel = "channel"
+)
+
+// Which attributes each object type has
+var objectLabelAttributes = map[string][]string{
+	objectTypeQueue:   {ObjectAttrDescr, ObjectAttrCustom, ObjectAttrCluster, ObjectAttrUsage, ObjectAttrDefType, ObjectAttrDefPsist},
+	objectTypeChannel: {ObjectAttrDescr},
+}
+
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// The "KEY(value)" items in a CUSTOM attribute
+var customItemRegexp = regexp.MustCompile(`([^\s(]+)\(([^)]*)\)`)
+
+// ConfigYObjectLabel is one of the labels, as it is written in the YAML file
+type ConfigYObjectLabel struct {
+	Label      string
+	ObjectType string `yaml:"objectType"`
+	Attribute  string
+	Key        string
+	Regex      string
+	Default    string
+}
+
+type objectLabel struct {
+	label      string
+	objectType string
+	attribute  string
+	key        string
+	regex      *regexp.Regexp
+	def        string
+}
+
+// ObjectLabels are the checked versions of the configured labels. A nil list adds nothing.
+type ObjectLabels []*objectLabel
+
+// The labels only come from the YAML file
+func CopyYamlObjectLabelConfig(cm *Config, labels []ConfigYObjectLabel) {
+	cm.ObjectLabelConfigs = labels
+}
+
+// Called from VerifyConfig to check the labels and compile their regexes
+func newObjectLabels(configs []ConfigYObjectLabel) (ObjectLabels, error) {
+	var labels ObjectLabels
+
+	for i, c := range configs {
+		l := &objectLabel{
+			label:      c.Label,
+			objectType: strings.ToLower(c.ObjectType),
+			attribute:  strings.ToLower(c.Attribute),
+			key:        c.Key,
+			def:        c.Default,
+		}
+		if l.objectType == "" {
+			l.objectType = objectTypeQueue
+		}
+
+		if !labelNameRegexp.MatchString(l.label) || strings.HasPrefix(l.label, "__") {
+			return nil, fmt.Errorf("objectLabels entry %d: invalid label name \"%s\"", i, c.Label)
+		}
+		attrs, ok := objectLabelAttributes[l.objectType]
+		if !ok {
+			return nil, fmt.Errorf("objectLabels entry %d: unknown objectType %s. Use queue or channel", i, c.ObjectType)
+		}
+		valid := false
+		for _, a := range attrs {
+			if a == l.attribute {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("objectLabels entry %d: %s is not an attribute that can be used for a %s. Use one of %s", i, c.Attribute, l.objectType, strings.Join(attrs, ", "))
+		}
+		if l.key != "" && l.attribute != ObjectAttrDescr && l.attribute != ObjectAttrCustom {
+			return nil, fmt.Errorf("objectLabels entry %d: a key can only be used with the descr or custom attributes", i)
+		}
+		if c.Regex != "" {
+			var err error
+			l.regex, err = regexp.Compile(c.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("objectLabels entry %d: invalid regex %s: %v", i, c.Regex, err)
+			}
+		}
+		labels = append(labels, l)
+	}
+	return labels, nil
+}
+
+/*
+Uses says whether any of the labels for an object type need the given attribute. It lets
+the collectors skip looking for attributes that are not otherwise available.
+*/
+func (labels ObjectLabels) Uses(objectType string, attribute string) bool {
+	for _, l := range labels {
+		if l.objectType == objectType && l.attribute == attribute {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Add puts the labels for an object into the map. The attribute function gives the value
+of one of the object's attributes, and is only called for the attributes that are needed.
+An empty value, or the DUMMY_STRING that mqmetric uses for unknown values, counts as missing.
+*/
+func (labels ObjectLabels) Add(objectType string, m map[string]string, attribute func(string) string) {
+	values := make(map[string]string)
+	for _, l := range labels {
+		if l.objectType != objectType {
+			continue
+		}
+		v, ok := values[l.attribute]
+		if !ok {
+			v = strings.TrimSpace(attribute(l.attribute))
+			if v == mqmetric.DUMMY_STRING {
+				v = ""
+			}
+			values[l.attribute] = v
+		}
+
+		v = l.value(v)
+		if v == "" {
+			v = l.def
+		}
+		if v != "" {
+			m[l.label] = v
+		}
+	}
+}
+
+// Pick out this label's part of the attribute value
+func (l *objectLabel) value(v string) string {
+	if l.key != "" {
+		if l.attribute == ObjectAttrCustom {
+			v = customItem(v, l.key)
+		} else {
+			v = descrItem(v, l.key)
+		}
+	}
+	if l.regex != nil && v != "" {
+		m := l.regex.FindStringSubmatch(v)
+		switch {
+		case m == nil:
+			v = ""
+		case len(m) > 1:
+			v = m[1]
+		default:
+			v = m[0]
+		}
+	}
+	return strings.TrimSpace(v)
+}
+
+// The value of "key=value" in a description such as "app=PAYMENTS;owner=team-x"
+func descrItem(descr string, key string) string {
+	for _, item := range strings.FieldsFunc(descr, func(r rune) bool { return r == ';' || r == ',' }) {
+		k, v, ok := strings.Cut(item, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(k), key) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// The value of "KEY(value)" in a CUSTOM attribute such as "APP(PAYMENTS) OWNER(TEAMX)"
+func customItem(custom string, key string) string {
+	for _, m := range customItemRegexp.FindAllStringSubmatch(custom, -1) {
+		if strings.EqualFold(m[1], key) {
+			return m[2]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+)
+
+func TestObjectLabels(t *testing.T) {
+	attrs := map[string]string{
+		ObjectAttrDescr:    "app=PAYMENTS; owner = team-blue,tier=1",
+		ObjectAttrCustom:   "OWNER(TEAMX) CAPEXPRY(100)",
+		ObjectAttrCluster:  mqmetric.DUMMY_STRING,
+		ObjectAttrUsage:    "NORMAL",
+		ObjectAttrDefPsist: "YES",
+	}
+
+	tests := []struct {
+		name       string
+		labels     []ConfigYObjectLabel
+		objectType string
+		want       map[string]string
+	}{
+		{
+			name:       "whole attribute",
+			labels:     []ConfigYObjectLabel{{Label: "usage", Attribute: "usage"}, {Label: "persistent", Attribute: "DefPsist"}},
+			objectType: objectTypeQueue,
+			want:       map[string]string{"usage": "NORMAL", "persistent": "YES"},
+		},
+		{
+			name:       "description keys",
+			labels:     []ConfigYObjectLabel{{Label: "app", Attribute: "descr", Key: "APP"}, {Label: "owner", Attribute: "descr", Key: "owner"}},
+			objectType: objectTypeQueue,
+			want:       map[string]string{"app": "PAYMENTS", "owner": "team-blue"},
+		},
+		{
+			name:       "custom key",
+			labels:     []ConfigYObjectLabel{{Label: "owner", Attribute: "custom", Key: "owner"}},
+			objectType: objectTypeQueue,
+			want:       map[string]string{"owner": "TEAMX"},
+		},
+		{
+			name:       "regex group",
+			labels:     []ConfigYObjectLabel{{Label: "team", Attribute: "descr", Key: "owner", Regex: `team-(\w+)`}},
+			objectType: objectTypeQueue,
+			want:       map[string]string{"team": "blue"},
+		},
+		{
+			name:       "regex without a group",
+			labels:     []ConfigYObjectLabel{{Label: "expiry", Attribute: "custom", Regex: `CAPEXPRY\(\d+\)`}},
+			objectType: objectTypeQueue,
+			want:       map[string]string{"expiry": "CAPEXPRY(100)"},
+		},
+		{
+			name: "missing values",
+			labels: []ConfigYObjectLabel{
+				{Label: "region", Attribute: "descr", Key: "region"},
+				{Label: "cluster", Attribute: "cluster"},
+				{Label: "deftype", Attribute: "deftype"},
+				{Label: "red", Attribute: "descr", Regex: "red"},
+			},
+			objectType: objectTypeQueue,
+			want:       map[string]string{},
+		},
+		{
+			name: "defaults",
+			labels: []ConfigYObjectLabel{
+				{Label: "region", Attribute: "descr", Key: "region", Default: "none"},
+				{Label: "cluster", Attribute: "cluster", Default: "none"},
+			},
+			objectType: objectTypeQueue,
+			want:       map[string]string{"region": "none", "cluster": "none"},
+		},
+		{
+			name: "object types",
+			labels: []ConfigYObjectLabel{
+				{Label: "app", Attribute: "descr", Key: "app"},
+				{Label: "tier", ObjectType: "Channel", Attribute: "descr", Key: "tier"},
+			},
+			objectType: objectTypeChannel,
+			want:       map[string]string{"tier": "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, err := newObjectLabels(tt.labels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			asked := make(map[string]int)
+			got := make(map[string]string)
+			labels.Add(tt.objectType, got, func(a string) string {
+				asked[a]++
+				return attrs[a]
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Labels are %v, expected %v", got, tt.want)
+			}
+			for a, n := range asked {
+				if n > 1 {
+					t.Errorf("Attribute %s was asked for %d times", a, n)
+				}
+			}
+		})
+	}
+}
+
+func TestObjectLabelsUses(t *testing.T) {
+	labels, err := newObjectLabels([]ConfigYObjectLabel{
+		{Label: "owner", Attribute: "custom", Key: "OWNER"},
+		{Label: "team", ObjectType: "channel", Attribute: "descr"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !labels.Uses(objectTypeQueue, ObjectAttrCustom) || labels.Uses(objectTypeQueue, ObjectAttrDescr) ||
+		!labels.Uses(objectTypeChannel, ObjectAttrDescr) || ObjectLabels(nil).Uses(objectTypeQueue, ObjectAttrCustom) {
+		t.Error("Wrong attributes are used")
+	}
+}
+
+func TestObjectLabelsInvalid(t *testing.T) {
+	tests := []struct {
+		label ConfigYObjectLabel
+		err   string
+	}{
+		{label: ConfigYObjectLabel{Label: "bad-name", Attribute: "descr"}, err: "invalid label name"},
+		{label: ConfigYObjectLabel{Label: "__app", Attribute: "descr"}, err: "invalid label name"},
+		{label: ConfigYObjectLabel{Label: "app", ObjectType: "topic", Attribute: "descr"}, err: "unknown objectType topic"},
+		{label: ConfigYObjectLabel{Label: "app", Attribute: "maxdepth"}, err: "maxdepth is not an attribute that can be used for a queue"},
+		{label: ConfigYObjectLabel{Label: "app", ObjectType: "channel", Attribute: "custom"}, err: "can be used for a channel"},
+		{label: ConfigYObjectLabel{Label: "app", Attribute: "usage", Key: "x"}, err: "a key can only be used"},
+		{label: ConfigYObjectLabel{Label: "app", Attribute: "descr", Regex: "("}, err: "invalid regex"},
+	}
+	for _, tt := range tests {
+		_, err := newObjectLabels([]ConfigYObjectLabel{tt.label})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Expected error containing %q, got %v", tt.err, err)
+		}
+	}
+}
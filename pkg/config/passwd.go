@@ -30,6 +30,12 @@ import (
 func GetPasswordFromStdin(prompt string) string {
 	var password string
 
+	// There's nobody to answer when a deployment pipeline is checking or printing the
+	// configuration, and the password is not needed for that
+	if checkingConfig() || printingConfig() {
+		return ""
+	}
+
 	fmt.Print(prompt)
 
 	// Stdin is not necessarily 0 on Windows so call the os to try to find it
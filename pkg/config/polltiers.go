@@ -0,0 +1,122 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+The pollInterval applies to all of the status polling. Different object types, and different
+sets of queues, can instead be polled at their own intervals with a list of tiers in the
+global section of the YAML file:
+
+	global:
+	  pollInterval: 60s
+	  pollTiers:
+	  - objectType: queue
+	    pattern: "PAY.*"
+	    interval: 10s
+	  - objectType: queue
+	    pattern: "BULK.*,ARCHIVE.*"
+	    interval: 5m
+	  - objectType: channel
+	    interval: 30s
+	  - objectType: topic
+	    interval: 1h
+
+The objectTypes are the same as the names of the polling phases: queue, channel, topic,
+subscription, qmgr, cluster, usage (the z/OS buffer pools and pagesets), amqp and mqtt. Only the
+queue tiers can have a pattern, which uses the same style as the monitored queue list; a queue
+belongs to the first tier that it matches, and anything not matched by a tier is polled at the
+pollInterval as before. The other object types can have a single tier, which sets the interval
+for all of their objects.
+*/
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+)
+
+// The object types that can have their own polling interval
+var pollTierObjectTypes = []string{"queue", "channel", "topic", "subscription", "qmgr", "cluster", "usage", "amqp", "mqtt"}
+
+// ConfigYPollTier is one of the tiers, as it is written in the YAML file
+type ConfigYPollTier struct {
+	ObjectType string `yaml:"objectType"`
+	Pattern    string
+	Interval   string
+}
+
+// PollTier is a checked tier, with its interval parsed
+type PollTier struct {
+	ObjectType string
+	Patterns   string // Only for queues. Empty means every object of the type.
+	Interval   time.Duration
+}
+
+// Called from VerifyConfig to check the tiers
+func newPollTiers(configs []ConfigYPollTier) ([]PollTier, error) {
+	var tiers []PollTier
+
+	seen := make(map[string]bool)
+	for i, c := range configs {
+		t := PollTier{
+			ObjectType: strings.ToLower(strings.TrimSpace(c.ObjectType)),
+			Patterns:   strings.TrimSpace(c.Pattern),
+		}
+
+		valid := false
+		for _, ot := range pollTierObjectTypes {
+			if ot == t.ObjectType {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("pollTiers entry %d: unknown objectType %s. Use one of %s", i, c.ObjectType, strings.Join(pollTierObjectTypes, ", "))
+		}
+
+		if t.ObjectType == "queue" {
+			if err := mqmetric.VerifyQueuePatterns(t.Patterns); err != nil {
+				return nil, fmt.Errorf("pollTiers entry %d: %v", i, err)
+			}
+		} else if t.Patterns != "" {
+			return nil, fmt.Errorf("pollTiers entry %d: only queue tiers can have a pattern", i)
+		}
+
+		// A tier without a pattern covers everything that's left, so there's no point
+		// having anything after it for the same object type
+		if seen[t.ObjectType] {
+			return nil, fmt.Errorf("pollTiers entry %d: there is already a tier for every %s", i, t.ObjectType)
+		}
+		if t.Patterns == "" {
+			seen[t.ObjectType] = true
+		}
+
+		if c.Interval == "" {
+			return nil, fmt.Errorf("pollTiers entry %d: missing interval", i)
+		}
+		var err error
+		t.Interval, err = time.ParseDuration(c.Interval)
+		if err != nil || t.Interval < 0 {
+			return nil, fmt.Errorf("pollTiers entry %d: invalid interval %s", i, c.Interval)
+		}
+		tiers = append(tiers, t)
+	}
+	return tiers, nil
+}
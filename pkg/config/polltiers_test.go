@@ -0,0 +1,110 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPollTiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		tiers []ConfigYPollTier
+		want  []PollTier
+		err   string
+	}{
+		{name: "none"},
+		{
+			name: "queue patterns and other types",
+			tiers: []ConfigYPollTier{
+				{ObjectType: "Queue", Pattern: " PAY.* ", Interval: "10s"},
+				{ObjectType: "queue", Pattern: "BULK.*,!BULK.X", Interval: "5m"},
+				{ObjectType: "queue", Interval: "1m"},
+				{ObjectType: "channel", Interval: "30s"},
+			},
+			want: []PollTier{
+				{ObjectType: "queue", Patterns: "PAY.*", Interval: 10 * time.Second},
+				{ObjectType: "queue", Patterns: "BULK.*,!BULK.X", Interval: 5 * time.Minute},
+				{ObjectType: "queue", Interval: time.Minute},
+				{ObjectType: "channel", Interval: 30 * time.Second},
+			},
+		},
+		{
+			name:  "unknown object type",
+			tiers: []ConfigYPollTier{{ObjectType: "listener", Interval: "10s"}},
+			err:   "pollTiers entry 0: unknown objectType listener",
+		},
+		{
+			name:  "bad queue pattern",
+			tiers: []ConfigYPollTier{{ObjectType: "queue", Pattern: "A*B", Interval: "10s"}},
+			err:   "'*' must be last character",
+		},
+		{
+			name:  "pattern for another type",
+			tiers: []ConfigYPollTier{{ObjectType: "channel", Pattern: "TO.*", Interval: "10s"}},
+			err:   "only queue tiers can have a pattern",
+		},
+		{
+			name:  "tier after everything",
+			tiers: []ConfigYPollTier{{ObjectType: "topic", Interval: "10s"}, {ObjectType: "topic", Interval: "1h"}},
+			err:   "pollTiers entry 1: there is already a tier for every topic",
+		},
+		{
+			name:  "queue tier after everything",
+			tiers: []ConfigYPollTier{{ObjectType: "queue", Interval: "10s"}, {ObjectType: "queue", Pattern: "A.*", Interval: "1h"}},
+			err:   "there is already a tier for every queue",
+		},
+		{
+			name:  "missing interval",
+			tiers: []ConfigYPollTier{{ObjectType: "qmgr"}},
+			err:   "missing interval",
+		},
+		{
+			name:  "bad interval",
+			tiers: []ConfigYPollTier{{ObjectType: "usage", Interval: "often"}},
+			err:   "invalid interval often",
+		},
+		{
+			name:  "negative interval",
+			tiers: []ConfigYPollTier{{ObjectType: "mqtt", Interval: "-1s"}},
+			err:   "invalid interval -1s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newPollTiers(tt.tiers)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tiers are %+v, expected %+v", got, tt.want)
+			}
+		})
+	}
+}
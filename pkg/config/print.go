@@ -0,0 +1,365 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+Running a collector with -printConfig=yaml or -printConfig=json shows the configuration that
+it would use, once all of the defaults, environment variables, command line flags and the YAML
+file have been combined. Each value says where it came from. Passwords and tokens are masked,
+unless they are references to where the secret is kept.
+
+The values are taken from the registry of parameters built by AddParm, so everything that can be
+set on the command line is shown, including the options for the individual collector. Entries in
+the queueManagers list are shown afterwards, with the settings that can differ between queue managers.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const maskedValue = "********"
+
+// The keys that were present in the YAML files, as "section.name" in lower case, and the
+// last file that set each of them
+var yamlKeys map[string]string
+
+// A resolved value, and where it came from
+type printedValue struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// A section of the configuration, with the values in the order they are to be printed
+type printedSection struct {
+	name   string
+	keys   []string
+	values map[string]printedValue
+}
+
+func newPrintedSection(name string) *printedSection {
+	return &printedSection{name: name, values: make(map[string]printedValue)}
+}
+
+func (ps *printedSection) add(key string, v printedValue) {
+	if _, ok := ps.values[key]; !ok {
+		ps.keys = append(ps.keys, key)
+	}
+	ps.values[key] = v
+}
+
+// Whether we've been asked to print the configuration. As with checking, this
+// is needed before the main configuration structure has been filled in.
+func printingConfig() bool {
+	if p, ok := configParms[envVarKey("global", "printConfig")]; ok {
+		return *(p.loc).(*string) != ""
+	}
+	return false
+}
+
+// Remember which keys the files set, so the printed configuration can say so. The files
+// have already been parsed successfully, so errors are not expected here. This is also
+// where the list of files that were used is kept.
+func noteYamlKeys(layers []configLayer) {
+	yamlKeys = make(map[string]string)
+	configLayers = nil
+
+	for _, l := range layers {
+		var raw map[string]interface{}
+
+		configLayers = append(configLayers, l.file)
+		if yaml.Unmarshal(l.data, &raw) != nil {
+			continue
+		}
+		for section, v := range raw {
+			if m, ok := v.(map[interface{}]interface{}); ok {
+				for k, kv := range m {
+					key := strings.TrimSuffix(fmt.Sprint(k), "+")
+					yamlKeys[strings.ToLower(section+"."+key)] = l.file
+
+					// A nested section such as connection.tls has parameters named
+					// like connection.tlsCipherSpec
+					if nested, ok := kv.(map[interface{}]interface{}); ok {
+						for nk := range nested {
+							yamlKeys[strings.ToLower(section+"."+key+fmt.Sprint(nk))] = l.file
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Where did the current value of a parameter come from. A flag overrides an environment
+// variable, and both override the file.
+func (p *ConfigParm) source() string {
+	if p.fromCli {
+		return "flag -" + p.cliName
+	}
+	if p.fromEnv {
+		return "env " + envVarKey(p.envSection, p.envName)
+	}
+	if f, ok := yamlKeys[strings.ToLower(p.envSection+"."+p.envName)]; ok {
+		src := "yaml " + p.envSection + "." + p.envName
+		if len(configLayers) > 1 {
+			src += " in " + f
+		}
+		return src
+	}
+	return "default"
+}
+
+func (p *ConfigParm) value() interface{} {
+	var v interface{}
+	switch p.parmType {
+	case CP_STR, CP_STR_LIST:
+		v = *(p.loc).(*string)
+	case CP_INT:
+		v = *(p.loc).(*int)
+	case CP_BOOL:
+		v = *(p.loc).(*bool)
+	}
+	if isSecret(p.envName) && v != "" && !IsSecretRef(fmt.Sprint(v)) {
+		v = maskedValue
+	}
+	return v
+}
+
+// Passwords and tokens are secret, but the names of files holding them are not
+func isSecret(name string) bool {
+	n := strings.ToLower(name)
+	return (strings.Contains(n, "password") || strings.Contains(n, "token")) && !strings.HasSuffix(n, "file")
+}
+
+/*
+ExitIfPrinting is called by each collector once its configuration has been completely read
+and verified. If it was asked to print that configuration, then it does so on stdout and exits.
+*/
+func ExitIfPrinting(cm *Config, cms []*Config) {
+	var err error
+	var out []byte
+
+	if cm.PrintConfig == "" {
+		return
+	}
+
+	sections := printedParms(cm)
+	qmgrs := printedQueueManagers(cm, cms)
+
+	if strings.EqualFold(cm.PrintConfig, "json") {
+		out, err = printJson(sections, qmgrs)
+	} else {
+		out = printYaml(sections, qmgrs)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot print configuration: %v\n", err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(out)
+	os.Exit(0)
+}
+
+// Group the registered parameters by their section. The parameters that have been removed
+// are only there to give an error if they are still used.
+func printedParms(cm *Config) []*printedSection {
+	var sections []*printedSection
+
+	bySection := make(map[string]*printedSection)
+	for _, k := range keys {
+		p, ok := configParms[k]
+		if !ok || strings.HasPrefix(p.cliName, "removed") {
+			continue
+		}
+		ps, ok := bySection[p.envSection]
+		if !ok {
+			ps = newPrintedSection(p.envSection)
+			bySection[p.envSection] = ps
+			sections = append(sections, ps)
+		}
+		ps.add(p.envName, printedValue{Value: p.value(), Source: p.source()})
+
+		// The metadataMap only comes from the file, and replaces the tags and values
+		if p.envSection == "connection" && p.envName == "metadataTags" && cm.metadataTags == "" && len(cm.MetadataTagsArray) > 0 {
+			ps.add("metadataMap", printedValue{Value: metadata(cm), Source: "yaml connection.metadataMap"})
+		}
+	}
+
+	sort.Slice(sections, func(i, j int) bool { return sections[i].name < sections[j].name })
+	return sections
+}
+
+// The settings for each entry in the queueManagers list, and whether they are different from
+// the main configuration. There's nothing extra to show if the list was not used.
+func printedQueueManagers(cm *Config, cms []*Config) [][]*printedSection {
+	var qmgrs [][]*printedSection
+
+	if len(cms) == 1 && cms[0] == cm {
+		return nil
+	}
+
+	settings := append([]setting{{"connection.queueManager", func(c *Config) interface{} { return c.QMgrName }}}, restartSettings...)
+	settings = append(settings, liveSettings...)
+	sort.SliceStable(settings, func(i, j int) bool { return section(settings[i].name) < section(settings[j].name) })
+
+	for i, c := range cms {
+		var sections []*printedSection
+		var ps *printedSection
+
+		for _, s := range settings {
+			sec, key, _ := strings.Cut(s.name, ".")
+			// The global and health settings are the same for every queue manager
+			if sec != "connection" && sec != "objects" && sec != "filters" {
+				continue
+			}
+			if ps == nil || ps.name != sec {
+				ps = newPrintedSection(sec)
+				sections = append(sections, ps)
+			}
+			src := "inherited"
+			if differs(s, cm, c) {
+				src = fmt.Sprintf("yaml queueManagers[%d].%s", i, s.name)
+			}
+			ps.add(key, printedValue{Value: printable(s.value(c)), Source: src})
+		}
+		qmgrs = append(qmgrs, sections)
+	}
+	return qmgrs
+}
+
+func section(name string) string {
+	sec, _, _ := strings.Cut(name, ".")
+	return sec
+}
+
+// Durations are shown the way they would be written in the configuration, rather than as
+// a number of nanoseconds
+func printable(v interface{}) interface{} {
+	if d, ok := v.(time.Duration); ok {
+		return d.String()
+	}
+	return v
+}
+
+func printJson(sections []*printedSection, qmgrs [][]*printedSection) ([]byte, error) {
+	toMap := func(sections []*printedSection) map[string]map[string]printedValue {
+		m := make(map[string]map[string]printedValue)
+		for _, ps := range sections {
+			m[ps.name] = ps.values
+		}
+		return m
+	}
+
+	out := make(map[string]interface{})
+	for k, v := range toMap(sections) {
+		out[k] = v
+	}
+	if len(qmgrs) > 0 {
+		var list []interface{}
+		for _, q := range qmgrs {
+			list = append(list, toMap(q))
+		}
+		out["queueManagers"] = list
+	}
+
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err == nil {
+		b = append(b, '\n')
+	}
+	return b, err
+}
+
+// The YAML is written directly so that the source of each value can be a comment
+func printYaml(sections []*printedSection, qmgrs [][]*printedSection) []byte {
+	var sb strings.Builder
+
+	writeSections := func(sections []*printedSection, indent string, first string) {
+		for i, ps := range sections {
+			if i == 0 {
+				sb.WriteString(first)
+			} else {
+				sb.WriteString(indent)
+			}
+			sb.WriteString(ps.name + ":\n")
+			for _, k := range ps.keys {
+				v := ps.values[k]
+				fmt.Fprintf(&sb, "%s  %s: %s # %s\n", indent, k, yamlScalar(v.Value), v.Source)
+			}
+		}
+	}
+
+	writeSections(sections, "", "")
+	if len(qmgrs) > 0 {
+		sb.WriteString("queueManagers:\n")
+		for _, q := range qmgrs {
+			writeSections(q, "    ", "  - ")
+		}
+	}
+	return []byte(sb.String())
+}
+
+// Let the yaml package decide on any quoting. Lists are written in the flow style so they
+// fit on one line.
+func yamlScalar(v interface{}) string {
+	if reflect.ValueOf(v).Kind() == reflect.Slice {
+		b, _ := yaml.Marshal(struct {
+			V interface{} `yaml:"v,flow"`
+		}{v})
+		return strings.TrimSpace(strings.TrimPrefix(string(b), "v:"))
+	}
+	b, _ := yaml.Marshal(v)
+	return strings.TrimSpace(string(b))
+}
+
+// A version of a configuration structure for debug logs, with any secrets masked
+func maskSecrets(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		return maskSecrets(v.Elem())
+	case reflect.Struct:
+		var fields []string
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := v.Field(i)
+			s := maskSecrets(f)
+			if isSecret(t.Field(i).Name) && !f.IsZero() && !(f.Kind() == reflect.String && IsSecretRef(f.String())) {
+				s = maskedValue
+			}
+			fields = append(fields, t.Field(i).Name+":"+s)
+		}
+		return "{" + strings.Join(fields, " ") + "}"
+	case reflect.Slice:
+		var elems []string
+		for i := 0; i < v.Len(); i++ {
+			elems = append(elems, maskSecrets(v.Index(i)))
+		}
+		return "[" + strings.Join(elems, " ") + "]"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,146 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrintedValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		parm  string
+		value string
+		want  string
+	}{
+		{name: "ordinary value", parm: "queueManager", value: "QM1", want: "QM1"},
+		{name: "password", parm: "password", value: "passw0rd", want: maskedValue},
+		{name: "token", parm: "token", value: "abc", want: maskedValue},
+		{name: "empty password", parm: "password", value: "", want: ""},
+		{name: "password reference", parm: "password", value: "env:MQ_PASSWORD", want: "env:MQ_PASSWORD"},
+		{name: "escaped password", parm: "password", value: "literal:env:X", want: maskedValue},
+		{name: "password file", parm: "passwordFile", value: "/etc/pw", want: "/etc/pw"},
+		{name: "key repository password", parm: "keyRepositoryPassword", value: "x", want: maskedValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.value
+			p := &ConfigParm{loc: &v, parmType: CP_STR, envSection: "connection", envName: tt.parm}
+			if got := p.value(); got != tt.want {
+				t.Errorf("Value is %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintedSource(t *testing.T) {
+	oldKeys, oldLayers := yamlKeys, configLayers
+	defer func() { yamlKeys, configLayers = oldKeys, oldLayers }()
+
+	yamlKeys = map[string]string{"connection.queuemanager": "site.yaml"}
+	tests := []struct {
+		name   string
+		parm   ConfigParm
+		layers []string
+		want   string
+	}{
+		{name: "default", parm: ConfigParm{envSection: "connection", envName: "channel"}, want: "default"},
+		{name: "file", parm: ConfigParm{envSection: "connection", envName: "queueManager"}, want: "yaml connection.queueManager"},
+		{
+			name:   "one of several files",
+			parm:   ConfigParm{envSection: "connection", envName: "queueManager"},
+			layers: []string{"base.yaml", "site.yaml"},
+			want:   "yaml connection.queueManager in site.yaml",
+		},
+		{
+			name: "environment",
+			parm: ConfigParm{envSection: "connection", envName: "queueManager", fromEnv: true},
+			want: "env IBMMQ_CONNECTION_QUEUEMANAGER",
+		},
+		{
+			name: "flag",
+			parm: ConfigParm{envSection: "connection", envName: "queueManager", cliName: "ibmmq.queueManager", fromEnv: true, fromCli: true},
+			want: "flag -ibmmq.queueManager",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configLayers = tt.layers
+			if got := tt.parm.source(); got != tt.want {
+				t.Errorf("Source is %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintYaml(t *testing.T) {
+	ps := newPrintedSection("connection")
+	ps.add("queueManager", printedValue{Value: "QM1", Source: "default"})
+	ps.add("password", printedValue{Value: maskedValue, Source: "flag -ibmmq.password"})
+	ps.add("metadataTags", printedValue{Value: []string{"a", "b"}, Source: "default"})
+	ps.add("queueManager", printedValue{Value: "QM2", Source: "yaml connection.queueManager"})
+	qm := newPrintedSection("connection")
+	qm.add("queueManager", printedValue{Value: "QM3", Source: "yaml queueManagers[0].connection.queueManager"})
+
+	want := "connection:\n" +
+		"  queueManager: QM2 # yaml connection.queueManager\n" +
+		"  password: '********' # flag -ibmmq.password\n" +
+		"  metadataTags: [a, b] # default\n" +
+		"queueManagers:\n" +
+		"  - connection:\n" +
+		"      queueManager: QM3 # yaml queueManagers[0].connection.queueManager\n"
+	if got := string(printYaml([]*printedSection{ps}, [][]*printedSection{{qm}})); got != want {
+		t.Errorf("Printed\n%s\nexpected\n%s", got, want)
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	type inner struct {
+		Password string
+	}
+	type outer struct {
+		QMgrName     string
+		Password     string
+		PasswordFile string
+		Token        string
+		Inner        *inner
+		List         []inner
+	}
+	v := outer{
+		QMgrName:     "QM1",
+		Password:     "passw0rd",
+		PasswordFile: "/etc/pw",
+		Token:        "file:/etc/token",
+		Inner:        &inner{Password: "secret"},
+		List:         []inner{{Password: "secret"}, {}},
+	}
+	got := maskSecrets(reflect.ValueOf(&v))
+	for _, s := range []string{"passw0rd", "secret"} {
+		if strings.Contains(got, s) {
+			t.Errorf("%q is in %s", s, got)
+		}
+	}
+	for _, s := range []string{"QMgrName:QM1", "PasswordFile:/etc/pw", "Token:file:/etc/token", "[{Password:********} {Password:}]"} {
+		if !strings.Contains(got, s) {
+			t.Errorf("%q is not in %s", s, got)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+A single collector can monitor several queue managers. Each entry in the "queueManagers"
+list of the YAML file starts with a copy of the main configuration, and anything that is
+set in the entry replaces the inherited value. Like the outputs list, there are no
+command-line or env var equivalents for these entries.
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ConfigYQueueManager struct {
+	Connection ConfigYConnection
+	Objects    ConfigYObjects
+	Filters    ConfigYFilters
+}
+
+/*
+QueueManagerConfigs returns the configuration for each queue manager that is to be monitored.
+The base configuration must already have been through VerifyConfig. If there is no list in the
+YAML file, then the base configuration is the only one returned.
+*/
+func QueueManagerConfigs(cm *Config, qms []ConfigYQueueManager) ([]*Config, error) {
+	return queueManagerConfigs(cm, qms, true)
+}
+
+// When the configuration is reloaded, there is nobody to answer a password prompt. The
+// connection details can't be changed then anyway, so the passwords are not needed.
+func queueManagerConfigs(cm *Config, qms []ConfigYQueueManager, getPasswords bool) ([]*Config, error) {
+	var err error
+	var cms []*Config
+
+	if len(qms) == 0 {
+		return []*Config{cm}, nil
+	}
+
+	names := make(map[string]bool)
+	for i, qm := range qms {
+		c := new(Config)
+		*c = *cm
+		c.MetadataTagsArray = append([]string(nil), cm.MetadataTagsArray...)
+		c.MetadataValuesArray = append([]string(nil), cm.MetadataValuesArray...)
+
+		// Each entry needs an explicit name; a blank or "*" name would be ambiguous across
+		// entries, and the name is what distinguishes the series from each other.
+		if qm.Connection.QueueManager == "" || strings.HasPrefix(qm.Connection.QueueManager, "*") {
+			err = fmt.Errorf("queueManagers entry %d must have an explicit queueManager name", i)
+		} else if names[qm.Connection.QueueManager] {
+			err = fmt.Errorf("queue manager %s appears more than once in queueManagers", qm.Connection.QueueManager)
+		}
+
+		if err == nil {
+			names[qm.Connection.QueueManager] = true
+			overrideConnection(c, qm.Connection)
+			overrideObjects(c, qm.Objects)
+			overrideFilters(c, qm.Filters)
+			err = VerifyConfig(c, c)
+		}
+
+		// The MQ client has a single key repository for the whole process, and reads the
+		// revocation settings once
+		if err == nil && (c.TLS.KeyRepository != cm.TLS.KeyRepository || c.TLS.KeyRepositoryPassword != cm.TLS.KeyRepositoryPassword ||
+			c.TLS.RevocationCheck != cm.TLS.RevocationCheck || c.TLS.OCSPCheckExtensions != cm.TLS.OCSPCheckExtensions ||
+			c.TLS.CDPCheckExtensions != cm.TLS.CDPCheckExtensions) {
+			err = fmt.Errorf("queueManagers entry %d cannot change the TLS key repository or revocation settings", i)
+		}
+
+		if err == nil && getPasswords {
+			// A different user needs its own password. Otherwise the inherited one is used.
+			if c.CC.UserId != "" && c.CC.Password == "" {
+				if c.PasswordFile == "" {
+					c.CC.Password = GetPasswordFromStdin("Enter password for MQ (" + c.QMgrName + "): ")
+				} else {
+					c.CC.Password, err = GetPasswordFromFile(c.PasswordFile, false)
+				}
+			}
+		}
+
+		if err != nil {
+			break
+		}
+		cms = append(cms, c)
+	}
+
+	return cms, err
+}
+
+func overrideConnection(c *Config, cyc ConfigYConnection) {
+	overrideStr(&c.QMgrName, cyc.QueueManager)
+	overrideStr(&c.CC.CcdtUrl, cyc.CcdtUrl)
+	overrideStr(&c.CC.ConnName, cyc.ConnName)
+	overrideStr(&c.CC.Channel, cyc.Channel)
+	overrideBool(&c.CC.ClientMode, cyc.Client)
+	overrideStr(&c.CC.DurableSubPrefix, cyc.DurableSubPrefix)
+	overrideStr(&c.ReplyQ, cyc.ReplyQueue)
+	overrideStr(&c.ReplyQ2, cyc.ReplyQueue2)
+	if cyc.WaitInterval != "" {
+		c.CC.WaitInterval = asInt(cyc.WaitInterval, c.CC.WaitInterval)
+	}
+	overrideBool(&c.KeepRunning, cyc.KeepRunning)
+	overrideStr(&c.reconnectInterval, cyc.Reconnect)
+	overrideStr(&c.reconnectMaxInterval, cyc.ReconnectMax)
+
+	// The password is dealt with after the rest of the configuration has been checked
+	if cyc.User != "" || cyc.Password != "" || cyc.PasswordFile != "" {
+		overrideStr(&c.CC.UserId, cyc.User)
+		c.CC.Password = cyc.Password
+		c.PasswordFile = cyc.PasswordFile
+	}
+
+	overrideStr(&c.TLS.KeyRepository, cyc.TLS.KeyRepository)
+	overrideStr(&c.TLS.KeyRepositoryPassword, cyc.TLS.KeyRepositoryPassword)
+	overrideStr(&c.TLS.CipherSpec, cyc.TLS.CipherSpec)
+	overrideStr(&c.TLS.CertificateLabel, cyc.TLS.CertificateLabel)
+	overrideStr(&c.TLS.PeerName, cyc.TLS.PeerName)
+	overrideStr(&c.TLS.RevocationCheck, cyc.TLS.RevocationCheck)
+	overrideBool(&c.TLS.OCSPCheckExtensions, cyc.TLS.OCSPCheckExtensions)
+	overrideBool(&c.TLS.CDPCheckExtensions, cyc.TLS.CDPCheckExtensions)
+
+	// As with the main configuration, the map is preferred to the arrays
+	if len(cyc.MetadataMap) > 0 {
+		c.metadataTags = ""
+		c.metadataValues = ""
+		c.MetadataTagsArray = nil
+		c.MetadataValuesArray = nil
+		for k, v := range cyc.MetadataMap {
+			c.MetadataTagsArray = append(c.MetadataTagsArray, k)
+			c.MetadataValuesArray = append(c.MetadataValuesArray, v)
+		}
+	} else if len(cyc.MetadataTags) > 0 {
+		c.metadataTags = strings.Join(cyc.MetadataTags, ",")
+		c.metadataValues = strings.Join(cyc.MetadataValues, ",")
+	}
+}
+
+// Setting a list of objects for the queue manager also replaces any patterns file that
+// was given for the main configuration.
+func overrideObjects(c *Config, cyo ConfigYObjects) {
+	overrideArray(&c.MonitoredQueues, &c.MonitoredQueuesFile, cyo.Queues)
+	overrideArray(&c.MonitoredChannels, &c.MonitoredChannelsFile, cyo.Channels)
+	overrideArray(&c.MonitoredAMQPChannels, &c.MonitoredAMQPChannelsFile, cyo.AMQPChannels)
+	overrideArray(&c.MonitoredMQTTChannels, &c.MonitoredMQTTChannelsFile, cyo.MQTTChannels)
+	overrideArray(&c.MonitoredTopics, &c.MonitoredTopicsFile, cyo.Topics)
+	overrideArray(&c.MonitoredSubscriptions, &c.MonitoredSubscriptionsFile, cyo.Subscriptions)
+}
+
+func overrideFilters(c *Config, cyf ConfigYFilters) {
+	overrideBool(&c.CC.ShowInactiveChannels, cyf.ShowInactiveChannels)
+	overrideBool(&c.CC.HideSvrConnJobname, cyf.HideSvrConnJobname)
+	overrideBool(&c.CC.HideAMQPClientId, cyf.HideAMQPClientId)
+	overrideBool(&c.CC.HideMQTTClientId, cyf.HideMQTTClientId)
+	if len(cyf.QueueSubscriptionSelector) > 0 {
+		c.QueueSubscriptionSelector = strings.Join(cyf.QueueSubscriptionSelector, ",")
+	}
+	if len(cyf.Metrics.Include) > 0 {
+		c.MetricsInclude = strings.Join(cyf.Metrics.Include, ",")
+	}
+	if len(cyf.Metrics.Exclude) > 0 {
+		c.MetricsExclude = strings.Join(cyf.Metrics.Exclude, ",")
+	}
+}
+
+func overrideStr(dst *string, val string) {
+	if val != "" {
+		*dst = val
+	}
+}
+
+func overrideBool(dst *bool, val string) {
+	if val != "" {
+		*dst = AsBool(val, *dst)
+	}
+}
+
+func overrideArray(dst *string, file *string, val []string) {
+	if len(val) > 0 {
+		*dst = strings.Join(val, ",")
+		*file = ""
+	}
+}
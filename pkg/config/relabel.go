@@ -0,0 +1,238 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+The labels and names of the metrics can be changed with a list of rules in the
+relabelConfigs section of the YAML file. They work like the relabel_configs in a Prometheus
+scrape configuration, and are applied to every point before it is given to any backend.
+
+	relabelConfigs:
+	- action: replace
+	  sourceLabels: [qmgr]
+	  targetLabel: queue_manager
+	- action: labeldrop
+	  regex: "qmgr|platform"
+	- sourceLabels: [__name__]
+	  regex: "queue_depth"
+	  targetLabel: __name__
+	  replacement: "queue_current_depth"
+
+The rules are applied in order. The name of the metric is available as the "__name__" label,
+in its normalised form such as "queue_depth", and changing that label renames the metric.
+Any other label that starts with "__" can be used to hold a value between rules, and is
+removed at the end. The actions are:
+
+	replace    Set targetLabel to the replacement when the regex matches the sourceLabels. This is the default.
+	keep       Drop the point unless the regex matches the sourceLabels
+	drop       Drop the point if the regex matches the sourceLabels
+	hashmod    Set targetLabel to a hash of the sourceLabels, modulo the modulus
+	labelmap   Copy the value of every label whose name matches the regex to a label named by the replacement
+	labeldrop  Remove every label whose name matches the regex
+	labelkeep  Remove every label whose name does not match the regex
+
+The values of the sourceLabels are joined by the separator, which is ";" by default. The regex
+has to match the whole value, and defaults to "(.*)". The replacement can refer to the regex
+groups as "$1" and so on, and defaults to "$1". There are no command-line or env var
+equivalents for these rules.
+*/
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The name of the metric, while the rules are being applied
+const RelabelNameLabel = "__name__"
+
+const (
+	relabelReplace   = "replace"
+	relabelKeep      = "keep"
+	relabelDrop      = "drop"
+	relabelHashMod   = "hashmod"
+	relabelLabelMap  = "labelmap"
+	relabelLabelDrop = "labeldrop"
+	relabelLabelKeep = "labelkeep"
+
+	defaultRelabelRegex       = "(.*)"
+	defaultRelabelSeparator   = ";"
+	defaultRelabelReplacement = "$1"
+)
+
+// ConfigYRelabel is one of the rules, as it is written in the YAML file
+type ConfigYRelabel struct {
+	Action       string
+	SourceLabels []string `yaml:"sourceLabels"`
+	Separator    string
+	TargetLabel  string `yaml:"targetLabel"`
+	Regex        string
+	Modulus      uint64
+	Replacement  *string
+}
+
+type relabelRule struct {
+	action       string
+	sourceLabels []string
+	separator    string
+	targetLabel  string
+	regex        *regexp.Regexp
+	modulus      uint64
+	replacement  string
+}
+
+// RelabelRules are the compiled versions of the configured rules. A nil list changes nothing.
+type RelabelRules []*relabelRule
+
+// The rules only come from the YAML file
+func CopyYamlRelabelConfig(cm *Config, rules []ConfigYRelabel) {
+	cm.RelabelConfigs = rules
+}
+
+// Called from VerifyConfig to check and compile the rules
+func newRelabelRules(configs []ConfigYRelabel) (RelabelRules, error) {
+	var rules RelabelRules
+
+	for i, c := range configs {
+		r := &relabelRule{
+			action:       strings.ToLower(c.Action),
+			sourceLabels: c.SourceLabels,
+			separator:    c.Separator,
+			targetLabel:  c.TargetLabel,
+			modulus:      c.Modulus,
+			replacement:  defaultRelabelReplacement,
+		}
+		if r.action == "" {
+			r.action = relabelReplace
+		}
+		if r.separator == "" {
+			r.separator = defaultRelabelSeparator
+		}
+		if c.Replacement != nil {
+			r.replacement = *c.Replacement
+		}
+		expr := c.Regex
+		if expr == "" {
+			expr = defaultRelabelRegex
+		}
+
+		var err error
+		r.regex, err = regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("relabelConfigs entry %d: invalid regex %s: %v", i, expr, err)
+		}
+
+		switch r.action {
+		case relabelReplace:
+			if r.targetLabel == "" {
+				err = fmt.Errorf("relabelConfigs entry %d: the replace action needs a targetLabel", i)
+			}
+		case relabelHashMod:
+			if r.targetLabel == "" || r.modulus == 0 {
+				err = fmt.Errorf("relabelConfigs entry %d: the hashmod action needs a targetLabel and a modulus", i)
+			}
+		case relabelKeep, relabelDrop:
+			if len(r.sourceLabels) == 0 {
+				err = fmt.Errorf("relabelConfigs entry %d: the %s action needs sourceLabels", i, r.action)
+			}
+		case relabelLabelMap, relabelLabelDrop, relabelLabelKeep:
+		default:
+			err = fmt.Errorf("relabelConfigs entry %d: unknown action %s", i, c.Action)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+/*
+Apply runs the rules against a set of labels, which includes the metric name as
+RelabelNameLabel. The map is updated in place. The return value is false if the
+point should be dropped. The labels that start with "__", apart from the name, are
+removed once all the rules have been applied.
+*/
+func (rules RelabelRules) Apply(labels map[string]string) bool {
+	for _, r := range rules {
+		if !r.apply(labels) {
+			return false
+		}
+	}
+	for k := range labels {
+		if strings.HasPrefix(k, "__") && k != RelabelNameLabel {
+			delete(labels, k)
+		}
+	}
+	return true
+}
+
+func (r *relabelRule) apply(labels map[string]string) bool {
+	values := make([]string, len(r.sourceLabels))
+	for i, l := range r.sourceLabels {
+		values[i] = labels[l]
+	}
+	val := strings.Join(values, r.separator)
+
+	switch r.action {
+	case relabelReplace:
+		idx := r.regex.FindStringSubmatchIndex(val)
+		if idx == nil {
+			break
+		}
+		target := string(r.regex.ExpandString(nil, r.targetLabel, val, idx))
+		if target == "" {
+			break
+		}
+		res := string(r.regex.ExpandString(nil, r.replacement, val, idx))
+		if res == "" {
+			delete(labels, target)
+		} else {
+			labels[target] = res
+		}
+	case relabelKeep:
+		return r.regex.MatchString(val)
+	case relabelDrop:
+		return !r.regex.MatchString(val)
+	case relabelHashMod:
+		// The same hash as Prometheus uses, so that the results agree
+		sum := md5.Sum([]byte(val))
+		labels[r.targetLabel] = fmt.Sprint(binary.BigEndian.Uint64(sum[8:]) % r.modulus)
+	case relabelLabelMap:
+		mapped := make(map[string]string)
+		for k, v := range labels {
+			if r.regex.MatchString(k) {
+				mapped[r.regex.ReplaceAllString(k, r.replacement)] = v
+			}
+		}
+		for k, v := range mapped {
+			labels[k] = v
+		}
+	case relabelLabelDrop, relabelLabelKeep:
+		// The name is not a real label, so it can't be removed this way
+		for k := range labels {
+			if k != RelabelNameLabel && r.regex.MatchString(k) == (r.action == relabelLabelDrop) {
+				delete(labels, k)
+			}
+		}
+	}
+	return true
+}
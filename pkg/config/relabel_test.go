@@ -0,0 +1,168 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestRelabel(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []ConfigYRelabel
+		want  map[string]string // Nil if the point is dropped
+	}{
+		{
+			name:  "no rules",
+			rules: nil,
+			want:  map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q"},
+		},
+		{
+			name:  "replace into a new label",
+			rules: []ConfigYRelabel{{SourceLabels: []string{"qmgr"}, TargetLabel: "queue_manager"}},
+			want:  map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q", "queue_manager": "QM1"},
+		},
+		{
+			name: "replace with groups",
+			rules: []ConfigYRelabel{{
+				SourceLabels: []string{"qmgr", "queue"}, Regex: "QM(.*);APP\\.(.*)",
+				TargetLabel: "id", Replacement: strPtr("$1-$2"),
+			}},
+			want: map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q", "id": "1-Q"},
+		},
+		{
+			name:  "replace doesn't match",
+			rules: []ConfigYRelabel{{SourceLabels: []string{"qmgr"}, Regex: "QM2", TargetLabel: "x"}},
+			want:  map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q"},
+		},
+		{
+			name:  "empty replacement removes the label",
+			rules: []ConfigYRelabel{{TargetLabel: "queue", Replacement: strPtr("")}},
+			want:  map[string]string{"__name__": "queue_depth", "qmgr": "QM1"},
+		},
+		{
+			name: "rename the metric",
+			rules: []ConfigYRelabel{{
+				SourceLabels: []string{"__name__"}, Regex: "queue_depth",
+				TargetLabel: "__name__", Replacement: strPtr("queue_current_depth"),
+			}},
+			want: map[string]string{"__name__": "queue_current_depth", "qmgr": "QM1", "queue": "APP.Q"},
+		},
+		{
+			name:  "keep",
+			rules: []ConfigYRelabel{{Action: "keep", SourceLabels: []string{"queue"}, Regex: "APP\\..*"}},
+			want:  map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q"},
+		},
+		{
+			name:  "keep doesn't match",
+			rules: []ConfigYRelabel{{Action: "keep", SourceLabels: []string{"queue"}, Regex: "SYSTEM\\..*"}},
+		},
+		{
+			name:  "drop",
+			rules: []ConfigYRelabel{{Action: "Drop", SourceLabels: []string{"__name__"}, Regex: "queue_.*"}},
+		},
+		{
+			name:  "hashmod",
+			rules: []ConfigYRelabel{{Action: "hashmod", SourceLabels: []string{"qmgr"}, TargetLabel: "shard", Modulus: 10}},
+			want:  map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q", "shard": "2"},
+		},
+		{
+			name:  "hashmod of several labels",
+			rules: []ConfigYRelabel{{Action: "hashmod", SourceLabels: []string{"qmgr", "queue"}, TargetLabel: "shard", Modulus: 10}},
+			want:  map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q", "shard": "1"},
+		},
+		{
+			name:  "labelmap",
+			rules: []ConfigYRelabel{{Action: "labelmap", Regex: "q(.*)", Replacement: strPtr("mq_q$1")}},
+			want: map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q",
+				"mq_qmgr": "QM1", "mq_queue": "APP.Q"},
+		},
+		{
+			name:  "labelmap doesn't touch the name",
+			rules: []ConfigYRelabel{{Action: "labelmap", Regex: "__name__", Replacement: strPtr("metric")}},
+			want:  map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q", "metric": "queue_depth"},
+		},
+		{
+			name:  "labeldrop",
+			rules: []ConfigYRelabel{{Action: "labeldrop", Regex: "qmgr|__name__"}},
+			want:  map[string]string{"__name__": "queue_depth", "queue": "APP.Q"},
+		},
+		{
+			name:  "labelkeep",
+			rules: []ConfigYRelabel{{Action: "labelkeep", Regex: "queue"}},
+			want:  map[string]string{"__name__": "queue_depth", "queue": "APP.Q"},
+		},
+		{
+			name: "temporary label",
+			rules: []ConfigYRelabel{
+				{SourceLabels: []string{"queue"}, Regex: "APP\\.(.*)", TargetLabel: "__app"},
+				{SourceLabels: []string{"__app"}, TargetLabel: "app"},
+			},
+			want: map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q", "app": "Q"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := newRelabelRules(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			labels := map[string]string{"__name__": "queue_depth", "qmgr": "QM1", "queue": "APP.Q"}
+			kept := rules.Apply(labels)
+			if tt.want == nil {
+				if kept {
+					t.Errorf("Point was kept, with %v", labels)
+				}
+				return
+			}
+			if !kept {
+				t.Fatal("Point was dropped")
+			}
+			if !reflect.DeepEqual(labels, tt.want) {
+				t.Errorf("Labels are %v, expected %v", labels, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelabelRulesInvalid(t *testing.T) {
+	tests := []struct {
+		rule ConfigYRelabel
+		err  string
+	}{
+		{rule: ConfigYRelabel{Regex: "(", TargetLabel: "x"}, err: "invalid regex"},
+		{rule: ConfigYRelabel{Action: "replace"}, err: "needs a targetLabel"},
+		{rule: ConfigYRelabel{Action: "hashmod", TargetLabel: "x"}, err: "needs a targetLabel and a modulus"},
+		{rule: ConfigYRelabel{Action: "keep"}, err: "needs sourceLabels"},
+		{rule: ConfigYRelabel{Action: "rename"}, err: "unknown action rename"},
+	}
+	for _, tt := range tests {
+		_, err := newRelabelRules([]ConfigYRelabel{{Action: "labeldrop", Regex: "x"}, tt.rule})
+		if err == nil || !strings.Contains(err.Error(), "entry 1: ") || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Expected error containing %q, got %v", tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+The YAML file can be read again while a collector is running. Only the sections that are
+common to all the collectors are used; anything in a collector's own section, such as the
+Prometheus port, is still what it was at startup. Values that were set on the command line
+or by environment variable continue to override the file, just as they did the first time.
+
+Some settings can be changed without disturbing the queue manager connection. These are the
+lists of objects to monitor, and how frequently things are done. Everything else needs a
+restart: it's either part of the connection, or it has already shaped the metrics that the
+backends know about. A reload reports any of those that are different, but leaves them as
+they were.
+*/
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The parts of the YAML file that all of the collectors share
+type configYReload struct {
+	Global         ConfigYGlobal
+	Connection     ConfigYConnection
+	Objects        ConfigYObjects
+	Filters        ConfigYFilters
+	Health         ConfigYHealth         `yaml:"health"`
+	RelabelConfigs []ConfigYRelabel      `yaml:"relabelConfigs"`
+	ObjectLabels   []ConfigYObjectLabel  `yaml:"objectLabels"`
+	QueueManagers  []ConfigYQueueManager `yaml:"queueManagers"`
+}
+
+// Changes describes what is different in a reloaded configuration
+type Changes struct {
+	Queues   bool     // The queues to monitor are different
+	Channels bool     // Any of the channel patterns are different
+	Live     []string // Settings that have been changed
+	Restart  []string // Settings that are different, but which need a restart to take effect
+}
+
+type setting struct {
+	name  string
+	value func(c *Config) interface{}
+}
+
+// Settings that can be changed while running. The object lists are given as their expanded
+// patterns, so a change to the contents of a patterns file is noticed.
+var liveSettings = []setting{
+	{"global.logLevel", func(c *Config) interface{} { return c.LogLevel }},
+	{"global.pollInterval", func(c *Config) interface{} { return c.PollIntervalDuration }},
+	{"global.pollTiers", func(c *Config) interface{} { return c.PollTiers }},
+	{"global.rediscoverInterval", func(c *Config) interface{} { return c.RediscoverDuration }},
+	{"global.reloadInterval", func(c *Config) interface{} { return c.ReloadIntervalDuration }},
+	{"global.secretTTL", func(c *Config) interface{} { return c.SecretTTLDuration }},
+	{"connection.reconnectInterval", func(c *Config) interface{} { return c.ReconnectIntervalDuration }},
+	{"connection.reconnectMaxInterval", func(c *Config) interface{} { return c.ReconnectMaxIntervalDuration }},
+	{"objects.queues", func(c *Config) interface{} { return c.MonitoredQueues }},
+	{"objects.channels", func(c *Config) interface{} { return c.MonitoredChannels }},
+	{"objects.amqpChannels", func(c *Config) interface{} { return c.MonitoredAMQPChannels }},
+	{"objects.mqttChannels", func(c *Config) interface{} { return c.MonitoredMQTTChannels }},
+	{"objects.topics", func(c *Config) interface{} { return c.MonitoredTopics }},
+	{"objects.subscriptions", func(c *Config) interface{} { return c.MonitoredSubscriptions }},
+}
+
+// Settings that are only used when connecting, or when first discovering what the queue manager
+// can provide. The filters are copied into the mqmetric connection, so they are in this list too.
+// The password is not compared as it is not read during a reload.
+var restartSettings = []setting{
+	{"connection.clientConnection", func(c *Config) interface{} { return c.CC.ClientMode }},
+	{"connection.ccdtUrl", func(c *Config) interface{} { return c.CC.CcdtUrl }},
+	{"connection.connName", func(c *Config) interface{} { return c.CC.ConnName }},
+	{"connection.channel", func(c *Config) interface{} { return c.CC.Channel }},
+	{"connection.user", func(c *Config) interface{} { return c.CC.UserId }},
+	{"connection.passwordFile", func(c *Config) interface{} { return c.PasswordFile }},
+	{"connection.replyQueue", func(c *Config) interface{} { return c.ReplyQ }},
+	{"connection.replyQueue2", func(c *Config) interface{} { return c.ReplyQ2 }},
+	{"connection.durableSubPrefix", func(c *Config) interface{} { return c.CC.DurableSubPrefix }},
+	{"connection.waitInterval", func(c *Config) interface{} { return c.CC.WaitInterval }},
+	{"connection.keepRunning", func(c *Config) interface{} { return c.KeepRunning }},
+	{"connection.tls.keyRepository", func(c *Config) interface{} { return c.TLS.KeyRepository }},
+	{"connection.tls.cipherSpec", func(c *Config) interface{} { return c.TLS.CipherSpec }},
+	{"connection.tls.certificateLabel", func(c *Config) interface{} { return c.TLS.CertificateLabel }},
+	{"connection.tls.peerName", func(c *Config) interface{} { return c.TLS.PeerName }},
+	{"connection.tls.revocationCheck", func(c *Config) interface{} { return c.TLS.RevocationCheck }},
+	{"connection.tls.ocspCheckExtensions", func(c *Config) interface{} { return c.TLS.OCSPCheckExtensions }},
+	{"connection.tls.cdpCheckExtensions", func(c *Config) interface{} { return c.TLS.CDPCheckExtensions }},
+	{"connection.metadata", metadata},
+	{"filters.queueSubscriptionSelector", func(c *Config) interface{} { return c.QueueSubscriptionSelector }},
+	{"filters.metrics.include", func(c *Config) interface{} { return c.MetricsInclude }},
+	{"filters.metrics.exclude", func(c *Config) interface{} { return c.MetricsExclude }},
+	{"filters.showInactiveChannels", func(c *Config) interface{} { return c.CC.ShowInactiveChannels }},
+	{"filters.hideSvrConnJobname", func(c *Config) interface{} { return c.CC.HideSvrConnJobname }},
+	{"filters.hideAMQPClientId", func(c *Config) interface{} { return c.CC.HideAMQPClientId }},
+	{"filters.hideMQTTClientId", func(c *Config) interface{} { return c.CC.HideMQTTClientId }},
+	{"global.useObjectStatus", func(c *Config) interface{} { return c.CC.UseStatus }},
+	{"global.usePublications", func(c *Config) interface{} { return c.CC.UsePublications }},
+	{"global.useResetQStats", func(c *Config) interface{} { return c.CC.UseResetQStats }},
+	{"global.metaPrefix", func(c *Config) interface{} { return c.MetaPrefix }},
+	{"global.tzOffset", func(c *Config) interface{} { return c.TZOffsetString }},
+	{"global.locale", func(c *Config) interface{} { return c.Locale }},
+	{"global.recordFile", func(c *Config) interface{} { return c.RecordFile }},
+	{"global.replayFile", func(c *Config) interface{} { return c.ReplayFile }},
+	{"global.replayLoop", func(c *Config) interface{} { return c.ReplayLoop }},
+	{"global.simulate", func(c *Config) interface{} { return c.Simulate }},
+	{"global.simulateQueues", func(c *Config) interface{} { return c.SimulateQueues }},
+	{"global.simulateChannels", func(c *Config) interface{} { return c.SimulateChannels }},
+	{"global.simulateReplicas", func(c *Config) interface{} { return c.SimulateReplicas }},
+	{"health.host", func(c *Config) interface{} { return c.HealthHost }},
+	{"health.port", func(c *Config) interface{} { return c.HealthPort }},
+	{"health.staleIntervals", func(c *Config) interface{} { return c.HealthStaleIntervals }},
+	{"health.readyWhen", func(c *Config) interface{} { return c.HealthReadyWhen }},
+	{"relabelConfigs", relabelConfigs},
+	{"objectLabels", func(c *Config) interface{} { return c.ObjectLabelConfigs }},
+}
+
+/*
+ReloadConfigFile reads the configuration file again, starting from the configuration that
+the collector was started with. It returns the configuration for each queue manager in the
+same way as QueueManagerConfigs. Nothing that is already in use is changed; the caller
+decides what to do with the new values.
+*/
+func ReloadConfigFile(cm *Config) ([]*Config, error) {
+	var cfy configYReload
+
+	if cm.ConfigFile == "" {
+		return nil, fmt.Errorf("No configuration file was given")
+	}
+
+	nc := new(Config)
+	*nc = *cm
+	nc.MetadataTagsArray = nil
+	nc.MetadataValuesArray = nil
+
+	err := ReadConfigFile(cm.ConfigFile, &cfy)
+	if err == nil {
+		CopyYamlConfig(nc, cfy.Global, cfy.Connection, cfy.Objects, cfy.Filters)
+		CopyYamlHealthConfig(nc, cfy.Health)
+		CopyYamlRelabelConfig(nc, cfy.RelabelConfigs)
+		CopyYamlObjectLabelConfig(nc, cfy.ObjectLabels)
+		err = VerifyConfig(nc, nc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return queueManagerConfigs(nc, cfy.QueueManagers, false)
+}
+
+/*
+Reconfigure copies the settings that can be changed while running from a reloaded
+configuration, and says what was different. The caller must make sure that nothing else
+is using the configuration at the same time.
+*/
+func Reconfigure(cm *Config, nc *Config) Changes {
+	var ch Changes
+
+	for _, s := range liveSettings {
+		if differs(s, cm, nc) {
+			ch.Live = append(ch.Live, s.name)
+		}
+	}
+
+	// A blank or wildcarded name is replaced by the real one after connecting, so there's no
+	// point comparing it
+	if nc.QMgrName != "" && !strings.HasPrefix(nc.QMgrName, "*") && nc.QMgrName != cm.QMgrName {
+		ch.Restart = append(ch.Restart, "connection.queueManager")
+	}
+	for _, s := range restartSettings {
+		if differs(s, cm, nc) {
+			ch.Restart = append(ch.Restart, s.name)
+		}
+	}
+
+	ch.Queues = cm.MonitoredQueues != nc.MonitoredQueues
+	ch.Channels = cm.MonitoredChannels != nc.MonitoredChannels ||
+		cm.MonitoredAMQPChannels != nc.MonitoredAMQPChannels ||
+		cm.MonitoredMQTTChannels != nc.MonitoredMQTTChannels
+
+	cm.LogLevel = nc.LogLevel
+
+	cm.pollInterval = nc.pollInterval
+	cm.PollIntervalDuration = nc.PollIntervalDuration
+	cm.PollTierConfigs = nc.PollTierConfigs
+	cm.PollTiers = nc.PollTiers
+	cm.rediscoverInterval = nc.rediscoverInterval
+	cm.RediscoverDuration = nc.RediscoverDuration
+	cm.reloadInterval = nc.reloadInterval
+	cm.ReloadIntervalDuration = nc.ReloadIntervalDuration
+	cm.secretTTL = nc.secretTTL
+	cm.SecretTTLDuration = nc.SecretTTLDuration
+	cm.reconnectInterval = nc.reconnectInterval
+	cm.ReconnectIntervalDuration = nc.ReconnectIntervalDuration
+	cm.reconnectMaxInterval = nc.reconnectMaxInterval
+	cm.ReconnectMaxIntervalDuration = nc.ReconnectMaxIntervalDuration
+
+	cm.MonitoredQueues = nc.MonitoredQueues
+	cm.MonitoredQueuesFile = nc.MonitoredQueuesFile
+	cm.MonitoredChannels = nc.MonitoredChannels
+	cm.MonitoredChannelsFile = nc.MonitoredChannelsFile
+	cm.MonitoredAMQPChannels = nc.MonitoredAMQPChannels
+	cm.MonitoredAMQPChannelsFile = nc.MonitoredAMQPChannelsFile
+	cm.MonitoredMQTTChannels = nc.MonitoredMQTTChannels
+	cm.MonitoredMQTTChannelsFile = nc.MonitoredMQTTChannelsFile
+	cm.MonitoredTopics = nc.MonitoredTopics
+	cm.MonitoredTopicsFile = nc.MonitoredTopicsFile
+	cm.MonitoredSubscriptions = nc.MonitoredSubscriptions
+	cm.MonitoredSubscriptionsFile = nc.MonitoredSubscriptionsFile
+
+	return ch
+}
+
+// The rules are compared by their contents, as a pointer to the replacement would always differ
+func relabelConfigs(c *Config) interface{} {
+	var rules []string
+	for _, r := range c.RelabelConfigs {
+		rule := fmt.Sprintf("%s %v %q %s %q %d", r.Action, r.SourceLabels, r.Separator, r.TargetLabel, r.Regex, r.Modulus)
+		if r.Replacement != nil {
+			rule += fmt.Sprintf(" %q", *r.Replacement)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// A metadataMap is read in no particular order, so the tags are sorted before comparing
+func metadata(c *Config) interface{} {
+	var m []string
+	for i := range c.MetadataTagsArray {
+		if i < len(c.MetadataValuesArray) {
+			m = append(m, c.MetadataTagsArray[i]+"="+c.MetadataValuesArray[i])
+		}
+	}
+	sort.Strings(m)
+	return m
+}
+
+func differs(s setting, cm *Config, nc *Config) bool {
+	return fmt.Sprint(s.value(cm)) != fmt.Sprint(s.value(nc))
+}
@@ -0,0 +1,221 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+A password or token can be given as a reference to where it is kept, instead of as the value
+itself. The reference is looked up when the value is needed, and again when reconnecting, so a
+rotated password is picked up without restarting the collector.
+
+	env:NAME                The value of the environment variable
+	file:/path              The first line of the file
+	exec:/path/to/cmd args  The first line of the command's output. The arguments are split on spaces.
+
+Anything else is the value itself. A password that really does start with one of those
+prefixes, which would have been used as it is before references were added, can be given
+after "literal:". So "literal:exec:xyz" is the password "exec:xyz".
+
+A value that has been looked up is kept for the secretTTL, so that several queue managers
+sharing a password don't run the same command at once. Only one lookup of a reference is
+made at a time, and anyone else wanting it waits for that one. The lock is only held while
+the saved values are used, so a slow command doesn't hold up the other references, such as
+the web server's credentials that are checked on every request.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	secretEnv  = "env:"
+	secretFile = "file:"
+	secretExec = "exec:"
+
+	// For a value that would otherwise look like a reference
+	secretLiteral = "literal:"
+
+	// How long a command can take to give us a secret
+	secretExecTimeout = 30 * time.Second
+)
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// A lookup that's in progress. Everyone else asking for the same reference waits for it.
+type secretLookup struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+var (
+	secretMutex   sync.Mutex
+	secretCache   = make(map[string]cachedSecret)
+	secretLookups = make(map[string]*secretLookup)
+)
+
+// IsSecretRef says whether a value is a reference to a secret, rather than the secret itself
+func IsSecretRef(s string) bool {
+	return strings.HasPrefix(s, secretEnv) || strings.HasPrefix(s, secretFile) || strings.HasPrefix(s, secretExec)
+}
+
+// IsLiteralSecret says whether a value has been escaped so it isn't taken as a reference
+func IsLiteralSecret(s string) bool {
+	return strings.HasPrefix(s, secretLiteral)
+}
+
+/*
+ResolveSecret returns the secret that a reference points to, or the value after "literal:".
+Any other value is returned unchanged. A value that was looked up within the ttl is reused.
+*/
+func ResolveSecret(s string, ttl time.Duration) (string, error) {
+	if IsLiteralSecret(s) {
+		return strings.TrimPrefix(s, secretLiteral), nil
+	}
+	if !IsSecretRef(s) {
+		return s, nil
+	}
+
+	secretMutex.Lock()
+	if c, ok := secretCache[s]; ok && time.Now().Before(c.expires) {
+		secretMutex.Unlock()
+		return c.value, nil
+	}
+	l, running := secretLookups[s]
+	if !running {
+		l = &secretLookup{done: make(chan struct{})}
+		secretLookups[s] = l
+	}
+	secretMutex.Unlock()
+
+	if running {
+		<-l.done
+		return l.value, l.err
+	}
+
+	l.value, l.err = lookupSecret(s)
+	if l.err != nil {
+		l.value = ""
+	}
+
+	secretMutex.Lock()
+	delete(secretLookups, s)
+	if l.err == nil && ttl > 0 {
+		secretCache[s] = cachedSecret{value: l.value, expires: time.Now().Add(ttl)}
+	}
+	secretMutex.Unlock()
+	close(l.done)
+
+	return l.value, l.err
+}
+
+/*
+ForgetSecret drops any saved value for a reference, so that it is looked up again next
+time. It's used when the value has been rejected, as it has probably been changed.
+*/
+func ForgetSecret(s string) {
+	secretMutex.Lock()
+	defer secretMutex.Unlock()
+	delete(secretCache, s)
+}
+
+// CheckSecretRef makes sure that a reference can be used, without actually looking it up.
+// Any other value is fine.
+func CheckSecretRef(s string) error {
+	var err error
+
+	switch {
+	case strings.HasPrefix(s, secretEnv):
+		if strings.TrimPrefix(s, secretEnv) == "" {
+			err = fmt.Errorf("missing environment variable name in %s", s)
+		}
+	case strings.HasPrefix(s, secretFile):
+		if strings.TrimPrefix(s, secretFile) == "" {
+			err = fmt.Errorf("missing file name in %s", s)
+		}
+	case strings.HasPrefix(s, secretExec):
+		if len(strings.Fields(strings.TrimPrefix(s, secretExec))) == 0 {
+			err = fmt.Errorf("missing command in %s", s)
+		}
+	}
+	return err
+}
+
+func lookupSecret(s string) (string, error) {
+	var v string
+	var err error
+
+	err = CheckSecretRef(s)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case strings.HasPrefix(s, secretEnv):
+		name := strings.TrimPrefix(s, secretEnv)
+		v = os.Getenv(name)
+		if v == "" {
+			err = fmt.Errorf("environment variable %s for secret is not set", name)
+		}
+	case strings.HasPrefix(s, secretFile):
+		v, err = GetPasswordFromFile(strings.TrimPrefix(s, secretFile), false)
+	case strings.HasPrefix(s, secretExec):
+		v, err = execSecret(strings.Fields(strings.TrimPrefix(s, secretExec)))
+	}
+	return v, err
+}
+
+// Run the command, and use the first line of its output. Anything written to stderr is
+// only used in the error message, as the command might have failed half way through.
+func execSecret(args []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretExecTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return "", fmt.Errorf("Running %s for secret: %v: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("Running %s for secret: %v", args[0], err)
+	}
+
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Scan()
+	v := strings.TrimSpace(scanner.Text())
+	if v == "" {
+		return "", fmt.Errorf("Running %s for secret: no output", args[0])
+	}
+	return v, nil
+}
@@ -0,0 +1,206 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Write a shell script that the exec: references can run
+func writeScript(t *testing.T, dir string, name string, body string) string {
+	t.Helper()
+	f := filepath.Join(dir, name)
+	if err := os.WriteFile(f, []byte("#!/bin/sh\n"+body), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MQ_TEST_SECRET", "fromenv")
+	pwFile := filepath.Join(dir, "pw")
+	if err := os.WriteFile(pwFile, []byte("fromfile\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	echo := writeScript(t, dir, "echo.sh", "echo \"$1\"\necho second line\n")
+	fail := writeScript(t, dir, "fail.sh", "echo broken >&2\nexit 3\n")
+	empty := writeScript(t, dir, "empty.sh", "exit 0\n")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  string
+	}{
+		{name: "plain value", in: "passw0rd", want: "passw0rd"},
+		{name: "empty value", in: "", want: ""},
+		{name: "environment", in: "env:MQ_TEST_SECRET", want: "fromenv"},
+		{name: "unset environment", in: "env:MQ_TEST_NOT_SET", err: "MQ_TEST_NOT_SET for secret is not set"},
+		{name: "missing variable name", in: "env:", err: "missing environment variable name"},
+		{name: "file", in: "file:" + pwFile, want: "fromfile"},
+		{name: "missing file name", in: "file:", err: "missing file name"},
+		{name: "command first line", in: "exec:" + echo + " fromexec", want: "fromexec"},
+		{name: "command fails", in: "exec:" + fail, err: "broken"},
+		{name: "command without output", in: "exec:" + empty, err: "no output"},
+		{name: "missing command", in: "exec: ", err: "missing command"},
+		{name: "literal reference", in: "literal:exec:xyz", want: "exec:xyz"},
+		{name: "literal plain", in: "literal:passw0rd", want: "passw0rd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSecret(tt.in, 0)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolved to %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// The escaped values are still secrets, so they mustn't be shown as if they were references
+func TestIsSecretRef(t *testing.T) {
+	tests := []struct {
+		in      string
+		ref     bool
+		literal bool
+	}{
+		{in: "passw0rd"},
+		{in: "env:X", ref: true},
+		{in: "file:/x", ref: true},
+		{in: "exec:/bin/x", ref: true},
+		{in: "literal:env:X", literal: true},
+		{in: "Env:X"},
+	}
+	for _, tt := range tests {
+		if got := IsSecretRef(tt.in); got != tt.ref {
+			t.Errorf("IsSecretRef(%q) is %v", tt.in, got)
+		}
+		if got := IsLiteralSecret(tt.in); got != tt.literal {
+			t.Errorf("IsLiteralSecret(%q) is %v", tt.in, got)
+		}
+	}
+}
+
+func TestResolveSecretTTL(t *testing.T) {
+	const name = "MQ_TEST_ROTATED"
+	ref := "env:" + name
+	defer ForgetSecret(ref)
+
+	t.Setenv(name, "one")
+	if v, err := ResolveSecret(ref, time.Hour); err != nil || v != "one" {
+		t.Fatalf("First lookup gave %q, %v", v, err)
+	}
+
+	// Still within the TTL, so the old value is used
+	t.Setenv(name, "two")
+	if v, _ := ResolveSecret(ref, time.Hour); v != "one" {
+		t.Errorf("Cached lookup gave %q, expected one", v)
+	}
+
+	// Until it's been rejected
+	ForgetSecret(ref)
+	if v, _ := ResolveSecret(ref, time.Hour); v != "two" {
+		t.Errorf("Lookup after forgetting gave %q, expected two", v)
+	}
+
+	// And nothing's kept without a TTL
+	other := "env:MQ_TEST_NO_TTL"
+	t.Setenv("MQ_TEST_NO_TTL", "one")
+	ResolveSecret(other, 0)
+	t.Setenv("MQ_TEST_NO_TTL", "two")
+	if v, _ := ResolveSecret(other, 0); v != "two" {
+		t.Errorf("Lookup without a TTL gave %q, expected two", v)
+	}
+}
+
+// Everyone asking for a slow reference at the same time gets the one lookup, while other
+// references are still looked up straight away.
+func TestResolveSecretConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	count := filepath.Join(dir, "count")
+	release := filepath.Join(dir, "release")
+	slow := writeScript(t, dir, "slow.sh",
+		"echo x >> "+count+"\nwhile [ ! -f "+release+" ]; do sleep 0.05; done\necho slowvalue\n")
+	ref := "exec:" + slow
+	defer ForgetSecret(ref)
+
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := ResolveSecret(ref, time.Hour)
+			if err != nil {
+				t.Errorf("Slow lookup failed: %v", err)
+			}
+			results[i] = v
+		}(i)
+	}
+
+	// Wait for the command to be running, and then make sure it's not holding up anyone else
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(count); err == nil {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Slow command did not start")
+		}
+	}
+	t.Setenv("MQ_TEST_FAST", "fast")
+	done := make(chan struct{})
+	go func() {
+		ResolveSecret("env:MQ_TEST_FAST", 0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Lookup of another reference waited for the slow command")
+	}
+
+	if err := os.WriteFile(release, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	for i, v := range results {
+		if v != "slowvalue" {
+			t.Errorf("Lookup %d gave %q", i, v)
+		}
+	}
+	if b, _ := os.ReadFile(count); strings.Count(string(b), "x") != 1 {
+		t.Errorf("Command was run %d times, expected once", strings.Count(string(b), "x"))
+	}
+}
@@ -0,0 +1,328 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+A TLS client connection can be configured in the connection.tls section, instead of having
+to build and distribute a CCDT. The mqmetric package only knows about a CCDT, or a plain
+connName and channel, so the settings are given to the MQ client in the ways it already
+understands:
+
+  - The cipherSpec, certificateLabel and peerName belong to the channel. They are written,
+    with the connName and channel, into a JSON CCDT which is then used for the connection.
+  - The keyRepository applies to the whole process, and is set in the MQSSLKEYR environment
+    variable. So it can't be different for each queue manager.
+  - The revocation checks, and the keyRepositoryPassword, go in the SSL stanza of an
+    mqclient.ini file that is named in MQCLNTCF, unless MQCLNTCF is already set. The mqmetric
+    package has no way to take an MQSCO, which would have been the better place for the
+    password. Where we make a connection of our own, the password is given in the MQSCO.
+
+The files are written for each attempt to connect, in a new directory that only this user
+can read, and that directory is removed as soon as the connect call has returned. The MQ
+client has read what it needs by then. So a keystore password given as a secret reference
+is looked up again each time, and it's not left lying around on disk.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TLSConfig holds the settings for a TLS client connection
+type TLSConfig struct {
+	KeyRepository         string
+	KeyRepositoryPassword string
+	CipherSpec            string
+	CertificateLabel      string
+	PeerName              string
+	RevocationCheck       string
+	OCSPCheckExtensions   bool
+	CDPCheckExtensions    bool
+}
+
+// The parts of a JSON CCDT that we need
+type ccdtFile struct {
+	Channel []ccdtChannel `json:"channel"`
+}
+
+type ccdtChannel struct {
+	Name                 string                   `json:"name"`
+	Type                 string                   `json:"type"`
+	ClientConnection     ccdtClientConnection     `json:"clientConnection"`
+	TransmissionSecurity ccdtTransmissionSecurity `json:"transmissionSecurity"`
+}
+
+type ccdtClientConnection struct {
+	Connection   []ccdtConnection `json:"connection"`
+	QueueManager string           `json:"queueManager"`
+}
+
+type ccdtConnection struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type ccdtTransmissionSecurity struct {
+	CipherSpecification string `json:"cipherSpecification"`
+	CertificateLabel    string `json:"certificateLabel,omitempty"`
+	CertificatePeerName string `json:"certificatePeerName,omitempty"`
+}
+
+const defaultMQPort = 1414
+
+// Whether any of the TLS settings have been given
+func (t *TLSConfig) configured() bool {
+	return t.KeyRepository != "" || t.KeyRepositoryPassword != "" || t.CipherSpec != "" ||
+		t.CertificateLabel != "" || t.PeerName != "" || t.RevocationCheck != "" ||
+		!t.OCSPCheckExtensions || !t.CDPCheckExtensions
+}
+
+// The revocation checks are left to the MQ client's own defaults unless something was said
+func (t *TLSConfig) revocationConfigured() bool {
+	return t.RevocationCheck != "" || !t.OCSPCheckExtensions || !t.CDPCheckExtensions
+}
+
+// Called from VerifyConfig
+func verifyTLS(cm *Config) error {
+	var err error
+	t := &cm.TLS
+
+	if !t.configured() {
+		return nil
+	}
+
+	if t.CipherSpec == "" {
+		err = fmt.Errorf("The connection.tls settings need a cipherSpec")
+	} else if cm.CC.CcdtUrl != "" {
+		err = fmt.Errorf("Cannot use both a CCDT and the connection.tls settings. Put the TLS settings in the CCDT instead")
+	} else if cm.CC.ConnName == "" || cm.CC.Channel == "" {
+		err = fmt.Errorf("The connection.tls settings need a connName and channel")
+	}
+
+	if err == nil && t.RevocationCheck != "" {
+		switch strings.ToUpper(t.RevocationCheck) {
+		case "REQUIRED", "OPTIONAL", "DISABLED":
+		default:
+			err = fmt.Errorf("Invalid value for TLS revocationCheck parameter: %s. Use required, optional or disabled", t.RevocationCheck)
+		}
+	}
+
+	if err == nil && IsSecretRef(t.KeyRepositoryPassword) {
+		err = CheckSecretRef(t.KeyRepositoryPassword)
+		if err != nil {
+			err = fmt.Errorf("Invalid value for TLS keyRepositoryPassword parameter: %v", err)
+		}
+	}
+	return err
+}
+
+// TLSConnection is what PrepareTLS has set up for one attempt to connect
+type TLSConnection struct {
+	CcdtUrl         string
+	KeyRepository   string // Without the ".kdb", as it's given to the MQ client
+	KeyRepoPassword string
+	dir             string
+}
+
+var (
+	tlsMutex     sync.Mutex
+	generatedIni string // The mqclient.ini that we last pointed MQCLNTCF at
+)
+
+/*
+PrepareTLS sets up the MQ client for a TLS connection with the configured settings, and
+returns the CCDT and keystore details to use for that connection. It's called before each
+attempt to connect, and Remove must be called once the attempt has been made.
+*/
+func PrepareTLS(cm *Config) (*TLSConnection, error) {
+	var err error
+	t := &cm.TLS
+	tc := &TLSConnection{}
+
+	tlsMutex.Lock()
+	defer tlsMutex.Unlock()
+
+	if t.KeyRepository != "" {
+		// MQSSLKEYR is the name of the key repository without its ".kdb"
+		tc.KeyRepository = strings.TrimSuffix(t.KeyRepository, ".kdb")
+		err = os.Setenv("MQSSLKEYR", tc.KeyRepository)
+	}
+
+	if err == nil && t.KeyRepositoryPassword != "" {
+		tc.KeyRepoPassword, err = ResolveSecret(t.KeyRepositoryPassword, cm.SecretTTLDuration)
+		if err != nil {
+			err = fmt.Errorf("Cannot get key repository password: %v", err)
+		}
+	}
+
+	if err == nil {
+		tc.dir, err = os.MkdirTemp("", "mq-metric-")
+		if err != nil {
+			err = fmt.Errorf("Cannot create directory for TLS connection files: %v", err)
+		}
+	}
+
+	if err == nil && (t.revocationConfigured() || tc.KeyRepoPassword != "") {
+		err = writeClientIni(t, tc)
+	}
+
+	if err == nil {
+		tc.CcdtUrl, err = writeCcdt(cm, tc.dir)
+	}
+
+	if err != nil {
+		tc.remove()
+		return nil, err
+	}
+	return tc, nil
+}
+
+// Remove deletes the files that were written for the connection. It can be called on a nil TLSConnection.
+func (tc *TLSConnection) Remove() {
+	if tc == nil || tc.dir == "" {
+		return
+	}
+
+	tlsMutex.Lock()
+	defer tlsMutex.Unlock()
+	tc.remove()
+}
+
+// The tlsMutex must already be held
+func (tc *TLSConnection) remove() {
+	if tc.dir == "" {
+		return
+	}
+
+	// Don't leave MQCLNTCF naming a file that's gone
+	if generatedIni != "" && filepath.Dir(generatedIni) == tc.dir {
+		if os.Getenv("MQCLNTCF") == generatedIni {
+			os.Unsetenv("MQCLNTCF")
+		}
+		generatedIni = ""
+	}
+	if err := os.RemoveAll(tc.dir); err != nil {
+		log.Warnf("Cannot remove TLS connection files in %s: %v", tc.dir, err)
+	}
+	tc.dir = ""
+}
+
+// Write the connection details to a CCDT that's only used for this connection
+func writeCcdt(cm *Config, dir string) (string, error) {
+	var conns []ccdtConnection
+
+	for _, cn := range strings.Split(cm.CC.ConnName, ",") {
+		host, port, err := parseConnName(cn)
+		if err != nil {
+			return "", err
+		}
+		conns = append(conns, ccdtConnection{Host: host, Port: port})
+	}
+
+	ccdt := ccdtFile{Channel: []ccdtChannel{{
+		Name: cm.CC.Channel,
+		Type: "clientConnection",
+		ClientConnection: ccdtClientConnection{
+			Connection:   conns,
+			QueueManager: strings.TrimPrefix(cm.QMgrName, "*"),
+		},
+		TransmissionSecurity: ccdtTransmissionSecurity{
+			CipherSpecification: cm.TLS.CipherSpec,
+			CertificateLabel:    cm.TLS.CertificateLabel,
+			CertificatePeerName: cm.TLS.PeerName,
+		},
+	}}}
+
+	b, err := json.MarshalIndent(ccdt, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	f := filepath.Join(dir, "ccdt.json")
+	err = os.WriteFile(f, b, 0600)
+	if err != nil {
+		return "", fmt.Errorf("Cannot write CCDT for TLS connection: %v", err)
+	}
+	log.Debugf("Using generated CCDT %s for TLS connection", f)
+	return "file://" + filepath.ToSlash(f), nil
+}
+
+// A connName entry is "host(port)", or just "host" for the default port
+func parseConnName(cn string) (string, int, error) {
+	cn = strings.TrimSpace(cn)
+	host, port, hasPort := strings.Cut(cn, "(")
+	if !hasPort {
+		return host, defaultMQPort, nil
+	}
+	p, err := strconv.Atoi(strings.TrimSuffix(port, ")"))
+	if err != nil || !strings.HasSuffix(port, ")") || host == "" {
+		return "", 0, fmt.Errorf("Invalid connName %s", cn)
+	}
+	return host, p, nil
+}
+
+// Write an mqclient.ini with the revocation checks and keystore password. If the MQ client has
+// already been pointed at a configuration file of somebody else's, then that's left alone and
+// the settings have to go in it.
+func writeClientIni(t *TLSConfig, tc *TLSConnection) error {
+	f := filepath.Join(tc.dir, "mqclient.ini")
+
+	if cur := os.Getenv("MQCLNTCF"); cur != "" && cur != generatedIni {
+		log.Warnf("MQCLNTCF is set to %s, so the TLS revocation settings and keystore password are not used. Put them in the SSL stanza of that file.", cur)
+		return nil
+	}
+
+	yesNo := func(b bool) string {
+		if b {
+			return "YES"
+		}
+		return "NO"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SSL:\n")
+	if tc.KeyRepoPassword != "" {
+		fmt.Fprintf(&sb, "  SSLKeyRepositoryPassword=%s\n", tc.KeyRepoPassword)
+	}
+	if t.revocationConfigured() {
+		if t.RevocationCheck != "" {
+			fmt.Fprintf(&sb, "  ClientRevocationChecks=%s\n", strings.ToUpper(t.RevocationCheck))
+		}
+		fmt.Fprintf(&sb, "  OCSPCheckExtensions=%s\n", yesNo(t.OCSPCheckExtensions))
+		fmt.Fprintf(&sb, "  CDPCheckExtensions=%s\n", yesNo(t.CDPCheckExtensions))
+	}
+
+	err := os.WriteFile(f, []byte(sb.String()), 0600)
+	if err == nil {
+		err = os.Setenv("MQCLNTCF", f)
+	}
+	if err == nil {
+		generatedIni = f
+	} else {
+		err = fmt.Errorf("Cannot write mqclient.ini for TLS connection: %v", err)
+	}
+	return err
+}
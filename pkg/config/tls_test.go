@@ -0,0 +1,193 @@
+package config
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tlsTestConfig() *Config {
+	cm := &Config{QMgrName: "QM1"}
+	cm.CC.ConnName = "host1(1415),host2"
+	cm.CC.Channel = "SYSTEM.TLS.SVRCONN"
+	cm.TLS = TLSConfig{CipherSpec: "ANY_TLS13", OCSPCheckExtensions: true, CDPCheckExtensions: true}
+	return cm
+}
+
+func TestVerifyTLS(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cm *Config)
+		err    string
+	}{
+		{name: "not configured", change: func(cm *Config) { cm.TLS = TLSConfig{OCSPCheckExtensions: true, CDPCheckExtensions: true} }},
+		{name: "valid", change: func(cm *Config) {}},
+		{name: "no cipherSpec", change: func(cm *Config) { cm.TLS.CipherSpec = ""; cm.TLS.PeerName = "CN=QM1" }, err: "need a cipherSpec"},
+		{name: "only a revocation setting", change: func(cm *Config) { cm.TLS = TLSConfig{CDPCheckExtensions: true} }, err: "need a cipherSpec"},
+		{name: "with a CCDT", change: func(cm *Config) { cm.CC.CcdtUrl = "file:///ccdt.json" }, err: "both a CCDT"},
+		{name: "no channel", change: func(cm *Config) { cm.CC.Channel = "" }, err: "need a connName and channel"},
+		{name: "revocation check", change: func(cm *Config) { cm.TLS.RevocationCheck = "Optional" }},
+		{name: "bad revocation check", change: func(cm *Config) { cm.TLS.RevocationCheck = "sometimes" }, err: "revocationCheck"},
+		{name: "password reference", change: func(cm *Config) { cm.TLS.KeyRepositoryPassword = "env:MQ_KDB_PW" }},
+		{name: "bad password reference", change: func(cm *Config) { cm.TLS.KeyRepositoryPassword = "env:" }, err: "keyRepositoryPassword"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := tlsTestConfig()
+			tt.change(cm)
+			err := verifyTLS(cm)
+			if tt.err == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("Expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseConnName(t *testing.T) {
+	tests := []struct {
+		in   string
+		host string
+		port int
+		err  bool
+	}{
+		{in: "host(1415)", host: "host", port: 1415},
+		{in: " host ", host: "host", port: defaultMQPort},
+		{in: "10.0.0.1(1414)", host: "10.0.0.1", port: 1414},
+		{in: "host(abc)", err: true},
+		{in: "host(1415", err: true},
+		{in: "(1415)", err: true},
+	}
+	for _, tt := range tests {
+		host, port, err := parseConnName(tt.in)
+		if (err != nil) != tt.err || host != tt.host || port != tt.port {
+			t.Errorf("parseConnName(%q) is %q, %d, %v", tt.in, host, port, err)
+		}
+	}
+}
+
+func TestPrepareTLS(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("MQSSLKEYR", "")
+	t.Setenv("MQCLNTCF", "")
+	t.Setenv("MQ_TEST_KDB_PW", "kdbpass")
+
+	cm := tlsTestConfig()
+	cm.QMgrName = "*QM1"
+	cm.TLS.KeyRepository = "/var/mqm/key.kdb"
+	cm.TLS.KeyRepositoryPassword = "env:MQ_TEST_KDB_PW"
+	cm.TLS.CertificateLabel = "client"
+	cm.TLS.RevocationCheck = "required"
+	cm.TLS.CDPCheckExtensions = false
+
+	tc, err := PrepareTLS(cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.KeyRepository != "/var/mqm/key" || os.Getenv("MQSSLKEYR") != "/var/mqm/key" {
+		t.Errorf("Key repository is %q, MQSSLKEYR is %q", tc.KeyRepository, os.Getenv("MQSSLKEYR"))
+	}
+	if tc.KeyRepoPassword != "kdbpass" {
+		t.Errorf("Key repository password is %q", tc.KeyRepoPassword)
+	}
+
+	// The CCDT has the channel's settings
+	ccdtName, ok := strings.CutPrefix(tc.CcdtUrl, "file://")
+	if !ok {
+		t.Fatalf("CCDT URL is %s", tc.CcdtUrl)
+	}
+	var ccdt ccdtFile
+	b, err := os.ReadFile(filepath.FromSlash(ccdtName))
+	if err == nil {
+		err = json.Unmarshal(b, &ccdt)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := ccdt.Channel[0]
+	if ch.Name != "SYSTEM.TLS.SVRCONN" || ch.ClientConnection.QueueManager != "QM1" ||
+		len(ch.ClientConnection.Connection) != 2 || ch.ClientConnection.Connection[1].Port != defaultMQPort ||
+		ch.TransmissionSecurity.CipherSpecification != "ANY_TLS13" || ch.TransmissionSecurity.CertificateLabel != "client" {
+		t.Errorf("CCDT is %s", b)
+	}
+
+	// And the mqclient.ini has the rest
+	ini := os.Getenv("MQCLNTCF")
+	b, err = os.ReadFile(ini)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"SSLKeyRepositoryPassword=kdbpass", "ClientRevocationChecks=REQUIRED", "OCSPCheckExtensions=YES", "CDPCheckExtensions=NO"} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("%s is not in mqclient.ini:\n%s", s, b)
+		}
+	}
+
+	// Nothing is left behind
+	dir := filepath.Dir(ini)
+	tc.Remove()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("%s was not removed", dir)
+	}
+	if os.Getenv("MQCLNTCF") != "" {
+		t.Errorf("MQCLNTCF is still set to %s", os.Getenv("MQCLNTCF"))
+	}
+	tc.Remove()
+	(*TLSConnection)(nil).Remove()
+}
+
+// Someone else's mqclient.ini is not replaced
+func TestPrepareTLSOwnClientIni(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("MQCLNTCF", "/etc/mqclient.ini")
+
+	cm := tlsTestConfig()
+	cm.TLS.RevocationCheck = "disabled"
+	tc, err := PrepareTLS(cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tc.Remove()
+
+	if os.Getenv("MQCLNTCF") != "/etc/mqclient.ini" {
+		t.Errorf("MQCLNTCF was changed to %s", os.Getenv("MQCLNTCF"))
+	}
+	if _, err := os.Stat(filepath.Join(tc.dir, "mqclient.ini")); !os.IsNotExist(err) {
+		t.Errorf("An mqclient.ini was written")
+	}
+}
+
+func TestPrepareTLSBadConnName(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	cm := tlsTestConfig()
+	cm.CC.ConnName = "host(x)"
+	if _, err := PrepareTLS(cm); err == nil || !strings.Contains(err.Error(), "Invalid connName") {
+		t.Errorf("Expected an invalid connName error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("Files were left in %s", tmp)
+	}
+}
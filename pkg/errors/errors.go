@@ -25,37 +25,35 @@ package errors
  */
 
 import (
+	"os"
+
 	"github.com/ibm-messaging/mq-golang/v5/ibmmq"
 	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
 
 	log "github.com/sirupsen/logrus"
 )
 
-var (
-	statusCollectionErrors = 0
-)
-
-const (
-	maxStatusCollectionErrors = 3
-)
-
-// Allow (but still report) a small number of consecutive failures in collecting status. If that's
-// exceeded, then exit with a fatal error
-func HandleStatus(err error) {
+// A collection error is only returned once a queue manager connection has been lost, and we've
+// been told not to keep running. So there is nothing more to do. Coming to the end of a replayed
+// recording is a normal way to finish.
+func HandleCollection(err error) {
+	if err == pipeline.ErrReplayFinished {
+		log.Infoln("Replay has finished")
+		os.Exit(0)
+	}
 	if err != nil {
-		statusCollectionErrors++
-		if statusCollectionErrors > maxStatusCollectionErrors {
-			if mqe, ok := err.(mqmetric.MQMetricError); ok {
-				mqrc := mqe.MQReturn.MQRC
-				if mqrc == ibmmq.MQRC_NO_MSG_AVAILABLE {
-					log.Errorf("  Not all responses received in time. Perhaps queue manager is running slowly.")
-				}
-			}
-			log.Fatalf("Error collecting status: %v. Maximum permitted failures reached.", err)
-		} else {
-			log.Errorf("Error collecting status: %v. Continuing for now.", err)
+		log.Fatalf("Connection to queue manager has been lost: %v", err)
+	}
+}
+
+// If the queue manager is the standby instance of a multi-instance pair, there is no point
+// in carrying on with this connection.
+func ExitIfStandby(err error) {
+	if mqe, ok := err.(mqmetric.MQMetricError); ok {
+		if mqe.MQReturn.MQRC == ibmmq.MQRC_STANDBY_Q_MGR {
+			log.Errorln(err)
+			os.Exit(30) // This is the same as the strmqm return code for "active instance running elsewhere"
 		}
-	} else {
-		statusCollectionErrors = 0
 	}
 }
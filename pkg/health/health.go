@@ -0,0 +1,252 @@
+package health
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This package serves the /healthz and /readyz endpoints that a container platform such as
+Kubernetes can use for its liveness and readiness probes. Both return a JSON description of
+what was checked, with a status of 200 when everything is OK and 503 otherwise.
+
+/healthz fails only when the collector looks to be stuck: a single collection has been
+running for too long, or the main loop has stopped calling for new collections. Restarting
+the process would then be reasonable. It does not care whether the queue manager is available,
+as the collectors reconnect by themselves. That includes when no queue manager has been
+reached yet, as the main loop doesn't start until then.
+
+/readyz looks at each queue manager in turn. One that is not connected, has not given a
+successful collection recently, or whose last write to a backend failed is not ready. The
+collector is ready if any of them is ready, as the others can still be reported, unless it
+has been configured to need all of them.
+
+"Too long" and "recently" are both the configured number of collection intervals. The
+Prometheus collector has no fixed interval when it is driven by scrapes, so only the checks that
+don't depend on it are made, with a minute being used for the time a collection might take.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	log "github.com/sirupsen/logrus"
+)
+
+// Used for the longest collection when there is no fixed interval
+const defaultInterval = time.Minute
+
+type Checker struct {
+	g              *pipeline.Group
+	interval       time.Duration
+	staleIntervals int
+	readyAll       bool // Every queue manager must be ready, not just one
+	started        time.Time
+}
+
+type qmgrReport struct {
+	QMgr           string   `json:"qmgr"`
+	Connected      bool     `json:"connected"`
+	Ready          *bool    `json:"ready,omitempty"` // Only for /readyz
+	CollectingFor  string   `json:"collectingFor,omitempty"`
+	LastCollection string   `json:"lastCollection,omitempty"`
+	LastError      string   `json:"lastError,omitempty"`
+	FailingOutputs []string `json:"failingOutputs,omitempty"`
+	Problems       []string `json:"problems,omitempty"`
+}
+
+type report struct {
+	Status        string       `json:"status"`
+	LastCycle     string       `json:"lastCycle,omitempty"`
+	Problems      []string     `json:"problems,omitempty"`
+	QueueManagers []qmgrReport `json:"queueManagers"`
+}
+
+/*
+NewChecker looks at the collectors in the Group. The interval is how often the main
+loop asks for a collection, or 0 if that is not done on a timer. The readyWhen is "any"
+or "all", for how many of the queue managers must be ready.
+*/
+func NewChecker(g *pipeline.Group, interval time.Duration, staleIntervals int, readyWhen string) *Checker {
+	return &Checker{
+		g:              g,
+		interval:       interval,
+		staleIntervals: staleIntervals,
+		readyAll:       strings.EqualFold(readyWhen, "all"),
+		started:        time.Now(),
+	}
+}
+
+// Register adds the endpoints to an existing server
+func (ck *Checker) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", ck.healthz)
+	mux.HandleFunc("/readyz", ck.readyz)
+}
+
+/*
+Start runs a server for the endpoints, if a port has been configured. A failure to listen is
+logged but is not fatal, as the collector can still do its real job.
+*/
+func Start(cm *cf.Config, g *pipeline.Group, interval time.Duration) {
+	if cm.HealthPort == "" {
+		return
+	}
+
+	ck := NewChecker(g, interval, cm.HealthStaleIntervals, cm.HealthReadyWhen)
+	mux := http.NewServeMux()
+	ck.Register(mux)
+
+	address := cm.HealthHost + ":" + cm.HealthPort
+	go func() {
+		log.Infoln("Health checks listening on http address", address)
+		err := http.ListenAndServe(address, mux)
+		log.Errorf("Health check server has failed: %v", err)
+	}()
+}
+
+// How long without progress before something is considered to be wrong
+func (ck *Checker) staleness() time.Duration {
+	interval := ck.interval
+	if interval == 0 {
+		interval = defaultInterval
+	}
+	return interval * time.Duration(ck.staleIntervals)
+}
+
+func (ck *Checker) healthz(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	limit := ck.staleness()
+	rep := report{}
+
+	if ck.interval > 0 {
+		lastCycle := ck.g.LastCycle()
+		since := lastCycle
+		if since.IsZero() {
+			// Allow time for the first connections before expecting the loop to start
+			since = ck.started
+		} else {
+			rep.LastCycle = lastCycle.Format(time.RFC3339)
+		}
+		if now.Sub(since) > limit && ck.connectedOnce() {
+			rep.Problems = append(rep.Problems, fmt.Sprintf("No collection started for %v", now.Sub(since).Round(time.Second)))
+		}
+	}
+
+	for _, c := range ck.g.Collectors {
+		h := c.Health()
+		q := newQMgrReport(h, now)
+		if !h.Collecting.IsZero() && now.Sub(h.Collecting) > limit {
+			q.Problems = append(q.Problems, "Collection is taking too long")
+		}
+		rep.QueueManagers = append(rep.QueueManagers, q)
+	}
+
+	write(w, rep, rep.ok(true))
+}
+
+// Whether any of the queue managers has ever been reached
+func (ck *Checker) connectedOnce() bool {
+	for _, c := range ck.g.Collectors {
+		if c.ConnectedOnce() {
+			return true
+		}
+	}
+	return false
+}
+
+func (ck *Checker) readyz(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	rep := report{}
+
+	for _, c := range ck.g.Collectors {
+		h := c.Health()
+		q := newQMgrReport(h, now)
+		if !h.Connected {
+			q.Problems = append(q.Problems, "Not connected")
+		} else if h.LastCollection.IsZero() {
+			// A collection driven by scrapes might not have happened yet, and
+			// that's no reason to say we're not ready
+			if ck.interval > 0 && now.Sub(ck.started) > ck.staleness() {
+				q.Problems = append(q.Problems, "No successful collection")
+			}
+		} else if ck.interval > 0 && now.Sub(h.LastCollection) > ck.staleness() {
+			q.Problems = append(q.Problems, "No recent successful collection")
+		}
+		if len(h.FailingOutputs) > 0 {
+			q.Problems = append(q.Problems, "Last write to a backend failed")
+		}
+		ready := len(q.Problems) == 0
+		q.Ready = &ready
+		rep.QueueManagers = append(rep.QueueManagers, q)
+	}
+
+	write(w, rep, rep.ok(ck.readyAll))
+}
+
+func newQMgrReport(h pipeline.CollectorHealth, now time.Time) qmgrReport {
+	q := qmgrReport{
+		QMgr:           h.QMgr,
+		Connected:      h.Connected,
+		LastError:      h.LastError,
+		FailingOutputs: h.FailingOutputs,
+	}
+	if !h.Collecting.IsZero() {
+		q.CollectingFor = now.Sub(h.Collecting).Round(time.Millisecond).String()
+	}
+	if !h.LastCollection.IsZero() {
+		q.LastCollection = h.LastCollection.Format(time.RFC3339)
+	}
+	return q
+}
+
+/*
+The overall status fails if there are any problems that aren't about a single queue manager.
+Then either all of the queue managers must be free of problems, or at least one of them.
+*/
+func (rep report) ok(all bool) bool {
+	if len(rep.Problems) > 0 {
+		return false
+	}
+	good := 0
+	for _, q := range rep.QueueManagers {
+		if len(q.Problems) == 0 {
+			good++
+		}
+	}
+	if all {
+		return good == len(rep.QueueManagers)
+	}
+	return good > 0
+}
+
+func write(w http.ResponseWriter, rep report, ok bool) {
+	code := http.StatusOK
+	rep.Status = "ok"
+	if !ok {
+		code = http.StatusServiceUnavailable
+		rep.Status = "fail"
+	}
+
+	data, _ := json.MarshalIndent(rep, "", "  ")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
@@ -0,0 +1,100 @@
+package health_test
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/health"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+)
+
+type qmgrState struct {
+	QMgr     string   `json:"qmgr"`
+	Ready    *bool    `json:"ready"`
+	Problems []string `json:"problems"`
+}
+
+// One of two simulated queue managers is connected
+func TestReadyz(t *testing.T) {
+	g := pipeline.NewGroup([]*cf.Config{
+		{QMgrName: "QM1", Simulate: true},
+		{QMgrName: "QM2", Simulate: true},
+	})
+	if err := g.Collectors[0].Connect(); err != nil {
+		t.Fatalf("Cannot start the simulation: %v", err)
+	}
+
+	tests := []struct {
+		readyWhen string
+		code      int
+	}{
+		{"any", http.StatusOK},
+		{"all", http.StatusServiceUnavailable},
+		{"ALL", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.readyWhen, func(t *testing.T) {
+			mux := http.NewServeMux()
+			health.NewChecker(g, 0, 3, tt.readyWhen).Register(mux)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.code {
+				t.Errorf("Status is %d, expected %d", rec.Code, tt.code)
+			}
+
+			var body struct {
+				QueueManagers []qmgrState `json:"queueManagers"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.QueueManagers) != 2 {
+				t.Fatalf("Got %d queue managers in %s", len(body.QueueManagers), rec.Body.String())
+			}
+			for i, want := range []bool{true, false} {
+				q := body.QueueManagers[i]
+				if q.Ready == nil || *q.Ready != want {
+					t.Errorf("%s ready is %v, expected %v", q.QMgr, q.Ready, want)
+				}
+				if want == (len(q.Problems) > 0) {
+					t.Errorf("%s has problems %v", q.QMgr, q.Problems)
+				}
+			}
+		})
+	}
+}
+
+// When nothing can be collected the collector isn't ready, whatever the rule
+func TestReadyzNoneConnected(t *testing.T) {
+	g := pipeline.NewGroup([]*cf.Config{{QMgrName: "QM1", Simulate: true}})
+	for _, readyWhen := range []string{"any", "all"} {
+		mux := http.NewServeMux()
+		health.NewChecker(g, 0, 3, readyWhen).Register(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Status with readyWhen %s is %d, expected 503", readyWhen, rec.Code)
+		}
+	}
+}
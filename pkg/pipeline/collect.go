@@ -0,0 +1,795 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This file contains the collection cycle that used to be repeated in every one of the
+collectors. Each call to Collect reads the available publications, polls for object
+status when the pollInterval says it's time, does any rediscovery, and then walks the
+mqmetric structures to build a Batch of Points for the backends.
+*/
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ibm-messaging/mq-golang/v5/ibmmq"
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+Collector holds the state that has to be kept between collection cycles for a
+single queue manager connection.
+*/
+type Collector struct {
+	// The configuration is replaced rather than changed, so it can be read without the lock
+	cf             atomic.Pointer[cf.Config]
+	cfMutex        sync.Mutex
+	key            string
+	discoverConfig mqmetric.DiscoverConfig
+	connected      atomic.Bool
+	connectedOnce  atomic.Bool
+
+	first              bool
+	lastPoll           time.Time
+	lastQueueDiscovery time.Time
+	platform           int32
+	platformString     string
+	supportsHostname   bool
+
+	// Used by a Group to decide when to try reconnecting, and when to give up on a connection
+	nextConnect    time.Time
+	reconnectDelay time.Duration
+	statusErrors   int
+
+	stats *selfStats
+
+	// Where the collected values come from, and whether they are also being saved
+	src      source
+	recorder *recorder
+	replay   *replayer
+	sim      *simulator
+
+	// The mqmetric package only keeps these for whichever queue manager was last polled
+	qmgrDescription string
+	hostname        string
+
+	// mqmetric's object maps for this queue manager, while another Collector is using them
+	objects *mqmetric.ObjectMaps
+
+	// Queue attributes for the object labels that mqmetric doesn't have
+	inquired *inquiredAttributes
+
+	// The status polling tiers, and the status that has been collected by them
+	tiers      []*pollTier
+	queueTiers map[string]*pollTier
+	status     map[int]*mqmetric.StatusSet
+}
+
+/*
+A source gives the values from the latest collection, and the object attributes that go
+into the labels. Normally that's the mqmetric package, for the Collector's connection. When
+replaying a recording, it's the recorded data instead, and when simulating it's made up.
+*/
+type source interface {
+	publishedMetrics() *mqmetric.AllMetrics
+	objectStatus(ot int) *mqmetric.StatusSet
+	objectDescription(name string, ot int32) string
+	queueAttribute(name string, attr int32) string
+	publicationCount() int
+}
+
+type liveSource struct {
+	key      string
+	inquired *inquiredAttributes
+	status   map[int]*mqmetric.StatusSet
+}
+
+func (s liveSource) publishedMetrics() *mqmetric.AllMetrics {
+	return mqmetric.GetPublishedMetrics(s.key)
+}
+
+func (s liveSource) objectStatus(ot int) *mqmetric.StatusSet {
+	if set, ok := s.status[ot]; ok {
+		return set
+	}
+	return mqmetric.GetObjectStatus(s.key, ot)
+}
+
+func (s liveSource) objectDescription(name string, ot int32) string {
+	return mqmetric.GetObjectDescription(name, ot)
+}
+
+func (s liveSource) queueAttribute(name string, attr int32) string {
+	switch attr {
+	case ibmmq.MQCA_CUSTOM, ibmmq.MQIA_DEF_PERSISTENCE:
+		return s.inquired.get(name, attr)
+	}
+	return mqmetric.GetQueueAttribute(name, attr)
+}
+
+func (s liveSource) publicationCount() int {
+	return mqmetric.GetProcessPublicationCount()
+}
+
+// Each of the object types that are reported via status polling, with the functions
+// needed to turn their attributes into points
+type statusType struct {
+	objectType string
+	ot         int
+	normalise  func(*mqmetric.StatusAttribute, int64) float64
+	labels     func(*Collector, *mqmetric.StatusSet, string) (map[string]string, bool)
+}
+
+var statusTypes = []statusType{
+	{ObjectChannel, mqmetric.OT_CHANNEL, mqmetric.ChannelNormalise, (*Collector).channelLabels},
+	{ObjectQueue, mqmetric.OT_Q, mqmetric.QueueNormalise, (*Collector).queueStatusLabels},
+	{ObjectTopic, mqmetric.OT_TOPIC, mqmetric.TopicNormalise, (*Collector).topicLabels},
+	{ObjectSubscription, mqmetric.OT_SUB, mqmetric.SubNormalise, (*Collector).subLabels},
+	{ObjectQMgr, mqmetric.OT_Q_MGR, mqmetric.QueueManagerNormalise, (*Collector).qmgrStatusLabels},
+	{ObjectCluster, mqmetric.OT_CLUSTER, mqmetric.ClusterNormalise, (*Collector).clusterLabels},
+	{ObjectBufferPool, mqmetric.OT_BP, mqmetric.UsageNormalise, (*Collector).bpLabels},
+	{ObjectPageSet, mqmetric.OT_PS, mqmetric.UsageNormalise, (*Collector).psLabels},
+	{ObjectAMQP, mqmetric.OT_CHANNEL_AMQP, mqmetric.ChannelNormalise, (*Collector).amqpLabels},
+	{ObjectMQTT, mqmetric.OT_CHANNEL_MQTT, mqmetric.ChannelNormalise, (*Collector).mqttLabels},
+}
+
+/*
+NewCollector creates the collection state for the queue manager named in the
+configuration. It does not connect; that is done by Connect.
+*/
+func NewCollector(cm *cf.Config) *Collector {
+	c := &Collector{
+		key:      newKey(cm.QMgrName),
+		first:    true,
+		lastPoll: time.Now(),
+		stats:    newSelfStats(),
+		inquired: &inquiredAttributes{},
+		status:   make(map[int]*mqmetric.StatusSet),
+	}
+	c.cf.Store(cm)
+	c.setPollTiers()
+	c.src = liveSource{key: c.key, inquired: c.inquired, status: c.status}
+	if cm.ReplayFile != "" {
+		c.replay = newReplayer(cm.ReplayFile, cm.ReplayLoop)
+		c.src = c.replay
+	} else {
+		if cm.Simulate {
+			c.sim = newSimulator(cm)
+			c.src = c.sim
+		}
+		if cm.RecordFile != "" {
+			c.recorder = getRecorder(cm.RecordFile)
+		}
+	}
+	return c
+}
+
+// Config gives access to the configuration for this Collector's queue manager. It must
+// not be changed; use updateConfig for that.
+func (c *Collector) Config() *cf.Config {
+	return c.cf.Load()
+}
+
+// Change a copy of the configuration, and then make that the one in use
+func (c *Collector) updateConfig(update func(cm *cf.Config)) {
+	c.cfMutex.Lock()
+	defer c.cfMutex.Unlock()
+	cm := *c.cf.Load()
+	update(&cm)
+	c.cf.Store(&cm)
+}
+
+// InitAttributes sets up the status attributes for all of the object types. They
+// belong to the connection, so this is called again after each reconnect.
+func InitAttributes() {
+	mqmetric.ChannelInitAttributes()
+	mqmetric.QueueInitAttributes()
+	mqmetric.TopicInitAttributes()
+	mqmetric.SubInitAttributes()
+	mqmetric.QueueManagerInitAttributes()
+	mqmetric.UsageInitAttributes()
+	mqmetric.ClusterInitAttributes()
+	mqmetric.ChannelAMQPInitAttributes()
+	mqmetric.ChannelMQTTInitAttributes()
+}
+
+/*
+discover finds out what metrics the queue manager can provide, and subscribes to them.
+It also gets the static attributes for the configured channels. This is done after each
+successful connection, and the next collection is then discarded.
+*/
+func (c *Collector) discover() error {
+	cm := c.Config()
+	var err error
+
+	// Do we need to expand wildcarded queue names
+	// or use the wildcard as-is in the subscriptions
+	wildcardResource := true
+	if cm.MetaPrefix != "" {
+		wildcardResource = false
+	}
+
+	c.discoverConfig.MonitoredQueues.ObjectNames = cm.MonitoredQueues
+	c.discoverConfig.MonitoredQueues.SubscriptionSelector = strings.ToUpper(cm.QueueSubscriptionSelector)
+	c.discoverConfig.MonitoredQueues.UseWildcard = wildcardResource
+	c.discoverConfig.MetaPrefix = cm.MetaPrefix
+
+	if cm.MetricFilter != nil && wildcardResource {
+		// We can't know which types of queue statistics the filters leave us with until the
+		// queue manager has told us what's in them. So the first pass has no queues, and
+		// the subscriptions for each queue are made by the second one, from what's left
+		// of the metadata.
+		c.discoverConfig.MonitoredQueues.ObjectNames = ""
+		c.discoverConfig.MonitoredQueues.UseWildcard = false
+		err = mqmetric.DiscoverAndSubscribe(c.discoverConfig)
+		if err == nil {
+			c.discoverConfig.MonitoredQueues.SubscriptionSelector = c.dropFilteredTypes(true)
+			c.discoverConfig.MonitoredQueues.ObjectNames = cm.MonitoredQueues
+			c.discoverConfig.MonitoredQueues.UseWildcard = true
+			err = mqmetric.RediscoverAndSubscribe(c.discoverConfig)
+		}
+	} else {
+		err = mqmetric.DiscoverAndSubscribe(c.discoverConfig)
+		if err == nil && cm.MetricFilter != nil {
+			c.dropFilteredTypes(false)
+		}
+	}
+
+	if err == nil {
+		c.rediscoverAttributes()
+		c.inquireQueueAttributes()
+		clear(c.status)
+		c.first = true
+		c.lastQueueDiscovery = time.Now()
+	}
+	return err
+}
+
+/*
+dropFilteredTypes takes out of the discovered metadata any type of published statistics
+where the metric filters drop every element, so that nothing more is done with it. The
+names of the elements come from the queue manager's own METADATA topics.
+
+The queue statistics have a subscription for each type for every monitored queue. When
+the queues have not yet been subscribed to, the queue types can go too, and the new
+queue subscription selector is returned. The queue manager-level subscriptions have
+already been made by then, so they are closed before the type is dropped. Once it's gone
+from the metadata, a rediscovery doesn't subscribe to it again.
+*/
+func (c *Collector) dropFilteredTypes(queues bool) string {
+	sel := c.discoverConfig.MonitoredQueues.SubscriptionSelector
+	var kept []string
+	dropped := false
+
+	for _, cl := range mqmetric.GetPublishedMetrics(c.key).Classes {
+		for idx, ty := range cl.Types {
+			objectType := ObjectQMgr
+			used := false
+			for _, elem := range ty.Elements {
+				objectType = PublishedObjectType(elem)
+				if c.Config().MetricFilter.Allows(FullName(objectType, elem.MetricName)) {
+					used = true
+					break
+				}
+			}
+
+			if objectType == ObjectQueue {
+				if !queues {
+					continue
+				}
+				if used {
+					kept = append(kept, ty.Name)
+					continue
+				}
+				dropped = true
+			} else if used || len(ty.Elements) == 0 {
+				continue
+			}
+			log.Debugf("Metric filters drop all of %s/%s for %s", cl.Name, ty.Name, c.Config().QMgrName)
+			ty.Unsubscribe()
+			delete(cl.Types, idx)
+		}
+	}
+
+	if !dropped {
+		return sel
+	}
+	log.Debugf("Queue subscriptions for %s reduced by metric filters to %v", c.Config().QMgrName, kept)
+	if len(kept) == 0 {
+		return "NONE"
+	}
+	slices.Sort(kept)
+	return strings.Join(kept, ",")
+}
+
+func (c *Collector) rediscoverAttributes() {
+	e := mqmetric.RediscoverAttributes(ibmmq.MQOT_CHANNEL, c.Config().MonitoredChannels)
+	if c.platform != ibmmq.MQPL_ZOS {
+		if e == nil {
+			e = mqmetric.RediscoverAttributes(mqmetric.OT_CHANNEL_AMQP, c.Config().MonitoredAMQPChannels)
+		}
+		if e == nil {
+			e = mqmetric.RediscoverAttributes(mqmetric.OT_CHANNEL_MQTT, c.Config().MonitoredMQTTChannels)
+		}
+	}
+	if e != nil {
+		log.Debugf("Returned from RediscoverAttributes with error %v", e)
+	}
+}
+
+// QMgrName returns the name used in the "qmgr" label
+func (c *Collector) QMgrName() string {
+	return strings.TrimSpace(c.Config().QMgrName)
+}
+
+// Platform returns the name used in the "platform" label. It's only known
+// after the first connection.
+func (c *Collector) Platform() string {
+	return c.platformString
+}
+
+// IsZOS says whether the queue manager is on z/OS, which affects the object types
+// that can be reported
+func (c *Collector) IsZOS() bool {
+	return c.platform == ibmmq.MQPL_ZOS
+}
+
+/*
+Collect is called at regular intervals by each collector. If there's an error processing
+the publications then no Batch is returned. Errors from the status polling are returned
+along with whatever could be collected, so the caller can decide how serious they are.
+The first collection after a (re)discovery always returns an empty Batch, as there might be
+accumulated stuff from a while ago and lead to a misleading range on graphs.
+*/
+func (c *Collector) Collect() (*Batch, error) {
+	return c.CollectSelected(nil)
+}
+
+/*
+CollectSelected is like Collect, but only asks the queue manager for what the Selection
+wants. A nil Selection is everything. The replayed and simulated data is always complete,
+as there's no cost to making it.
+*/
+func (c *Collector) CollectSelected(sel *Selection) (*Batch, error) {
+	c.stats.startCollection()
+	b, err := c.collect(sel)
+	c.stats.endCollection(b != nil, err)
+	return b, err
+}
+
+func (c *Collector) collect(sel *Selection) (*Batch, error) {
+	var err error
+
+	if c.replay != nil {
+		return c.collectReplay()
+	}
+	if c.sim != nil {
+		return c.collectSimulate()
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	collectStartTime := time.Now()
+
+	// Publications that nobody has asked for are left where they are until somebody does.
+	// A recording needs all of them, so that it can be replayed.
+	skipPublications := sel != nil && !sel.publications() && c.recorder == nil
+
+	if !skipPublications {
+		// Clear out everything we know so far. In particular, replace
+		// the map of values for each object so the collection starts
+		// clean.
+		for _, cl := range mqmetric.GetPublishedMetrics(c.key).Classes {
+			for _, ty := range cl.Types {
+				for _, elem := range ty.Elements {
+					elem.Values = make(map[string]int64)
+				}
+			}
+		}
+
+		// Deal with all the publications that have arrived
+		err = c.stats.timed(PhasePublications, mqmetric.ProcessPublications)
+		if err != nil {
+			log.Errorf("Error processing publications: %v", err)
+			return nil, err
+		}
+		log.Debugf("Collected and processed %d resource publications successfully", mqmetric.GetProcessPublicationCount())
+	}
+
+	// Do we need to poll for object status on this iteration. Each of the tiers has its
+	// own interval, unless the Selection says which ones are wanted.
+	var pollStatus bool
+	if sel == nil {
+		pollStatus = c.dueTiers(time.Now())
+	} else {
+		pollStatus = c.selectTiers(sel, time.Now())
+	}
+	if pollStatus {
+		log.Debugf("Polling for object status")
+	} else {
+		log.Debugf("Skipping poll for object status")
+	}
+
+	// We may have unknown objects being referenced and while it is worth logging the
+	// error, it doesn't stop us trying the other object types.
+	if pollStatus {
+		err = c.pollStatus(sel)
+	}
+
+	thisDiscovery := time.Now()
+	if c.Config().RediscoverDuration > 0 {
+		if thisDiscovery.Sub(c.lastQueueDiscovery) >= c.Config().RediscoverDuration {
+			log.Debugf("Doing queue rediscovery")
+			c.stats.timed(PhaseDiscovery, func() error {
+				_ = mqmetric.RediscoverAndSubscribe(c.discoverConfig)
+				c.rediscoverAttributes()
+				c.inquireQueueAttributes()
+				return nil
+			})
+			c.lastQueueDiscovery = thisDiscovery
+		}
+	}
+
+	// Have now processed all of the publications, and all the MQ-owned
+	// value fields and maps have been updated.
+	if c.first {
+		c.first = false
+		return c.newBatch(time.Now(), pollStatus), err
+	}
+
+	if c.recorder != nil {
+		return c.recordBatch(pollStatus, collectStartTime), err
+	}
+	return c.buildBatch(time.Now(), pollStatus, skipPublications, collectStartTime), err
+}
+
+func (c *Collector) newBatch(ts time.Time, pollStatus bool) *Batch {
+	return &Batch{
+		Timestamp:    ts,
+		QMgr:         c.QMgrName(),
+		Platform:     c.Platform(),
+		StatusPolled: pollStatus,
+		filter:       c.Config().MetricFilter,
+		relabel:      c.Config().Relabel,
+	}
+}
+
+// Turn what the source now holds into a Batch of Points
+func (c *Collector) buildBatch(ts time.Time, pollStatus bool, skipPublications bool, collectStartTime time.Time) *Batch {
+	b := c.newBatch(ts, pollStatus)
+	b.PublicationsSkipped = skipPublications
+
+	// Start with a metric that shows how many publications were processed by this collection
+	publications := 0
+	if !skipPublications {
+		publications = c.src.publicationCount()
+	}
+	b.add(Point{
+		Metric:      "exporter_publications",
+		ObjectType:  ObjectQMgr,
+		Description: "How many resource publications processed",
+		Labels:      c.newLabels(),
+		Value:       float64(publications),
+		Source:      SourceExporter,
+	})
+
+	if !skipPublications {
+		c.addPublishedPoints(b)
+	}
+	if pollStatus {
+		c.addStatusPoints(b)
+	}
+
+	c.addSelfPoints(b)
+
+	b.add(Point{
+		Metric:      "exporter_collection_time",
+		ObjectType:  ObjectQMgr,
+		Description: "How long the last collection took",
+		Labels:      c.newLabels(),
+		Value:       time.Since(collectStartTime).Seconds(),
+		Unit:        UnitSeconds,
+		Source:      SourceExporter,
+	})
+
+	return b
+}
+
+/*
+DisconnectedBatch has a single point, showing that the queue manager is not available.
+There's no MQQMSTA_STOPPED value defined. All the regular qmgr status constants start
+from 1, so we use "0" to indicate qmgr not available/stopped. This must have the same
+set of labels as the other qmgr-level points.
+*/
+func (c *Collector) DisconnectedBatch() *Batch {
+	b := &Batch{
+		Timestamp:    time.Now(),
+		QMgr:         c.QMgrName(),
+		Platform:     c.Platform(),
+		StatusPolled: true,
+		filter:       c.Config().MetricFilter,
+		relabel:      c.Config().Relabel,
+	}
+	b.add(Point{
+		Metric:      mqmetric.ATTR_QMGR_STATUS,
+		ObjectType:  ObjectQMgr,
+		Description: "Queue Manager Status",
+		Labels:      c.QMgrLabels(mqmetric.DUMMY_STRING),
+		Value:       0,
+		Source:      SourceStatus,
+	})
+	return b
+}
+
+// Issue the various DISPLAY xxSTATUS commands for the tiers that are due. All of them are
+// tried, and the last error is returned. Each one is timed as a separate phase, named after
+// the object type.
+func (c *Collector) pollStatus(sel *Selection) error {
+	cm := c.Config()
+	var pollError error
+
+	check := func(what string, objectType string, collect func() error) {
+		err := c.stats.timed(objectType+"_status", collect)
+		if err != nil {
+			log.Errorf("Error collecting %s status: %v", what, err)
+			c.stats.statusError()
+			pollError = err
+		} else {
+			log.Debugf("Collected all %s status", what)
+		}
+	}
+
+	// The whole of the object type is polled, and its status set is then the one to use
+	poll := func(what string, objectType string, collect func() error) {
+		if !c.tierDue(objectType) {
+			return
+		}
+		check(what, objectType, collect)
+		for _, ot := range pollObjectTypes[objectType] {
+			c.status[ot] = mqmetric.GetObjectStatus(c.key, ot)
+		}
+	}
+
+	if cm.CC.UseStatus {
+		poll("channel", ObjectChannel, func() error { return mqmetric.CollectChannelStatus(cm.MonitoredChannels) })
+		poll("topic", ObjectTopic, func() error { return mqmetric.CollectTopicStatus(cm.MonitoredTopics) })
+		poll("subscription", ObjectSubscription, func() error { return mqmetric.CollectSubStatus(cm.MonitoredSubscriptions) })
+		c.pollQueueStatus(check)
+		if sel != nil && sel.namedQueues() {
+			c.pollNamedQueues(check, sel)
+		}
+	}
+
+	// DISPLAY QMSTATUS is not supported on z/OS
+	// but we do extract a couple of MQINQable attributes
+	poll("queue manager", ObjectQMgr, mqmetric.CollectQueueManagerStatus)
+	poll("cluster", ObjectCluster, mqmetric.CollectClusterStatus)
+
+	if c.platform == ibmmq.MQPL_ZOS {
+		poll("buffer pool/pageset", objectUsage, mqmetric.CollectUsageStatus)
+	} else {
+		if cm.MonitoredAMQPChannels != "" {
+			poll("AMQP channel", ObjectAMQP, func() error { return mqmetric.CollectAMQPChannelStatus(cm.MonitoredAMQPChannels) })
+		}
+		if cm.MonitoredMQTTChannels != "" {
+			poll("MQTT channel", ObjectMQTT, func() error { return mqmetric.CollectMQTTChannelStatus(cm.MonitoredMQTTChannels) })
+		}
+	}
+	c.carryStatus()
+
+	c.qmgrDescription = mqmetric.GetObjectDescription("", ibmmq.MQOT_Q_MGR)
+	if c.supportsHostname {
+		c.hostname = mqmetric.GetQueueManagerAttribute(cm.QMgrName, ibmmq.MQCACF_HOST_NAME)
+	}
+
+	return pollError
+}
+
+// The resource publications can refer to the queue manager, a queue, or a NativeHA instance
+func (c *Collector) addPublishedPoints(b *Batch) {
+	for _, cl := range c.src.publishedMetrics().Classes {
+		for _, ty := range cl.Types {
+			for _, elem := range ty.Elements {
+				kind := Gauge
+				if elem.Datatype == ibmmq.MQIAMO_MONITOR_DELTA {
+					kind = Counter
+				}
+				description := elem.DescriptionNLS
+				if description == "" {
+					description = elem.Description
+				}
+
+				for key, value := range elem.Values {
+					var labels map[string]string
+					objectType := PublishedObjectType(elem)
+
+					switch objectType {
+					case ObjectQMgr:
+						labels = c.QMgrLabels("")
+					case ObjectNHA:
+						labels = c.nhaLabels(key)
+					default:
+						ok := false
+						if labels, ok = c.queueLabels(key); !ok {
+							continue
+						}
+					}
+
+					b.add(Point{
+						Metric:      elem.MetricName,
+						ObjectType:  objectType,
+						Description: description,
+						Labels:      labels,
+						Value:       mqmetric.Normalise(elem, key, value),
+						Kind:        kind,
+						Source:      SourcePublication,
+					})
+				}
+			}
+		}
+	}
+}
+
+/*
+PublishedObjectType says which kind of object a published metric refers to,
+based on the topic it was published on.
+*/
+func PublishedObjectType(elem *mqmetric.MonElement) string {
+	objectType := ObjectQMgr
+	ot := elem.Parent.ObjectTopic
+	if strings.Contains(ot, "%s") {
+		if strings.Contains(ot, "/NHAREPLICA/") {
+			objectType = ObjectNHA
+		} else {
+			objectType = ObjectQueue
+		}
+	}
+	return objectType
+}
+
+// StatusObjectTypes returns the object types that are relevant for the platform
+// of the connected queue manager, along with the mqmetric OT_ value
+func (c *Collector) StatusObjectTypes() map[string]int {
+	m := make(map[string]int)
+	for _, st := range statusTypes {
+		if c.applies(st.objectType) {
+			m[st.objectType] = st.ot
+		}
+	}
+	return m
+}
+
+/*
+ObjectStatus gives the status attributes for one of the object types. The set
+belongs to the current connection, so it should not be held across a reconnect.
+*/
+func (c *Collector) ObjectStatus(ot int) *mqmetric.StatusSet {
+	c.lock()
+	defer c.unlock()
+	return c.src.objectStatus(ot)
+}
+
+/*
+PublishedMetrics gives the description of the metrics that the queue manager
+publishes. As with ObjectStatus, it belongs to the current connection.
+*/
+func (c *Collector) PublishedMetrics() *mqmetric.AllMetrics {
+	c.lock()
+	defer c.unlock()
+	return c.src.publishedMetrics()
+}
+
+// The BufferPool and PageSet stuff is only for z/OS, while AMQP and MQTT are Distributed only
+func (c *Collector) applies(objectType string) bool {
+	switch objectType {
+	case ObjectBufferPool, ObjectPageSet:
+		return c.platform == ibmmq.MQPL_ZOS
+	case ObjectAMQP, ObjectMQTT:
+		return c.platform != ibmmq.MQPL_ZOS
+	}
+	return true
+}
+
+// Several of the attributes are only used to build the labels that uniquely identify an
+// object. Those are the Pseudo attributes, and they are not reported as metrics themselves.
+func (c *Collector) addStatusPoints(b *Batch) {
+	for _, st := range statusTypes {
+		if !c.applies(st.objectType) {
+			continue
+		}
+
+		set := c.src.objectStatus(st.ot)
+		for _, attr := range set.Attributes {
+			if attr.Pseudo {
+				continue
+			}
+			kind := Gauge
+			if attr.Delta {
+				kind = Counter
+			}
+			for key, value := range attr.Values {
+				if !value.IsInt64 {
+					continue
+				}
+				labels, ok := st.labels(c, set, key)
+				if !ok {
+					continue
+				}
+				b.add(Point{
+					Metric:      attr.MetricName,
+					ObjectType:  st.objectType,
+					Description: attr.Description,
+					Labels:      labels,
+					Value:       st.normalise(attr, value.ValueInt64),
+					Kind:        kind,
+					Source:      SourceStatus,
+				})
+			}
+		}
+	}
+}
+
+func (c *Collector) queueStatusLabels(st *mqmetric.StatusSet, key string) (map[string]string, bool) {
+	return c.queueLabels(strAttr(st, mqmetric.ATTR_Q_NAME, key, key))
+}
+
+// Fill in the fields that are common to every point in the batch. Anything that has been
+// filtered out by name is dropped here, and the relabelling rules are applied, so that
+// every backend sees the same thing.
+func (b *Batch) add(p Point) {
+	if !b.filter.Allows(p.Name()) {
+		return
+	}
+	p.Timestamp = b.Timestamp
+	if p.Unit == UnitNone {
+		p.Unit = unitFor(p.Metric)
+	}
+	if len(b.relabel) > 0 && !b.applyRelabel(&p) {
+		return
+	}
+	b.Points = append(b.Points, p)
+}
+
+// The rules work on a copy of the labels, as the same map can be shared by several points
+func (b *Batch) applyRelabel(p *Point) bool {
+	name := p.Name()
+	labels := make(map[string]string, len(p.Labels)+1)
+	for k, v := range p.Labels {
+		labels[k] = v
+	}
+	labels[cf.RelabelNameLabel] = name
+
+	if !b.relabel.Apply(labels) {
+		return false
+	}
+
+	if newName := labels[cf.RelabelNameLabel]; newName != "" && newName != name {
+		p.rename(newName)
+	}
+	delete(labels, cf.RelabelNameLabel)
+	p.Labels = labels
+	return true
+}
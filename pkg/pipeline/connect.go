@@ -0,0 +1,258 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This file manages the queue manager connection for each Collector.
+
+The mqmetric package can hold several connections, each identified by a key. But the key
+for most of its operations is a package-level setting, and it is not safe to call from more
+than one goroutine at a time. So every use of mqmetric from here goes through lock(), which
+also selects the Collector's connection.
+
+Some of what mqmetric knows about the monitored objects - such as which queues are being
+tracked, and the channel descriptions - is held in package-level maps rather than with the
+connection. The vendored copy of mqmetric has a way to save and restore those maps, which
+lock() and unlock() use so that each Collector keeps its own. The subscriptions belong to
+the connection, so they are kept as they are when another Collector has had its turn.
+*/
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ibm-messaging/mq-golang/v5/ibmmq"
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	mqMutex  sync.Mutex
+	keyCount int
+)
+
+// Each Collector has its own key for the mqmetric connection
+func newKey(qMgrName string) string {
+	mqMutex.Lock()
+	defer mqMutex.Unlock()
+	keyCount++
+	return fmt.Sprintf("%d/%s", keyCount, qMgrName)
+}
+
+func (c *Collector) lock() {
+	mqMutex.Lock()
+	mqmetric.SetConnectionKey(c.key)
+	mqmetric.RestoreObjectMaps(c.objects)
+}
+
+func (c *Collector) unlock() {
+	c.objects = mqmetric.SaveObjectMaps()
+	mqMutex.Unlock()
+}
+
+/*
+Connect makes the connection to the queue manager, and then finds out what it can
+provide and subscribes to it. Anything left from an earlier connection is cleaned
+up first, so this is also how to reconnect. A warning from the connection is logged
+but does not stop things; any other error is returned, and the Collector is left
+disconnected. After a failure, ReconnectDue says when it is worth trying again.
+*/
+func (c *Collector) Connect() error {
+	if c.replay != nil {
+		return c.connectReplay()
+	}
+	if c.sim != nil {
+		return c.connectSimulate()
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	c.connected.Store(false)
+	mqmetric.EndConnection()
+
+	cc, tc, err := c.connectionConfig()
+	if err == nil {
+		err = mqmetric.InitConnectionKey(c.key, c.Config().QMgrName, c.Config().ReplyQ, c.Config().ReplyQ2, cc)
+	}
+	// The MQ client has read the generated TLS files by now
+	tc.Remove()
+	if err != nil {
+		if mqe, ok := err.(mqmetric.MQMetricError); ok && mqe.MQReturn.MQCC == ibmmq.MQCC_WARNING {
+			// Report the error but allow it to continue
+			log.Errorln(err)
+			err = nil
+		} else if ok && mqe.MQReturn.MQRC == ibmmq.MQRC_NOT_AUTHORIZED && c.Config().PasswordRef != "" {
+			// The saved password may be out of date, so get it again next time
+			cf.ForgetSecret(c.Config().PasswordRef)
+		}
+	}
+
+	// If we tried to connect to a default qmgr, or a wildcarded name via CCDT, then set the real name
+	// so it can be used in attribute tags
+	if err == nil {
+		if c.Config().QMgrName == "" || strings.HasPrefix(c.Config().QMgrName, "*") {
+			qmName := mqmetric.GetResolvedQMgrName()
+			log.Infoln("Resolving blank/default qmgr name to ", qmName)
+			c.updateConfig(func(cm *cf.Config) { cm.QMgrName = qmName })
+		}
+		log.Infoln("Connected to queue manager ", c.Config().QMgrName)
+		c.setPlatform()
+	}
+
+	// What metrics can the queue manager provide? Find out, and subscribe.
+	if err == nil {
+		InitAttributes()
+		mqmetric.SetLocale(c.Config().Locale)
+		err = c.discover()
+	}
+
+	if err == nil {
+		var compCode int32
+		compCode, err = mqmetric.VerifyConfig()
+		// We could choose to fail after a warning, but instead will continue for now
+		if compCode == ibmmq.MQCC_WARNING {
+			log.Println(err)
+			err = nil
+		}
+	}
+
+	if err != nil {
+		mqmetric.EndConnection()
+	}
+	c.connectDone(err)
+	return err
+}
+
+// The connection settings to use for this attempt to connect. When TLS is configured, the
+// returned TLSConnection must be removed once the connect call has been made.
+func (c *Collector) connectionConfig() (*mqmetric.ConnectionConfig, *cf.TLSConnection, error) {
+	var err error
+	var tc *cf.TLSConnection
+
+	// Neither the password nor the generated CCDT is kept in the configuration. It's shared
+	// with the other goroutines, and the CCDT would look like a change when the configuration
+	// is reloaded.
+	cm := c.Config()
+	cc := cm.CC
+
+	// A password that's kept elsewhere is looked up each time, in case it has been changed
+	if cm.PasswordRef != "" {
+		cc.Password, err = cf.ResolveSecret(cm.PasswordRef, cm.SecretTTLDuration)
+		if err != nil {
+			err = fmt.Errorf("Cannot get password for %s: %v", cm.QMgrName, err)
+		}
+	}
+
+	// The TLS settings are given to the MQ client through a generated CCDT
+	if err == nil && cm.TLS.CipherSpec != "" && cc.ConnName != "" {
+		tc, err = cf.PrepareTLS(cm)
+		if err == nil {
+			cc.CcdtUrl = tc.CcdtUrl
+		}
+	}
+	return &cc, tc, err
+}
+
+// Update the connection state after an attempt to connect
+func (c *Collector) connectDone(err error) {
+	if err == nil {
+		if c.connectedOnce.Load() {
+			c.stats.reconnected()
+		}
+		c.connected.Store(true)
+		c.connectedOnce.Store(true)
+		c.reconnectDelay = 0
+		c.statusErrors = 0
+	} else {
+		c.backoff()
+	}
+}
+
+// Each failure to connect doubles the time until the next attempt, up to the configured maximum
+func (c *Collector) backoff() {
+	if c.reconnectDelay == 0 {
+		c.reconnectDelay = c.Config().ReconnectIntervalDuration
+	} else {
+		c.reconnectDelay *= 2
+	}
+	if c.reconnectDelay > c.Config().ReconnectMaxIntervalDuration {
+		c.reconnectDelay = c.Config().ReconnectMaxIntervalDuration
+	}
+	c.nextConnect = time.Now().Add(c.reconnectDelay)
+	log.Debugf("Next connection attempt to %s in %v", c.QMgrName(), c.reconnectDelay)
+}
+
+// ReconnectDue says whether enough time has passed since the last failed connection attempt
+func (c *Collector) ReconnectDue() bool {
+	return !time.Now().Before(c.nextConnect)
+}
+
+// Disconnect ends the connection. Connect can be called again to reestablish it.
+func (c *Collector) Disconnect() {
+	if c.replay != nil {
+		c.connected.Store(false)
+		c.replay.close()
+		return
+	}
+	if c.sim != nil {
+		c.connected.Store(false)
+		return
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	c.connected.Store(false)
+	mqmetric.EndConnection()
+}
+
+// Connected says whether the Collector currently has a usable connection. It is safe to
+// call from any goroutine.
+func (c *Collector) Connected() bool {
+	return c.connected.Load()
+}
+
+// ConnectedOnce says whether there has ever been a successful connection, so the
+// platform and the set of labels are known.
+func (c *Collector) ConnectedOnce() bool {
+	return c.connectedOnce.Load()
+}
+
+/*
+The platform and the availability of the hostname label are stashed from the first
+connection so they don't get reset if we're in a reconnect sequence, and the set of
+labels stays the same.
+*/
+func (c *Collector) setPlatform() {
+	if c.platformString != "" {
+		return
+	}
+	platform := mqmetric.GetPlatform()
+	c.setPlatformInfo(platform, platform != ibmmq.MQPL_ZOS && mqmetric.GetCommandLevel() >= ibmmq.MQCMDL_LEVEL_932)
+}
+
+func (c *Collector) setPlatformInfo(platform int32, supportsHostname bool) {
+	c.platform = platform
+	c.supportsHostname = supportsHostname
+	c.platformString = strings.Replace(ibmmq.MQItoString("PL", int(c.platform)), "MQPL_", "", -1)
+}
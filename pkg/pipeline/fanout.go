@@ -0,0 +1,201 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This file lets a Group of Collectors, with one connection and one set of subscriptions for
+each queue manager, feed several Sinks. Each Sink can have its own interval. Collection is
+done at the shortest of those intervals, and the points are held for each output until
+it is next due to be written.
+
+While points are being held, Counters are added together so that nothing is lost, and Gauges
+keep only their most recent value. Points are held separately for each queue manager, and
+each one is written to the Sink as its own Batch.
+*/
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultInterval is used when none of the outputs has said how often it wants data
+const DefaultInterval = 10 * time.Second
+
+/*
+Output is one of the destinations for a Fanout. An Interval of 0 means that the
+Sink is written on every collection.
+*/
+type Output struct {
+	Sink     Sink
+	Interval time.Duration
+
+	lastWrite time.Time
+	qmgrs     []string
+	pending   map[string]*pendingBatch
+}
+
+// The points held for one queue manager
+type pendingBatch struct {
+	b      Batch
+	keys   []string
+	points map[string]*Point
+}
+
+/*
+Fanout drives a Group and passes the results to all of the configured outputs.
+*/
+type Fanout struct {
+	g        *Group
+	outputs  []*Output
+	interval time.Duration
+}
+
+// NewOutput creates an output for the Sink that is written at the given interval
+func NewOutput(s Sink, interval time.Duration) *Output {
+	return &Output{
+		Sink:      s,
+		Interval:  interval,
+		lastWrite: time.Now(),
+		pending:   make(map[string]*pendingBatch),
+	}
+}
+
+// NewFanout works out how often collection needs to happen for the outputs
+func NewFanout(g *Group, outputs []*Output) *Fanout {
+	f := &Fanout{g: g, outputs: outputs}
+	for _, o := range outputs {
+		if o.Interval > 0 && (f.interval == 0 || o.Interval < f.interval) {
+			f.interval = o.Interval
+		}
+	}
+	if f.interval == 0 {
+		f.interval = DefaultInterval
+	}
+	return f
+}
+
+// Interval says how long the caller should wait between calls to Collect
+func (f *Fanout) Interval() time.Duration {
+	return f.interval
+}
+
+/*
+Collect does one collection cycle, and writes to any outputs that are due. A failure to write
+to one output is logged, but it does not stop the others from getting their data. The error
+is the same as from Group.Collect.
+*/
+func (f *Fanout) Collect() error {
+	batches, err := f.g.Collect()
+
+	now := time.Now()
+	for _, o := range f.outputs {
+		for _, b := range batches {
+			o.add(b)
+		}
+		// Allow a little leeway so that an output with the same interval as the collection
+		// is not pushed back by a whole cycle when a sleep finishes slightly early
+		if o.Interval == 0 || now.Sub(o.lastWrite) >= o.Interval-(f.interval/10) {
+			if werr := o.flush(f.g, now); werr != nil {
+				log.Errorf("Error writing to %s output: %v", o.Sink.Name(), werr)
+			}
+		}
+	}
+	return err
+}
+
+// Merge the points from this batch into the ones waiting to be written
+func (o *Output) add(b *Batch) {
+	pb, ok := o.pending[b.QMgr]
+	if !ok {
+		pb = &pendingBatch{points: make(map[string]*Point)}
+		o.pending[b.QMgr] = pb
+		o.qmgrs = append(o.qmgrs, b.QMgr)
+	}
+
+	statusPolled := pb.b.StatusPolled || b.StatusPolled
+	pb.b = Batch{
+		Timestamp:    b.Timestamp,
+		QMgr:         b.QMgr,
+		Platform:     b.Platform,
+		StatusPolled: statusPolled,
+	}
+
+	for i := range b.Points {
+		p := b.Points[i]
+		k := pointKey(&p)
+		if prev, ok := pb.points[k]; ok {
+			if p.Kind == Counter {
+				p.Value += prev.Value
+			}
+			*prev = p
+		} else {
+			pb.points[k] = &p
+			pb.keys = append(pb.keys, k)
+		}
+	}
+}
+
+// Write out everything that has been held, in the order it was first seen, and reset.
+// All of the queue managers are tried, and the last error is returned. The Group records
+// the outcome of each write.
+func (o *Output) flush(g *Group, now time.Time) error {
+	var err error
+
+	o.lastWrite = now
+	for _, qmgr := range o.qmgrs {
+		pb := o.pending[qmgr]
+		if len(pb.keys) == 0 {
+			continue
+		}
+
+		out := pb.b
+		out.Points = make([]Point, 0, len(pb.keys))
+		for _, k := range pb.keys {
+			out.Points = append(out.Points, *pb.points[k])
+		}
+
+		log.Debugf("Writing %d points for %s to %s output", len(out.Points), qmgr, o.Sink.Name())
+		if werr := g.Write(o.Sink, &out); werr != nil {
+			err = werr
+		}
+	}
+
+	o.qmgrs = nil
+	o.pending = make(map[string]*pendingBatch)
+	return err
+}
+
+// Build a string that uniquely identifies a series
+func pointKey(p *Point) string {
+	keys := make([]string, 0, len(p.Labels))
+	for k := range p.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(p.Name())
+	for _, k := range keys {
+		sb.WriteString("/" + k + "=" + p.Labels[k])
+	}
+	return sb.String()
+}
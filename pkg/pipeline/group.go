@@ -0,0 +1,242 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+A Group is the set of Collectors, one for each queue manager, that a program is
+monitoring. The collectors that push data on a timer all drive their queue managers
+through a Group, so that each connection is made, and remade, independently of the others.
+
+When a connection fails, and the configuration says to keep running, the Group tries to
+reconnect on later cycles. The wait between attempts starts at the reconnectInterval, and
+doubles after each failure up to the reconnectMaxInterval. While a queue manager is not
+connected, its Batch just has a status point that says so, and the backends carry on
+getting data from any other queue managers.
+*/
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ibm-messaging/mq-golang/v5/ibmmq"
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Allow (but still report) a small number of consecutive failures in collecting status
+// before deciding that the connection is no longer usable
+const maxStatusErrors = 3
+
+type Group struct {
+	Collectors []*Collector
+
+	mutex     sync.Mutex
+	lastCycle time.Time
+}
+
+// NewGroup creates a Collector for each of the configurations. If we're going to manage
+// reconnection, then the MQ client automatic option is turned off.
+func NewGroup(cms []*cf.Config) *Group {
+	g := &Group{}
+	for _, cm := range cms {
+		cm.CC.SingleConnect = cm.KeepRunning
+		g.Collectors = append(g.Collectors, NewCollector(cm))
+	}
+	return g
+}
+
+/*
+Connect makes the first connection to each queue manager. If one of several queue managers
+cannot be reached, that is logged and it is tried again during Collect. But if none of them
+can be connected, the first error is returned as it probably means a configuration problem.
+The same happens if we've been told not to keep running after a failure.
+*/
+func (g *Group) Connect() error {
+	var firstErr error
+
+	connected := 0
+	for _, c := range g.Collectors {
+		if err := c.Connect(); err != nil {
+			log.Errorf("Connection to %s has failed: %v", c.QMgrName(), err)
+			if firstErr == nil || !c.Config().KeepRunning {
+				firstErr = err
+			}
+			if !c.Config().KeepRunning {
+				return firstErr
+			}
+		} else {
+			connected++
+		}
+	}
+
+	if connected == 0 {
+		return firstErr
+	}
+	return nil
+}
+
+// Disconnect ends all of the connections
+func (g *Group) Disconnect() {
+	for _, c := range g.Collectors {
+		c.Disconnect()
+	}
+}
+
+/*
+Reconnect tries again for each queue manager that is not connected, once the wait after its
+previous failure has passed. It returns how many have been connected.
+*/
+func (g *Group) Reconnect() int {
+	connected := 0
+	for _, c := range g.Collectors {
+		if c.Connected() || !c.ReconnectDue() || c.ReplayFinished() {
+			continue
+		}
+		if err := c.Connect(); err != nil {
+			log.Errorf("Reconnection to %s has failed: %v", c.QMgrName(), err)
+		} else {
+			log.Infof("Reconnected to %s", c.QMgrName())
+			connected++
+		}
+	}
+	return connected
+}
+
+/*
+Collect does a collection cycle for each queue manager, first trying to reconnect any that
+are not currently connected. A queue manager whose publications cannot be processed, or
+which keeps failing to return status, is disconnected so that it does not stop the others
+being reported. An error is only returned when a connection has been lost and we've been
+told not to keep running; the caller should then give up.
+*/
+func (g *Group) Collect() ([]*Batch, error) {
+	var batches []*Batch
+
+	g.mutex.Lock()
+	g.lastCycle = time.Now()
+	g.mutex.Unlock()
+
+	g.Reconnect()
+
+	for _, c := range g.Collectors {
+		if c.Connected() {
+			b, err := c.Collect()
+			if b == nil {
+				if err != ErrReplayFinished {
+					log.Errorf("Error processing publications from %s: %v", c.QMgrName(), err)
+				}
+				err = g.lost(c, err)
+			} else {
+				batches = append(batches, b)
+				err = g.checkStatus(c, err)
+			}
+			if err != nil {
+				return batches, err
+			}
+		}
+
+		if !c.Connected() && c.ConnectedOnce() {
+			batches = append(batches, c.DisconnectedBatch())
+		}
+	}
+
+	if g.ReplayFinished() {
+		return batches, ErrReplayFinished
+	}
+	return batches, nil
+}
+
+// ReplayFinished says whether every queue manager has come to the end of its recording
+func (g *Group) ReplayFinished() bool {
+	for _, c := range g.Collectors {
+		if !c.ReplayFinished() {
+			return false
+		}
+	}
+	return len(g.Collectors) > 0
+}
+
+// LastCycle says when Collect was last called, so that a stuck collection loop can be noticed
+func (g *Group) LastCycle() time.Time {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.lastCycle
+}
+
+/*
+Write sends a Batch to a Sink, recording how long it took and whether it worked in the
+statistics for the Batch's queue manager.
+*/
+func (g *Group) Write(s Sink, b *Batch) error {
+	start := time.Now()
+	err := s.Write(b)
+	for _, c := range g.Collectors {
+		if c.QMgrName() == b.QMgr {
+			c.RecordWrite(s.Name(), time.Since(start), err)
+			break
+		}
+	}
+	return err
+}
+
+// Count the consecutive failures to collect status. When there are too many, the connection is dropped.
+func (g *Group) checkStatus(c *Collector, err error) error {
+	if g.StatusFailing(c, err) {
+		return g.lost(c, err)
+	}
+	return nil
+}
+
+/*
+StatusFailing counts the consecutive failures to collect status from a queue manager, as
+returned along with a Batch by Collect. A nil error starts the count again. It says whether
+there have now been too many, when the connection ought to be dropped. Until then, the Batch
+is still worth using.
+*/
+func (g *Group) StatusFailing(c *Collector, err error) bool {
+	if err == nil {
+		c.statusErrors = 0
+		return false
+	}
+
+	c.statusErrors++
+	if c.statusErrors <= maxStatusErrors {
+		log.Errorf("Error collecting status from %s: %v. Continuing for now.", c.QMgrName(), err)
+		return false
+	}
+
+	if mqe, ok := err.(mqmetric.MQMetricError); ok {
+		if mqe.MQReturn.MQRC == ibmmq.MQRC_NO_MSG_AVAILABLE {
+			log.Errorf("  Not all responses received in time. Perhaps queue manager is running slowly.")
+		}
+	}
+	log.Errorf("Error collecting status from %s: %v. Maximum permitted failures reached.", c.QMgrName(), err)
+	return true
+}
+
+// Drop a connection that is no longer usable. The error is passed back if we're not going to reconnect.
+func (g *Group) lost(c *Collector, err error) error {
+	log.Errorf("Disconnecting from %s", c.QMgrName())
+	c.Disconnect()
+	if !c.Config().KeepRunning {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package pipeline_test
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"errors"
+	"testing"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+)
+
+// A few failures to get status are tolerated, as long as they're not all in a row
+func TestStatusFailing(t *testing.T) {
+	g := pipeline.NewGroup([]*cf.Config{{QMgrName: "QM1", Simulate: true}})
+	c := g.Collectors[0]
+	failed := errors.New("no status")
+
+	for i, err := range []error{failed, failed, failed, nil, failed, failed, failed} {
+		if g.StatusFailing(c, err) {
+			t.Fatalf("Step %d said the status was failing", i)
+		}
+	}
+	if !g.StatusFailing(c, failed) {
+		t.Errorf("Too many failures in a row were not reported")
+	}
+}
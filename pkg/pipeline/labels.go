@@ -0,0 +1,250 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+The labels (tags) for each object type are built here, so that every backend
+reports the same set. Queues and channels can also have labels taken from their
+own attributes. They can then be changed by the relabelling rules as the points
+are added to a Batch.
+*/
+
+import (
+	"strings"
+
+	"github.com/ibm-messaging/mq-golang/v5/ibmmq"
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+)
+
+/*
+SupportsHostnameLabel says whether we're connected to a qmgr that has the hostname status
+attribute. The answer comes from the first connection, so it doesn't get reset if we're in a
+reconnect sequence, and the set of qmgr labels stays the same.
+*/
+func (c *Collector) SupportsHostnameLabel() bool {
+	return c.supportsHostname
+}
+
+func (c *Collector) newLabels() map[string]string {
+	return map[string]string{
+		"qmgr":     c.QMgrName(),
+		"platform": c.Platform(),
+	}
+}
+
+func (c *Collector) addMetaLabels(labels map[string]string) map[string]string {
+	cm := c.Config()
+	for i := 0; i < len(cm.MetadataTagsArray); i++ {
+		labels[cm.MetadataTagsArray[i]] = cm.MetadataValuesArray[i]
+	}
+	return labels
+}
+
+/*
+QMgrLabels returns the labels for queue manager-level points. The hostname can be
+overridden, for example when reporting that the queue manager is not available. The
+description and hostname are the ones saved from the most recent status poll.
+*/
+func (c *Collector) QMgrLabels(hostname string) map[string]string {
+	labels := c.newLabels()
+	labels["description"] = orDummy(c.qmgrDescription)
+	if c.SupportsHostnameLabel() {
+		if hostname == "" {
+			hostname = orDummy(c.hostname)
+		}
+		labels["hostname"] = hostname
+	}
+	return c.addMetaLabels(labels)
+}
+
+// Labels need a non-empty value
+func orDummy(s string) string {
+	if s == "" {
+		return mqmetric.DUMMY_STRING
+	}
+	return s
+}
+
+func (c *Collector) nhaLabels(key string) map[string]string {
+	labels := c.newLabels()
+	labels["nha"] = strings.Replace(key, mqmetric.NativeHAKeyPrefix, "", -1)
+	return c.addMetaLabels(labels)
+}
+
+// Returns false if the queue has not yet had a full attribute discovery. Typically
+// that's the first collection period after a rediscover/resubscribe, and we don't
+// report anything for the queue until it has.
+func (c *Collector) queueLabels(qName string) (map[string]string, bool) {
+	usage := c.usageString(qName)
+	if usage == "" {
+		return nil, false
+	}
+	labels := c.newLabels()
+	labels["queue"] = qName
+	labels["usage"] = usage
+	labels["description"] = c.src.objectDescription(qName, ibmmq.MQOT_Q)
+	labels["cluster"] = c.src.queueAttribute(qName, ibmmq.MQCA_CLUSTER_NAME)
+	c.addMetaLabels(labels)
+	c.Config().ObjectLabels.Add(ObjectQueue, labels, func(attr string) string {
+		return c.queueObjectAttribute(qName, attr)
+	})
+	return labels, true
+}
+
+// The attributes that can be used in the objectLabels for a queue
+func (c *Collector) queueObjectAttribute(qName string, attr string) string {
+	switch attr {
+	case cf.ObjectAttrDescr:
+		return c.src.objectDescription(qName, ibmmq.MQOT_Q)
+	case cf.ObjectAttrCustom:
+		return c.src.queueAttribute(qName, ibmmq.MQCA_CUSTOM)
+	case cf.ObjectAttrCluster:
+		return c.src.queueAttribute(qName, ibmmq.MQCA_CLUSTER_NAME)
+	case cf.ObjectAttrUsage:
+		return c.usageString(qName)
+	case cf.ObjectAttrDefType:
+		return c.src.queueAttribute(qName, ibmmq.MQIA_DEFINITION_TYPE)
+	case cf.ObjectAttrDefPsist:
+		return c.src.queueAttribute(qName, ibmmq.MQIA_DEF_PERSISTENCE)
+	}
+	return ""
+}
+
+func (c *Collector) usageString(qName string) string {
+	usage := ""
+	if usageAttr, ok := c.src.objectStatus(mqmetric.OT_Q).Attributes[mqmetric.ATTR_Q_USAGE]; ok {
+		if v, ok := usageAttr.Values[qName]; ok {
+			if v.ValueInt64 == int64(ibmmq.MQUS_TRANSMISSION) {
+				usage = "XMITQ"
+			} else {
+				usage = "NORMAL"
+			}
+		}
+	}
+	return usage
+}
+
+func (c *Collector) channelLabels(st *mqmetric.StatusSet, key string) (map[string]string, bool) {
+	chlType := int(intAttr(st, mqmetric.ATTR_CHL_TYPE, key, 0))
+	chlTypeString := strings.Replace(ibmmq.MQItoString("CHT", chlType), "MQCHT_", "", -1)
+	chlName := strAttr(st, mqmetric.ATTR_CHL_NAME, key, "")
+
+	labels := c.newLabels()
+	labels["channel"] = chlName
+	labels["description"] = c.src.objectDescription(chlName, ibmmq.MQOT_CHANNEL)
+	labels[mqmetric.ATTR_CHL_TYPE] = strings.TrimSpace(chlTypeString)
+	// Not every channel status report has the RQMNAME attribute (eg SVRCONNs)
+	labels[mqmetric.ATTR_CHL_RQMNAME] = strings.TrimSpace(strAttr(st, mqmetric.ATTR_CHL_RQMNAME, key, mqmetric.DUMMY_STRING))
+	labels[mqmetric.ATTR_CHL_CONNNAME] = strings.TrimSpace(strAttr(st, mqmetric.ATTR_CHL_CONNNAME, key, ""))
+	labels[mqmetric.ATTR_CHL_JOBNAME] = strings.TrimSpace(strAttr(st, mqmetric.ATTR_CHL_JOBNAME, key, ""))
+	labels[mqmetric.ATTR_CHL_SSLCIPH] = strings.TrimSpace(strAttr(st, mqmetric.ATTR_CHL_SSLCIPH, key, mqmetric.DUMMY_STRING))
+	c.addMetaLabels(labels)
+	c.Config().ObjectLabels.Add(ObjectChannel, labels, func(attr string) string {
+		return c.src.objectDescription(chlName, ibmmq.MQOT_CHANNEL)
+	})
+	return labels, true
+}
+
+func (c *Collector) topicLabels(st *mqmetric.StatusSet, key string) (map[string]string, bool) {
+	labels := c.newLabels()
+	labels["topic"] = strAttr(st, mqmetric.ATTR_TOPIC_STRING, key, "")
+	labels["type"] = strAttr(st, mqmetric.ATTR_TOPIC_STATUS_TYPE, key, "")
+	return c.addMetaLabels(labels), true
+}
+
+func (c *Collector) subLabels(st *mqmetric.StatusSet, key string) (map[string]string, bool) {
+	subType := int(intAttr(st, mqmetric.ATTR_SUB_TYPE, key, 0))
+	labels := c.newLabels()
+	labels["subid"] = strAttr(st, mqmetric.ATTR_SUB_ID, key, "")
+	labels["subscription"] = strAttr(st, mqmetric.ATTR_SUB_NAME, key, "")
+	labels["type"] = strings.Replace(ibmmq.MQItoString("SUBTYPE", subType), "MQSUBTYPE_", "", -1)
+	labels["topic"] = strAttr(st, mqmetric.ATTR_SUB_TOPIC_STRING, key, "")
+	return c.addMetaLabels(labels), true
+}
+
+func (c *Collector) qmgrStatusLabels(st *mqmetric.StatusSet, key string) (map[string]string, bool) {
+	return c.QMgrLabels(""), true
+}
+
+func (c *Collector) clusterLabels(st *mqmetric.StatusSet, key string) (map[string]string, bool) {
+	qmTypeString := "PARTIAL"
+	if intAttr(st, mqmetric.ATTR_CLUSTER_QMTYPE, key, int64(ibmmq.MQQMT_NORMAL)) == int64(ibmmq.MQQMT_REPOSITORY) {
+		qmTypeString = "FULL"
+	}
+	labels := c.newLabels()
+	labels["cluster"] = strAttr(st, mqmetric.ATTR_CLUSTER_NAME, key, "")
+	labels["qmtype"] = qmTypeString
+	return c.addMetaLabels(labels), true
+}
+
+func (c *Collector) bpLabels(st *mqmetric.StatusSet, key string) (map[string]string, bool) {
+	labels := c.newLabels()
+	labels["bufferpool"] = strAttr(st, mqmetric.ATTR_BP_ID, key, "")
+	labels["location"] = strAttr(st, mqmetric.ATTR_BP_LOCATION, key, "")
+	labels["pageclass"] = strAttr(st, mqmetric.ATTR_BP_CLASS, key, "")
+	return c.addMetaLabels(labels), true
+}
+
+func (c *Collector) psLabels(st *mqmetric.StatusSet, key string) (map[string]string, bool) {
+	labels := c.newLabels()
+	labels["pageset"] = strAttr(st, mqmetric.ATTR_PS_ID, key, "")
+	labels["bufferpool"] = strAttr(st, mqmetric.ATTR_PS_BPID, key, "")
+	return c.addMetaLabels(labels), true
+}
+
+func (c *Collector) amqpLabels(st *mqmetric.StatusSet, key string) (map[string]string, bool) {
+	chlName := strAttr(st, mqmetric.ATTR_CHL_NAME, key, "")
+	labels := c.newLabels()
+	labels["channel"] = chlName
+	labels["description"] = c.src.objectDescription(chlName, mqmetric.OT_CHANNEL_AMQP)
+	labels[mqmetric.ATTR_CHL_AMQP_CLIENT_ID] = strAttr(st, mqmetric.ATTR_CHL_AMQP_CLIENT_ID, key, "")
+	labels[mqmetric.ATTR_CHL_CONNNAME] = strings.TrimSpace(strAttr(st, mqmetric.ATTR_CHL_CONNNAME, key, ""))
+	return c.addMetaLabels(labels), true
+}
+
+func (c *Collector) mqttLabels(st *mqmetric.StatusSet, key string) (map[string]string, bool) {
+	chlName := strAttr(st, mqmetric.ATTR_CHL_NAME, key, "")
+	labels := c.newLabels()
+	labels["channel"] = chlName
+	labels["description"] = c.src.objectDescription(chlName, mqmetric.OT_CHANNEL_MQTT)
+	labels[mqmetric.ATTR_CHL_MQTT_CLIENT_ID] = strAttr(st, mqmetric.ATTR_CHL_MQTT_CLIENT_ID, key, "")
+	labels[mqmetric.ATTR_CHL_CONNNAME] = strings.TrimSpace(strAttr(st, mqmetric.ATTR_CHL_CONNNAME, key, ""))
+	return c.addMetaLabels(labels), true
+}
+
+// Safe ways to extract the values of the attributes that are used as labels. Not every
+// status response includes every attribute.
+func strAttr(st *mqmetric.StatusSet, attr string, key string, def string) string {
+	if a, ok := st.Attributes[attr]; ok {
+		if v, ok := a.Values[key]; ok {
+			return v.ValueString
+		}
+	}
+	return def
+}
+
+func intAttr(st *mqmetric.StatusSet, attr string, key string, def int64) int64 {
+	if a, ok := st.Attributes[attr]; ok {
+		if v, ok := a.Values[key]; ok {
+			return v.ValueInt64
+		}
+	}
+	return def
+}
@@ -0,0 +1,280 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+The objectLabels configuration can use queue attributes that mqmetric does not keep - the
+CUSTOM attribute and the default persistence. Those are inquired here, but only if they are
+actually used. The mqmetric package doesn't share its connection handle, so we make a
+separate short-lived connection with the same settings, and send an INQUIRE_Q command for
+each of the monitored queue patterns. The replies come back to a dynamic queue made from
+the replyQueue model. That's the same as the collector does for its other commands, so it
+doesn't need any more authority than the collector already has; in particular, there's no
+need for inquire authority on each queue.
+
+This is done after each discovery, and then at the rediscoverInterval, with the values held
+until the next time. When the attributes can't be inquired, the queues just don't get those
+labels.
+*/
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ibm-messaging/mq-golang/v5/ibmmq"
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// The queue attributes that we find out for ourselves, saved by lookupKey
+type inquiredAttributes struct {
+	values map[string]string
+}
+
+func (a *inquiredAttributes) get(name string, attr int32) string {
+	if v, ok := a.values[lookupKey(attr, name)]; ok && v != "" {
+		return v
+	}
+	return mqmetric.DUMMY_STRING
+}
+
+// Which of the extra attributes are needed for the object labels
+func (c *Collector) inquirySelectors() []int32 {
+	var selectors []int32
+	if c.Config().ObjectLabels.Uses(ObjectQueue, cf.ObjectAttrCustom) {
+		selectors = append(selectors, ibmmq.MQCA_CUSTOM)
+	}
+	if c.Config().ObjectLabels.Uses(ObjectQueue, cf.ObjectAttrDefPsist) {
+		selectors = append(selectors, ibmmq.MQIA_DEF_PERSISTENCE)
+	}
+	return selectors
+}
+
+/*
+inquireQueueAttributes refreshes the saved attributes for the queues that mqmetric has
+discovered. It's called with the lock held, so mqmetric's queue list is ours. After a
+failure, the previous values are kept.
+*/
+func (c *Collector) inquireQueueAttributes() {
+	selectors := c.inquirySelectors()
+	if len(selectors) == 0 {
+		return
+	}
+
+	qMgr, err := c.connectForInquiry()
+	if err != nil {
+		log.Warnf("Cannot connect to %s to inquire queue attributes: %v", c.QMgrName(), err)
+		return
+	}
+	defer qMgr.Disc()
+
+	discovered := make(map[string]bool)
+	for _, qName := range mqmetric.GetDiscoveredQueues() {
+		discovered[qName] = true
+	}
+
+	values := make(map[string]string)
+	cmdQ, replyQ, err := c.openCommandQueues(qMgr)
+	if err == nil {
+		defer cmdQ.Close(0)
+		defer replyQ.Close(0)
+
+		// Exclusions are dealt with by only keeping the discovered queues
+		for _, pattern := range strings.Split(c.Config().MonitoredQueues, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" || strings.HasPrefix(pattern, "!") {
+				continue
+			}
+			err = c.inquireQueues(cmdQ, replyQ, pattern, selectors, func(qName string, attr int32, val string) {
+				if discovered[qName] {
+					values[lookupKey(attr, qName)] = val
+				}
+			})
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		log.Warnf("Cannot inquire attributes of the queues on %s for the object labels: %v", c.QMgrName(), err)
+		return
+	}
+	c.inquired.values = values
+}
+
+// Open the command queue, and a dynamic queue for the replies
+func (c *Collector) openCommandQueues(qMgr ibmmq.MQQueueManager) (ibmmq.MQObject, ibmmq.MQObject, error) {
+	var replyQ ibmmq.MQObject
+
+	od := ibmmq.NewMQOD()
+	od.ObjectType = ibmmq.MQOT_Q
+	od.ObjectName = "SYSTEM.ADMIN.COMMAND.QUEUE"
+	if c.platform == ibmmq.MQPL_ZOS {
+		od.ObjectName = "SYSTEM.COMMAND.INPUT"
+	}
+	cmdQ, err := qMgr.Open(od, ibmmq.MQOO_OUTPUT|ibmmq.MQOO_FAIL_IF_QUIESCING)
+	if err != nil {
+		return cmdQ, replyQ, fmt.Errorf("Cannot open queue %s: %v", od.ObjectName, err)
+	}
+
+	od = ibmmq.NewMQOD()
+	od.ObjectType = ibmmq.MQOT_Q
+	od.ObjectName = c.Config().ReplyQ
+	replyQ, err = qMgr.Open(od, ibmmq.MQOO_INPUT_EXCLUSIVE|ibmmq.MQOO_FAIL_IF_QUIESCING)
+	if err != nil {
+		cmdQ.Close(0)
+		return cmdQ, replyQ, fmt.Errorf("Cannot open queue %s: %v", od.ObjectName, err)
+	}
+	return cmdQ, replyQ, nil
+}
+
+// Send an INQUIRE_Q for the local queues matching the pattern, and give each of the requested
+// attributes in the replies to the save function
+func (c *Collector) inquireQueues(cmdQ ibmmq.MQObject, replyQ ibmmq.MQObject, pattern string, selectors []int32, save func(string, int32, string)) error {
+	putmqmd := ibmmq.NewMQMD()
+	pmo := ibmmq.NewMQPMO()
+	pmo.Options = ibmmq.MQPMO_NO_SYNCPOINT | ibmmq.MQPMO_NEW_MSG_ID | ibmmq.MQPMO_NEW_CORREL_ID | ibmmq.MQPMO_FAIL_IF_QUIESCING
+	putmqmd.Format = "MQADMIN"
+	putmqmd.ReplyToQ = replyQ.Name
+	putmqmd.MsgType = ibmmq.MQMT_REQUEST
+	putmqmd.Report = ibmmq.MQRO_PASS_DISCARD_AND_EXPIRY
+
+	cfh := ibmmq.NewMQCFH()
+	cfh.Version = ibmmq.MQCFH_VERSION_3
+	cfh.Type = ibmmq.MQCFT_COMMAND_XR
+	cfh.Command = ibmmq.MQCMD_INQUIRE_Q
+
+	attrs := make([]int64, len(selectors))
+	for i, s := range selectors {
+		attrs[i] = int64(s)
+	}
+	parms := []*ibmmq.PCFParameter{
+		{Type: ibmmq.MQCFT_STRING, Parameter: ibmmq.MQCA_Q_NAME, String: []string{pattern}},
+		{Type: ibmmq.MQCFT_INTEGER, Parameter: ibmmq.MQIA_Q_TYPE, Int64Value: []int64{int64(ibmmq.MQQT_LOCAL)}},
+		{Type: ibmmq.MQCFT_INTEGER_LIST, Parameter: ibmmq.MQIACF_Q_ATTRS, Int64Value: attrs},
+	}
+	// Shared queues are only included when they are asked for
+	if c.platform == ibmmq.MQPL_ZOS {
+		parms = append(parms, &ibmmq.PCFParameter{Type: ibmmq.MQCFT_INTEGER, Parameter: ibmmq.MQIA_QSG_DISP, Int64Value: []int64{int64(ibmmq.MQQSGD_ALL)}})
+	}
+
+	var buf []byte
+	for _, p := range parms {
+		buf = append(buf, p.Bytes()...)
+	}
+	cfh.ParameterCount = int32(len(parms))
+	buf = append(cfh.Bytes(), buf...)
+
+	err := cmdQ.Put(putmqmd, pmo, buf)
+	if err != nil {
+		return err
+	}
+
+	// There's one reply for each queue, and the last one is marked
+	replyBuf := make([]byte, 10240)
+	for done := false; !done; {
+		getmqmd := ibmmq.NewMQMD()
+		gmo := ibmmq.NewMQGMO()
+		gmo.Options = ibmmq.MQGMO_NO_SYNCPOINT | ibmmq.MQGMO_FAIL_IF_QUIESCING | ibmmq.MQGMO_WAIT | ibmmq.MQGMO_CONVERT
+		gmo.WaitInterval = 3 * 1000
+		gmo.Version = ibmmq.MQGMO_VERSION_2
+		gmo.MatchOptions = ibmmq.MQMO_MATCH_CORREL_ID
+		getmqmd.CorrelId = putmqmd.MsgId
+
+		datalen, err := replyQ.Get(getmqmd, gmo, replyBuf)
+		if err != nil {
+			return err
+		}
+
+		rcfh, offset := ibmmq.ReadPCFHeader(replyBuf)
+		done = rcfh.Control == ibmmq.MQCFC_LAST
+		if rcfh.CompCode != ibmmq.MQCC_OK {
+			// A pattern that matches nothing is not a problem
+			if rcfh.Reason == ibmmq.MQRC_UNKNOWN_OBJECT_NAME {
+				continue
+			}
+			return fmt.Errorf("INQUIRE_Q failed with CC %d RC %d", rcfh.CompCode, rcfh.Reason)
+		}
+		// Returned by z/OS queue managers but are not interesting
+		if rcfh.Type == ibmmq.MQCFT_XR_SUMMARY || rcfh.Type == ibmmq.MQCFT_XR_MSG {
+			continue
+		}
+
+		qName := ""
+		v := make(map[int32]string)
+		for offset < datalen {
+			elem, bytesRead := ibmmq.ReadPCFParameter(replyBuf[offset:datalen])
+			offset += bytesRead
+			switch elem.Parameter {
+			case ibmmq.MQCA_Q_NAME:
+				qName = strings.TrimSpace(elem.String[0])
+			case ibmmq.MQIA_DEF_PERSISTENCE:
+				if int32(elem.Int64Value[0]) == ibmmq.MQPER_PERSISTENT {
+					v[elem.Parameter] = "YES"
+				} else {
+					v[elem.Parameter] = "NO"
+				}
+			case ibmmq.MQCA_CUSTOM:
+				v[elem.Parameter] = strings.TrimSpace(elem.String[0])
+			}
+		}
+		for attr, val := range v {
+			save(qName, attr, val)
+		}
+	}
+	return nil
+}
+
+// Connect in the same way as mqmetric does for the main connection, but without reconnection
+func (c *Collector) connectForInquiry() (ibmmq.MQQueueManager, error) {
+	cc, tc, err := c.connectionConfig()
+	if err != nil {
+		return ibmmq.MQQueueManager{}, err
+	}
+	defer tc.Remove()
+
+	cno := ibmmq.NewMQCNO()
+	if cc.CcdtUrl != "" || cc.ConnName != "" || cc.Channel != "" || cc.ClientMode {
+		cno.Options = ibmmq.MQCNO_CLIENT_BINDING | ibmmq.MQCNO_RECONNECT_DISABLED
+		if cc.CcdtUrl != "" {
+			cno.CCDTUrl = cc.CcdtUrl
+		} else if cc.ConnName != "" || cc.Channel != "" {
+			cd := ibmmq.NewMQCD()
+			cd.ChannelName = cc.Channel
+			cd.ConnectionName = cc.ConnName
+			cno.ClientConn = cd
+		}
+	}
+	if cc.UserId != "" {
+		csp := ibmmq.NewMQCSP()
+		csp.UserId = cc.UserId
+		csp.Password = cc.Password
+		cno.SecurityParms = csp
+	}
+	// Unlike mqmetric, we can give the keystore details directly
+	if tc != nil {
+		sco := ibmmq.NewMQSCO()
+		sco.KeyRepository = tc.KeyRepository
+		sco.KeyRepoPassword = tc.KeyRepoPassword
+		cno.SSLConfig = sco
+	}
+	return ibmmq.Connx(c.Config().QMgrName, cno)
+}
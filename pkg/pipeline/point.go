@@ -0,0 +1,169 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This file defines the neutral model of a metric point that is produced once per
+collection cycle, and the Sink interface that each backend implements to
+write those points to its own database or output format.
+*/
+
+import (
+	"strings"
+	"time"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+)
+
+// Kind says whether a metric can be treated as an accumulating counter, or
+// is an absolute value. Backends that don't distinguish the two can ignore it.
+type Kind int
+
+const (
+	Gauge Kind = iota
+	Counter
+)
+
+// Source says where the value for a point came from.
+type Source int
+
+const (
+	SourcePublication Source = iota // Resource publications on $SYS topics (like amqsrua)
+	SourceStatus                    // Responses to DISPLAY xxSTATUS and similar polled commands
+	SourceExporter                  // Information about the collector itself
+)
+
+// Units that are derived from the metric names
+const (
+	UnitNone    = ""
+	UnitSeconds = "seconds"
+	UnitBytes   = "bytes"
+	UnitPercent = "percent"
+)
+
+// The object types that are used as the series names, and as
+// the prefix for the full metric names
+const (
+	ObjectQMgr         = "qmgr"
+	ObjectQueue        = "queue"
+	ObjectNHA          = "nha"
+	ObjectChannel      = "channel"
+	ObjectTopic        = "topic"
+	ObjectSubscription = "subscription"
+	ObjectCluster      = "cluster"
+	ObjectBufferPool   = "bufferpool"
+	ObjectPageSet      = "pageset"
+	ObjectAMQP         = "amqp"
+	ObjectMQTT         = "mqtt"
+)
+
+/*
+Point is a single value for a metric, with the labels (tags) that identify the object it
+refers to. The Metric field is the name as known by the mqmetric package, without any
+object type prefix, unless it has been renamed by the relabelling rules.
+*/
+type Point struct {
+	Metric      string
+	ObjectType  string
+	Description string
+	Labels      map[string]string
+	Value       float64
+	Timestamp   time.Time
+	Kind        Kind
+	Unit        string
+	Source      Source
+
+	name string // A new name that can't be made from the ObjectType and Metric
+}
+
+/*
+Batch is the complete set of points from one collection cycle. Status points are
+only included when there has been a poll for status on this cycle; backends that keep
+state between cycles (like Prometheus) can use StatusPolled to decide whether to reset
+their previous values. Similarly, a collection for a Selection might not have processed
+the publications, and then PublicationsSkipped says that the previous published values
+still stand.
+*/
+type Batch struct {
+	Timestamp           time.Time
+	QMgr                string
+	Platform            string
+	StatusPolled        bool
+	PublicationsSkipped bool
+	Points              []Point
+
+	filter  *cf.MetricFilter // Which metrics are kept when points are added
+	relabel cf.RelabelRules  // And then how their names and labels are changed
+}
+
+/*
+Sink is implemented by each backend. Write is called once for each collection cycle
+that has produced data.
+*/
+type Sink interface {
+	Name() string
+	Write(b *Batch) error
+}
+
+/*
+Name returns the combination of object type and metric name, joined by "_". Some
+metrics would look a bit silly, such as "queue_queue_purged", so we remove the duplicate.
+This is the form that is used in the Prometheus metric names, and it is the "normalised"
+name that configuration options refer to.
+*/
+func (p *Point) Name() string {
+	if p.name != "" {
+		return p.name
+	}
+	return FullName(p.ObjectType, p.Metric)
+}
+
+/*
+rename gives the point a new full name. The backends that keep the object type apart
+from the metric, such as InfluxDB, get the rest of the name as the metric when it still
+starts with the object type. Otherwise they get the whole of it.
+*/
+func (p *Point) rename(name string) {
+	p.Metric = strings.TrimPrefix(name, p.ObjectType+"_")
+	p.name = ""
+	if FullName(p.ObjectType, p.Metric) != name {
+		p.name = name
+	}
+}
+
+// FullName does the same as Point.Name for when there's not yet a Point to work from
+func FullName(objectType string, metric string) string {
+	if objectType == ObjectQueue && strings.HasPrefix(metric, "queue_") {
+		return metric
+	}
+	return objectType + "_" + metric
+}
+
+// Work out the units from the suffix that the mqmetric package puts onto the metric names
+func unitFor(metric string) string {
+	unit := UnitNone
+	if strings.HasSuffix(metric, "_seconds") {
+		unit = UnitSeconds
+	} else if strings.HasSuffix(metric, "_bytes") {
+		unit = UnitBytes
+	} else if strings.HasSuffix(metric, "_percentage") || strings.HasSuffix(metric, "_percent") {
+		unit = UnitPercent
+	}
+	return unit
+}
@@ -0,0 +1,320 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+The status polling is split into tiers, each with its own interval. Every object type has a
+tier that covers all of its objects, at the pollInterval unless the configuration says
+otherwise, and the queues can also be split into tiers by name.
+
+Each call to one of the mqmetric Collect*Status functions empties the status for that object
+type first. So the Collector keeps its own status sets, which are what the source gives back.
+When a tier is polled, its objects are replaced in those sets, and everything else is left as
+it was. That way, the last-known values for the tiers that are not due are reported again
+along with the ones that have just been polled. The values that are differences from the
+previous poll are carried forward as 0, so they are not counted twice.
+
+The queue status has no values like that, and the other object types can only have a single
+tier, which matters because mqmetric works out the differences for all of a type's objects
+at once.
+*/
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	log "github.com/sirupsen/logrus"
+)
+
+// Which of mqmetric's status sets are filled by polling each object type
+var pollObjectTypes = map[string][]int{
+	ObjectChannel:      {mqmetric.OT_CHANNEL},
+	ObjectTopic:        {mqmetric.OT_TOPIC},
+	ObjectSubscription: {mqmetric.OT_SUB},
+	ObjectQueue:        {mqmetric.OT_Q},
+	ObjectQMgr:         {mqmetric.OT_Q_MGR},
+	ObjectCluster:      {mqmetric.OT_CLUSTER},
+	objectUsage:        {mqmetric.OT_BP, mqmetric.OT_PS},
+	ObjectAMQP:         {mqmetric.OT_CHANNEL_AMQP},
+	ObjectMQTT:         {mqmetric.OT_CHANNEL_MQTT},
+}
+
+// The buffer pools and pagesets are polled together
+const objectUsage = "usage"
+
+type pollTier struct {
+	objectType string
+	patterns   string // Queue patterns, or empty for all the objects of the type
+	interval   time.Duration
+	lastPoll   time.Time
+	due        bool
+}
+
+/*
+setPollTiers builds the tiers from the configuration, with a tier at the pollInterval for
+anything that isn't otherwise covered. It's called again when the configuration is reloaded,
+when a tier that hasn't changed keeps the time it was last polled.
+*/
+func (c *Collector) setPollTiers() {
+	var tiers []*pollTier
+
+	for _, t := range c.Config().PollTiers {
+		tiers = append(tiers, &pollTier{objectType: t.ObjectType, patterns: t.Patterns, interval: t.Interval})
+	}
+	for objectType := range pollObjectTypes {
+		if !slices.ContainsFunc(tiers, func(t *pollTier) bool { return t.objectType == objectType && t.patterns == "" }) {
+			tiers = append(tiers, &pollTier{objectType: objectType, interval: c.Config().PollIntervalDuration})
+		}
+	}
+
+	for _, t := range tiers {
+		for _, old := range c.tiers {
+			if old.objectType == t.objectType && old.patterns == t.patterns {
+				t.lastPoll = old.lastPoll
+			}
+		}
+	}
+	c.tiers = tiers
+	c.queueTiers = make(map[string]*pollTier)
+}
+
+// Work out which tiers are due to be polled in this collection. They are all due after a
+// (re)discovery.
+func (c *Collector) dueTiers(now time.Time) bool {
+	anyDue := false
+	for _, t := range c.tiers {
+		t.due = c.first || now.Sub(t.lastPoll) >= t.interval
+		if t.due {
+			t.lastPoll = now
+			anyDue = true
+		}
+	}
+	return anyDue
+}
+
+/*
+selectTiers makes the tiers for the selected object types due, regardless of when they were
+last polled. When the queues are wanted by name, their tiers are left alone, as those queues
+are polled separately. The return value says whether anything will be polled.
+*/
+func (c *Collector) selectTiers(sel *Selection, now time.Time) bool {
+	anyDue := sel.namedQueues() && c.Config().CC.UseStatus
+	for _, t := range c.tiers {
+		t.due = sel.polls(t.objectType) && !(t.objectType == ObjectQueue && sel.namedQueues())
+		if t.due {
+			t.lastPoll = now
+			anyDue = true
+		}
+	}
+	return anyDue
+}
+
+func (c *Collector) tierDue(objectType string) bool {
+	return slices.ContainsFunc(c.tiers, func(t *pollTier) bool { return t.objectType == objectType && t.due })
+}
+
+// A queue belongs to the first tier whose patterns it matches. The tiers only divide up the
+// monitored queues, so anything else is not in a tier at all. The answers are saved until the
+// tiers or the monitored queues change.
+func (c *Collector) queueTier(qName string) *pollTier {
+	if t, ok := c.queueTiers[qName]; ok {
+		return t
+	}
+
+	var tier *pollTier
+	if len(mqmetric.FilterRegExp(c.Config().MonitoredQueues, []string{qName})) == 0 {
+		c.queueTiers[qName] = nil
+		return nil
+	}
+	for _, t := range c.tiers {
+		if t.objectType == ObjectQueue && (t.patterns == "" || len(mqmetric.FilterRegExp(t.patterns, []string{qName})) > 0) {
+			tier = t
+			break
+		}
+	}
+	c.queueTiers[qName] = tier
+	return tier
+}
+
+/*
+pollQueueStatus polls the queues in the tiers that are due. If the tier for all the other
+queues is due, then a single poll of all the monitored queues does for every tier. Otherwise
+each tier is polled with its own patterns.
+*/
+func (c *Collector) pollQueueStatus(check func(string, string, func() error)) {
+	var due []*pollTier
+	all := false
+	count := 0
+	for _, t := range c.tiers {
+		if t.objectType != ObjectQueue {
+			continue
+		}
+		count++
+		if t.due {
+			due = append(due, t)
+			all = all || t.patterns == ""
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	// The usual case, without any tiers for the queues
+	if count == 1 {
+		check("queue", ObjectQueue, func() error { return mqmetric.CollectQueueStatus(c.Config().MonitoredQueues) })
+		c.status[mqmetric.OT_Q] = mqmetric.GetObjectStatus(c.key, mqmetric.OT_Q)
+		return
+	}
+
+	inTiers := func(tiers []*pollTier) func(string) bool {
+		return func(qName string) bool { return slices.Contains(tiers, c.queueTier(qName)) }
+	}
+	if all {
+		check("queue", ObjectQueue, func() error { return mqmetric.CollectQueueStatus(c.Config().MonitoredQueues) })
+		c.mergeQueueStatus(inTiers(due))
+	} else {
+		for _, t := range due {
+			log.Debugf("Polling queue status for %s", t.patterns)
+			check("queue", ObjectQueue, func() error { return mqmetric.CollectQueueStatus(t.patterns) })
+			c.mergeQueueStatus(inTiers([]*pollTier{t}))
+		}
+	}
+}
+
+// Up to this many queues are asked about by name. Beyond that, it's cheaper to poll the
+// monitored queues in the usual way and keep the ones that were selected.
+const maxNamedQueues = 20
+
+/*
+pollNamedQueues polls the queues whose names match a Selection's patterns. The patterns come
+from the request, so they only choose among the monitored queues that have been discovered;
+the queue manager is never asked about anything else. The status of all the other queues
+stays as it was. Without any tiers, the set of queue status belongs to mqmetric, and is
+about to be emptied by the poll, so it has to be copied first.
+*/
+func (c *Collector) pollNamedQueues(check func(string, string, func() error), sel *Selection) {
+	var names []string
+	for _, qName := range mqmetric.GetDiscoveredQueues() {
+		if c.queueTier(qName) != nil && sel.matchesName(qName) {
+			names = append(names, qName)
+		}
+	}
+	if len(names) == 0 {
+		log.Debugf("No monitored queues match %s", sel.names)
+		return
+	}
+	slices.Sort(names)
+	patterns := strings.Join(names, ",")
+	if len(names) > maxNamedQueues {
+		patterns = c.Config().MonitoredQueues
+	}
+
+	if set, ok := c.status[mqmetric.OT_Q]; ok && set == mqmetric.GetObjectStatus(c.key, mqmetric.OT_Q) {
+		c.status[mqmetric.OT_Q] = copyStatus(set)
+	}
+
+	log.Debugf("Polling queue status for %d queues matching %s", len(names), sel.names)
+	check("queue", ObjectQueue, func() error { return mqmetric.CollectQueueStatus(patterns) })
+	c.mergeQueueStatus(func(qName string) bool {
+		return c.queueTier(qName) != nil && sel.matchesName(qName)
+	})
+}
+
+// Replace the status of the queues that have just been polled with what has been collected
+func (c *Collector) mergeQueueStatus(polled func(string) bool) {
+	fresh := mqmetric.GetObjectStatus(c.key, mqmetric.OT_Q)
+	merged, ok := c.status[mqmetric.OT_Q]
+	if !ok || merged == fresh {
+		merged = &mqmetric.StatusSet{Attributes: make(map[string]*mqmetric.StatusAttribute)}
+		c.status[mqmetric.OT_Q] = merged
+	}
+
+	for name, attr := range fresh.Attributes {
+		m, ok := merged.Attributes[name]
+		if !ok {
+			a := *attr
+			a.Values = make(map[string]*mqmetric.StatusValue)
+			m = &a
+			merged.Attributes[name] = m
+		}
+		for k := range m.Values {
+			if polled(k) {
+				delete(m.Values, k)
+			}
+		}
+		for k, v := range attr.Values {
+			if polled(k) {
+				m.Values[k] = v
+			}
+		}
+	}
+}
+
+// The status of the object types that were not due is reported again, but without the differences
+func (c *Collector) carryStatus() {
+	for _, t := range c.tiers {
+		if t.due || t.objectType == ObjectQueue {
+			continue
+		}
+		for _, ot := range pollObjectTypes[t.objectType] {
+			if set, ok := c.status[ot]; ok {
+				c.status[ot] = zeroDeltas(set)
+			}
+		}
+	}
+}
+
+// A copy of the set, with the values that are differences from the previous poll set to 0.
+// The attributes that don't change are shared with the original set.
+func zeroDeltas(set *mqmetric.StatusSet) *mqmetric.StatusSet {
+	n := &mqmetric.StatusSet{Attributes: make(map[string]*mqmetric.StatusAttribute, len(set.Attributes))}
+	for name, attr := range set.Attributes {
+		if !attr.Delta {
+			n.Attributes[name] = attr
+			continue
+		}
+		a := *attr
+		a.Values = make(map[string]*mqmetric.StatusValue, len(attr.Values))
+		for k, v := range attr.Values {
+			sv := *v
+			if sv.IsInt64 {
+				sv.ValueInt64 = 0
+			}
+			a.Values[k] = &sv
+		}
+		n.Attributes[name] = &a
+	}
+	return n
+}
+
+// A copy of the set that does not share anything that a poll would change
+func copyStatus(set *mqmetric.StatusSet) *mqmetric.StatusSet {
+	n := &mqmetric.StatusSet{Attributes: make(map[string]*mqmetric.StatusAttribute, len(set.Attributes))}
+	for name, attr := range set.Attributes {
+		a := *attr
+		a.Values = make(map[string]*mqmetric.StatusValue, len(attr.Values))
+		for k, v := range attr.Values {
+			a.Values[k] = v
+		}
+		n.Attributes[name] = &a
+	}
+	return n
+}
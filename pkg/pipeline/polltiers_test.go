@@ -0,0 +1,189 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+)
+
+func tierTestCollector() *Collector {
+	return NewCollector(&cf.Config{
+		QMgrName:             "QM1",
+		MonitoredQueues:      "APP.*,PAY.*,BULK.*,!BULK.X",
+		PollIntervalDuration: time.Minute,
+		PollTiers: []cf.PollTier{
+			{ObjectType: ObjectQueue, Patterns: "PAY.*", Interval: 10 * time.Second},
+			{ObjectType: ObjectQueue, Patterns: "BULK.*,APP.*", Interval: 5 * time.Minute},
+			{ObjectType: ObjectChannel, Interval: 30 * time.Second},
+		},
+	})
+}
+
+func findTier(c *Collector, objectType string, patterns string) *pollTier {
+	for _, t := range c.tiers {
+		if t.objectType == objectType && t.patterns == patterns {
+			return t
+		}
+	}
+	return nil
+}
+
+// Every object type has a tier for all of its objects, at the pollInterval unless it's configured
+func TestSetPollTiers(t *testing.T) {
+	c := tierTestCollector()
+	for objectType := range pollObjectTypes {
+		want := time.Minute
+		if objectType == ObjectChannel {
+			want = 30 * time.Second
+		}
+		tier := findTier(c, objectType, "")
+		if tier == nil {
+			t.Errorf("No tier for every %s", objectType)
+		} else if tier.interval != want {
+			t.Errorf("Tier for every %s has interval %v, expected %v", objectType, tier.interval, want)
+		}
+	}
+	if len(c.tiers) != len(pollObjectTypes)+2 {
+		t.Errorf("There are %d tiers", len(c.tiers))
+	}
+
+	// A reload keeps the time of the last poll for the tiers that are still there
+	polled := time.Now().Add(-time.Hour)
+	findTier(c, ObjectQueue, "PAY.*").lastPoll = polled
+	findTier(c, ObjectTopic, "").lastPoll = polled
+	c.updateConfig(func(cm *cf.Config) {
+		cm.PollTiers = []cf.PollTier{{ObjectType: ObjectQueue, Patterns: "PAY.*", Interval: 20 * time.Second}}
+	})
+	c.setPollTiers()
+	if tier := findTier(c, ObjectQueue, "PAY.*"); tier == nil || !tier.lastPoll.Equal(polled) || tier.interval != 20*time.Second {
+		t.Errorf("PAY.* tier is %+v", tier)
+	}
+	if tier := findTier(c, ObjectTopic, ""); !tier.lastPoll.Equal(polled) {
+		t.Errorf("Topic tier was last polled at %v", tier.lastPoll)
+	}
+	if findTier(c, ObjectQueue, "BULK.*,APP.*") != nil {
+		t.Error("Removed tier is still there")
+	}
+}
+
+func TestDueTiers(t *testing.T) {
+	c := tierTestCollector()
+	start := time.Now()
+
+	// Everything is due the first time
+	if !c.dueTiers(start) {
+		t.Fatal("Nothing was due")
+	}
+	for _, tier := range c.tiers {
+		if !tier.due {
+			t.Errorf("%s tier %s was not due", tier.objectType, tier.patterns)
+		}
+	}
+	c.first = false
+
+	tests := []struct {
+		after time.Duration
+		due   []string // Queue patterns, or the other object types
+	}{
+		{after: 5 * time.Second},
+		{after: 10 * time.Second, due: []string{"PAY.*"}},
+		{after: 30 * time.Second, due: []string{"PAY.*", ObjectChannel}},
+		{after: time.Minute, due: []string{"PAY.*", ObjectChannel, "", ObjectTopic, ObjectQMgr}},
+	}
+	for _, tt := range tests {
+		anyDue := c.dueTiers(start.Add(tt.after))
+		if anyDue != (len(tt.due) > 0) {
+			t.Errorf("After %v, due is %v", tt.after, anyDue)
+		}
+		for _, d := range tt.due {
+			var tier *pollTier
+			if d == "" || d == "PAY.*" {
+				tier = findTier(c, ObjectQueue, d)
+			} else {
+				tier = findTier(c, d, "")
+			}
+			if !tier.due {
+				t.Errorf("After %v, %s tier was not due", tt.after, d)
+			}
+		}
+		if tier := findTier(c, ObjectQueue, "BULK.*,APP.*"); tier.due {
+			t.Errorf("After %v, BULK tier was due", tt.after)
+		}
+		// Which means they are not due again straight away
+		start = start.Add(tt.after)
+	}
+}
+
+func TestQueueTier(t *testing.T) {
+	c := tierTestCollector()
+	tests := []struct {
+		queue    string
+		patterns string
+		none     bool
+	}{
+		{queue: "PAY.IN", patterns: "PAY.*"},
+		{queue: "APP.Q", patterns: "BULK.*,APP.*"},
+		{queue: "BULK.Y", patterns: "BULK.*,APP.*"},
+		{queue: "BULK.X", none: true},
+		{queue: "OTHER.Q", none: true},
+	}
+	for i := 0; i < 2; i++ {
+		for _, tt := range tests {
+			tier := c.queueTier(tt.queue)
+			if tt.none {
+				if tier != nil {
+					t.Errorf("%s is in tier %s", tt.queue, tier.patterns)
+				}
+			} else if tier == nil || tier.patterns != tt.patterns {
+				t.Errorf("%s is in tier %+v, expected %s", tt.queue, tier, tt.patterns)
+			}
+		}
+	}
+	if len(c.queueTiers) != len(tests) {
+		t.Errorf("%d queues were remembered", len(c.queueTiers))
+	}
+}
+
+// The last values of the tiers that aren't polled are reported again, without counting the differences twice
+func TestZeroDeltas(t *testing.T) {
+	depth := &mqmetric.StatusAttribute{Values: map[string]*mqmetric.StatusValue{"A": {IsInt64: true, ValueInt64: 5}}}
+	msgs := &mqmetric.StatusAttribute{Delta: true, Values: map[string]*mqmetric.StatusValue{
+		"A": {IsInt64: true, ValueInt64: 7},
+		"B": {ValueString: "x"},
+	}}
+	set := &mqmetric.StatusSet{Attributes: map[string]*mqmetric.StatusAttribute{"depth": depth, "msgs": msgs}}
+
+	z := zeroDeltas(set)
+	if z.Attributes["depth"] != depth {
+		t.Error("Attribute without differences was copied")
+	}
+	if v := z.Attributes["msgs"].Values["A"].ValueInt64; v != 0 {
+		t.Errorf("Difference is %d", v)
+	}
+	if v := z.Attributes["msgs"].Values["B"].ValueString; v != "x" {
+		t.Errorf("String value is %q", v)
+	}
+	if msgs.Values["A"].ValueInt64 != 7 {
+		t.Error("Original set was changed")
+	}
+}
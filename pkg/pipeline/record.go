@@ -0,0 +1,220 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This file saves what each collection received from the queue manager, so that it can be
+replayed later without a connection. The messages themselves - the resource publications and
+the PCF responses to the status commands - are read and decoded inside the mqmetric package,
+and are not available here. So what is saved is mqmetric's view of them once they have been
+processed: the values of every published element and status attribute, along with the object
+descriptions and attributes that were looked up while building the labels.
+
+The file has one JSON object per line, for each collection from each queue manager. Several
+Collectors can write to the same file. The first collection after a connection is not saved,
+as it is not reported either.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	log "github.com/sirupsen/logrus"
+)
+
+type record struct {
+	Timestamp        time.Time                                     `json:"timestamp"`
+	QMgr             string                                        `json:"qmgr"`
+	Platform         int32                                         `json:"platform"`
+	SupportsHostname bool                                          `json:"supportsHostname"`
+	Description      string                                        `json:"description"`
+	Hostname         string                                        `json:"hostname"`
+	StatusPolled     bool                                          `json:"statusPolled"`
+	PublicationCount int                                           `json:"publicationCount"`
+	Published        []recordedElement                             `json:"published"`
+	Status           map[string]map[string]recordedStatusAttribute `json:"status"`
+	Descriptions     map[string]string                             `json:"descriptions"`
+	QueueAttributes  map[string]string                             `json:"queueAttributes"`
+}
+
+type recordedElement struct {
+	Class          string           `json:"class"`
+	Type           string           `json:"type"`
+	ObjectTopic    string           `json:"objectTopic"`
+	Description    string           `json:"description"`
+	DescriptionNLS string           `json:"descriptionNLS,omitempty"`
+	MetricName     string           `json:"metricName"`
+	Datatype       int32            `json:"datatype"`
+	Values         map[string]int64 `json:"values"`
+}
+
+type recordedStatusAttribute struct {
+	Description string                           `json:"description"`
+	MetricName  string                           `json:"metricName"`
+	Pseudo      bool                             `json:"pseudo,omitempty"`
+	Delta       bool                             `json:"delta,omitempty"`
+	Values      map[string]*mqmetric.StatusValue `json:"values"`
+}
+
+// The looked-up values are saved with the object type or attribute number
+func lookupKey(n int32, name string) string {
+	return fmt.Sprintf("%d/%s", n, name)
+}
+
+type recorder struct {
+	mutex  sync.Mutex
+	file   string
+	f      *os.File
+	enc    *json.Encoder
+	failed bool
+}
+
+var (
+	recordersMutex sync.Mutex
+	recorders      = make(map[string]*recorder)
+)
+
+// All of the Collectors that record to the same file share its recorder
+func getRecorder(file string) *recorder {
+	recordersMutex.Lock()
+	defer recordersMutex.Unlock()
+
+	r, ok := recorders[file]
+	if !ok {
+		r = &recorder{file: file}
+		recorders[file] = r
+	}
+	return r
+}
+
+// The file is opened on the first write, and added to rather than replaced. Failures are
+// logged once, and do not stop the collection.
+func (r *recorder) write(rec *record) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.failed {
+		return
+	}
+	if r.f == nil {
+		f, err := os.OpenFile(r.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+		if err != nil {
+			log.Errorf("Cannot open recording file %s: %v", r.file, err)
+			r.failed = true
+			return
+		}
+		log.Infof("Recording collections to %s", r.file)
+		r.f = f
+		r.enc = json.NewEncoder(f)
+	}
+	if err := r.enc.Encode(rec); err != nil {
+		log.Errorf("Cannot write to recording file %s: %v", r.file, err)
+		r.failed = true
+	}
+}
+
+// A source that saves whatever is looked up for the labels
+type recordingSource struct {
+	source
+	rec *record
+}
+
+func (s recordingSource) objectDescription(name string, ot int32) string {
+	v := s.source.objectDescription(name, ot)
+	s.rec.Descriptions[lookupKey(ot, name)] = v
+	return v
+}
+
+func (s recordingSource) queueAttribute(name string, attr int32) string {
+	v := s.source.queueAttribute(name, attr)
+	s.rec.QueueAttributes[lookupKey(attr, name)] = v
+	return v
+}
+
+// Build the Batch as usual, while saving everything that went into it
+func (c *Collector) recordBatch(pollStatus bool, collectStartTime time.Time) *Batch {
+	live := c.src
+	rec := &record{
+		QMgr:             c.QMgrName(),
+		Platform:         c.platform,
+		SupportsHostname: c.supportsHostname,
+		Description:      c.qmgrDescription,
+		Hostname:         c.hostname,
+		StatusPolled:     pollStatus,
+		PublicationCount: live.publicationCount(),
+		Status:           make(map[string]map[string]recordedStatusAttribute),
+		Descriptions:     make(map[string]string),
+		QueueAttributes:  make(map[string]string),
+	}
+
+	for _, cl := range live.publishedMetrics().Classes {
+		for _, ty := range cl.Types {
+			for _, elem := range ty.Elements {
+				re := recordedElement{
+					Class:          cl.Name,
+					Type:           ty.Name,
+					ObjectTopic:    ty.ObjectTopic,
+					Description:    elem.Description,
+					DescriptionNLS: elem.DescriptionNLS,
+					MetricName:     elem.MetricName,
+					Datatype:       elem.Datatype,
+					Values:         make(map[string]int64),
+				}
+				for k, v := range elem.Values {
+					re.Values[k] = v
+				}
+				rec.Published = append(rec.Published, re)
+			}
+		}
+	}
+
+	for _, st := range statusTypes {
+		if !c.applies(st.objectType) {
+			continue
+		}
+		attrs := make(map[string]recordedStatusAttribute)
+		for name, attr := range live.objectStatus(st.ot).Attributes {
+			ra := recordedStatusAttribute{
+				Description: attr.Description,
+				MetricName:  attr.MetricName,
+				Pseudo:      attr.Pseudo,
+				Delta:       attr.Delta,
+				Values:      make(map[string]*mqmetric.StatusValue),
+			}
+			for k, v := range attr.Values {
+				sv := *v
+				ra.Values[k] = &sv
+			}
+			attrs[name] = ra
+		}
+		rec.Status[st.objectType] = attrs
+	}
+
+	c.src = recordingSource{source: live, rec: rec}
+	b := c.buildBatch(time.Now(), pollStatus, false, collectStartTime)
+	c.src = live
+
+	rec.Timestamp = b.Timestamp
+	c.recorder.write(rec)
+	return b
+}
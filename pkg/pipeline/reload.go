@@ -0,0 +1,122 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This file applies a reloaded configuration to the Collectors, without dropping their
+connections. A change to the list of queues is handled in the same way as the regular
+rediscovery: new queues are subscribed to, and queues that no longer match are unsubscribed.
+Channel attributes are fetched again for the new patterns. The topic and subscription
+patterns are only used when polling for status, so they take effect on the next poll.
+*/
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+Reload gives each Collector its part of a reloaded configuration. Queue managers are matched
+by name, unless there's only one, when the name might have been resolved from a default. The
+list of queue managers itself can't be changed without a restart.
+*/
+func (g *Group) Reload(cms []*cf.Config) {
+	used := make(map[*cf.Config]bool)
+
+	for _, c := range g.Collectors {
+		var nc *cf.Config
+		if len(g.Collectors) == 1 && len(cms) == 1 {
+			nc = cms[0]
+		} else {
+			for _, cm := range cms {
+				if strings.TrimSpace(cm.QMgrName) == c.QMgrName() {
+					nc = cm
+					break
+				}
+			}
+		}
+
+		if nc == nil {
+			log.Warnf("Queue manager %s is no longer in the configuration. It will be monitored until the collector is restarted.", c.QMgrName())
+			continue
+		}
+		used[nc] = true
+		c.Reconfigure(nc)
+	}
+
+	for _, cm := range cms {
+		if !used[cm] {
+			log.Warnf("Queue manager %s has been added to the configuration. It will not be monitored until the collector is restarted.", cm.QMgrName)
+		}
+	}
+}
+
+/*
+Reconfigure applies the settings that can be changed while running, and reports the ones
+that can't. If the monitored objects are different, and there's a live connection, they
+are rediscovered straightaway so the next collection uses them.
+*/
+func (c *Collector) Reconfigure(nc *cf.Config) {
+	c.lock()
+	defer c.unlock()
+
+	// The changes are made to a copy, as the configuration is read without the lock
+	var ch cf.Changes
+	c.updateConfig(func(cm *cf.Config) { ch = cf.Reconfigure(cm, nc) })
+	if len(ch.Restart) > 0 {
+		log.Warnf("Configuration changes for %s need a restart to take effect: %s", c.QMgrName(), strings.Join(ch.Restart, ", "))
+	}
+	if len(ch.Live) == 0 {
+		log.Infof("No configuration changes to apply for %s", c.QMgrName())
+		return
+	}
+	log.Infof("Applying configuration changes for %s: %s", c.QMgrName(), strings.Join(ch.Live, ", "))
+
+	// The new object lists are picked up when the connection is next made
+	c.discoverConfig.MonitoredQueues.ObjectNames = c.Config().MonitoredQueues
+	c.setPollTiers()
+	if c.replay != nil || c.sim != nil || !c.Connected() {
+		return
+	}
+
+	if ch.Queues || ch.Channels {
+		resubscribe(c, ch.Queues)
+	}
+}
+
+/*
+resubscribe makes the subscriptions for the queues that now match the patterns, and closes
+the ones for queues that no longer match, when the queues have changed. The attributes for
+the labels are then fetched again. The tests replace it, as they have no queue manager.
+*/
+var resubscribe = func(c *Collector, queues bool) {
+	if queues {
+		err := mqmetric.RediscoverAndSubscribe(c.discoverConfig)
+		if err != nil {
+			log.Errorf("Error subscribing to the queues for %s: %v", c.QMgrName(), err)
+		}
+		c.inquireQueueAttributes()
+		c.lastQueueDiscovery = time.Now()
+	}
+	c.rediscoverAttributes()
+}
@@ -0,0 +1,70 @@
+package pipeline_test
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+The configuration can be reloaded while the web endpoints and the collection loop are
+reading it. This is only useful with "go test -race".
+*/
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+)
+
+func TestReconfigureWhileReading(t *testing.T) {
+	c := pipeline.NewCollector(&cf.Config{Simulate: true, MonitoredQueues: "APP.*"})
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Cannot start the simulation: %v", err)
+	}
+
+	// Reloading and collecting take the lock, but the readers here don't
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			nc := &cf.Config{Simulate: true, MonitoredQueues: "APP.*", PollIntervalDuration: time.Duration(i) * time.Second}
+			if i%2 == 1 {
+				nc.MonitoredQueues = "APP.*,SYSTEM.*"
+			}
+			c.Reconfigure(nc)
+			if _, err := c.Collect(); err != nil {
+				t.Errorf("Collection failed while reconfiguring: %v", err)
+			}
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		if c.QMgrName() == "" {
+			t.Errorf("No queue manager name while reconfiguring")
+		}
+		if c.DisconnectedBatch() == nil {
+			t.Errorf("No batch while reconfiguring")
+		}
+		if c.Config().MonitoredQueues == "" {
+			t.Errorf("No monitored queues while reconfiguring")
+		}
+	}
+	wg.Wait()
+}
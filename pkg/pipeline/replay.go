@@ -0,0 +1,274 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This file feeds a recording back through a Collector in place of a queue manager
+connection. Connecting opens the file and reads the first collection for the queue
+manager, which is enough for the collectors to know which metrics there are. Each call
+to Collect then moves on to the next one, and builds the Batch in exactly the same way
+as for a live connection.
+
+When a queue manager name has not been configured, the first one in the file is used.
+At the end of the file, the replay either starts again, or the Collector stays
+disconnected and ErrReplayFinished is returned. A replay that starts again reports the
+current time, as a database might not accept the same timestamps twice; otherwise the
+recorded times are kept, so the output is the same on each run.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrReplayFinished is returned when there is nothing more to replay
+var ErrReplayFinished = errors.New("end of replay file")
+
+type replayer struct {
+	file     string
+	loop     bool
+	qmgr     string
+	f        *os.File
+	dec      *json.Decoder
+	pending  *record // Read while connecting, and returned by the first call to next
+	finished bool
+
+	current   *record
+	published *mqmetric.AllMetrics
+	status    map[int]*mqmetric.StatusSet
+}
+
+func newReplayer(file string, loop bool) *replayer {
+	return &replayer{
+		file: file,
+		loop: loop,
+	}
+}
+
+// Start reading from the beginning of the file, and find the first collection for the queue manager
+func (r *replayer) open(qmgr string) error {
+	if r.finished {
+		return ErrReplayFinished
+	}
+	r.close()
+	r.qmgr = qmgr
+
+	f, err := os.Open(r.file)
+	if err != nil {
+		return err
+	}
+	r.f = f
+	r.dec = json.NewDecoder(f)
+
+	rec, err := r.read()
+	if err == io.EOF {
+		err = fmt.Errorf("No data for queue manager '%s' in %s", qmgr, r.file)
+	}
+	if err != nil {
+		r.close()
+		return err
+	}
+	r.qmgr = rec.QMgr
+	r.pending = rec
+	r.setCurrent(rec)
+	return nil
+}
+
+func (r *replayer) close() {
+	if r.f != nil {
+		r.f.Close()
+		r.f = nil
+	}
+}
+
+// Read the next collection for our queue manager, skipping the others
+func (r *replayer) read() (*record, error) {
+	for {
+		rec := new(record)
+		if err := r.dec.Decode(rec); err != nil {
+			if err != io.EOF {
+				err = fmt.Errorf("Cannot read replay file %s: %v", r.file, err)
+			}
+			return nil, err
+		}
+		if r.qmgr == "" || rec.QMgr == r.qmgr {
+			return rec, nil
+		}
+	}
+}
+
+func (r *replayer) next() (*record, error) {
+	var err error
+
+	rec := r.pending
+	r.pending = nil
+	if rec == nil {
+		if r.f == nil {
+			return nil, ErrReplayFinished
+		}
+		rec, err = r.read()
+		if err == io.EOF && r.loop {
+			log.Infof("Restarting replay of %s for %s", r.file, r.qmgr)
+			if err = r.open(r.qmgr); err == nil {
+				rec = r.pending
+				r.pending = nil
+			}
+		}
+		if err == io.EOF {
+			log.Infof("Replay of %s for %s has finished", r.file, r.qmgr)
+			r.finished = true
+			r.close()
+			err = ErrReplayFinished
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.setCurrent(rec)
+	return rec, nil
+}
+
+// Rebuild the mqmetric structures from a recorded collection
+func (r *replayer) setCurrent(rec *record) {
+	r.current = rec
+
+	all := &mqmetric.AllMetrics{Classes: make(map[int]*mqmetric.MonClass)}
+	classes := make(map[string]*mqmetric.MonClass)
+	types := make(map[string]*mqmetric.MonType)
+	for _, re := range rec.Published {
+		cl, ok := classes[re.Class]
+		if !ok {
+			cl = &mqmetric.MonClass{Parent: all, Name: re.Class, Types: make(map[int]*mqmetric.MonType)}
+			all.Classes[len(all.Classes)] = cl
+			classes[re.Class] = cl
+		}
+		ty, ok := types[re.Class+"/"+re.Type]
+		if !ok {
+			ty = &mqmetric.MonType{Parent: cl, Name: re.Type, ObjectTopic: re.ObjectTopic, Elements: make(map[int]*mqmetric.MonElement)}
+			cl.Types[len(cl.Types)] = ty
+			types[re.Class+"/"+re.Type] = ty
+		}
+		values := re.Values
+		if values == nil {
+			values = make(map[string]int64)
+		}
+		ty.Elements[len(ty.Elements)] = &mqmetric.MonElement{
+			Parent:         ty,
+			Description:    re.Description,
+			DescriptionNLS: re.DescriptionNLS,
+			MetricName:     re.MetricName,
+			Datatype:       re.Datatype,
+			Values:         values,
+		}
+	}
+	r.published = all
+
+	r.status = make(map[int]*mqmetric.StatusSet)
+	for _, st := range statusTypes {
+		set := &mqmetric.StatusSet{Attributes: make(map[string]*mqmetric.StatusAttribute)}
+		for name, ra := range rec.Status[st.objectType] {
+			values := ra.Values
+			if values == nil {
+				values = make(map[string]*mqmetric.StatusValue)
+			}
+			set.Attributes[name] = &mqmetric.StatusAttribute{
+				Description: ra.Description,
+				MetricName:  ra.MetricName,
+				Pseudo:      ra.Pseudo,
+				Delta:       ra.Delta,
+				Values:      values,
+			}
+		}
+		r.status[st.ot] = set
+	}
+}
+
+func (r *replayer) publishedMetrics() *mqmetric.AllMetrics {
+	return r.published
+}
+
+func (r *replayer) objectStatus(ot int) *mqmetric.StatusSet {
+	if set, ok := r.status[ot]; ok {
+		return set
+	}
+	return &mqmetric.StatusSet{Attributes: make(map[string]*mqmetric.StatusAttribute)}
+}
+
+func (r *replayer) objectDescription(name string, ot int32) string {
+	if v, ok := r.current.Descriptions[lookupKey(ot, name)]; ok {
+		return v
+	}
+	return mqmetric.DUMMY_STRING
+}
+
+func (r *replayer) queueAttribute(name string, attr int32) string {
+	if v, ok := r.current.QueueAttributes[lookupKey(attr, name)]; ok {
+		return v
+	}
+	return mqmetric.DUMMY_STRING
+}
+
+func (r *replayer) publicationCount() int {
+	return r.current.PublicationCount
+}
+
+// Connecting to a recording just means opening the file
+func (c *Collector) connectReplay() error {
+	err := c.replay.open(c.QMgrName())
+	if err == nil {
+		rec := c.replay.current
+		c.updateConfig(func(cm *cf.Config) { cm.QMgrName = rec.QMgr })
+		log.Infof("Replaying data for queue manager %s from %s", rec.QMgr, c.replay.file)
+		c.setPlatformInfo(rec.Platform, rec.SupportsHostname)
+		c.first = false
+	}
+	c.connectDone(err)
+	return err
+}
+
+func (c *Collector) collectReplay() (*Batch, error) {
+	collectStartTime := time.Now()
+
+	rec, err := c.replay.next()
+	if err != nil {
+		return nil, err
+	}
+	c.qmgrDescription = rec.Description
+	c.hostname = rec.Hostname
+
+	ts := rec.Timestamp
+	if c.replay.loop {
+		ts = time.Now()
+	}
+	return c.buildBatch(ts, rec.StatusPolled, false, collectStartTime), nil
+}
+
+// ReplayFinished says whether this Collector has come to the end of its recording
+func (c *Collector) ReplayFinished() bool {
+	return c.replay != nil && c.replay.finished
+}
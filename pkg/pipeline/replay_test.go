@@ -0,0 +1,146 @@
+package pipeline_test
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+These tests feed the recording in testdata through the sinks, and compare what comes out
+with the saved output. The recording was made from a simulated queue manager with one
+queue, channel and NativeHA replica, and useObjectStatus set. Run "go test -update" to
+save new output after a deliberate change to the names, labels or values.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/sinks/jsonsink"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/sinks/promsink"
+)
+
+var update = flag.Bool("update", false, "Save the output as the new golden files")
+
+// Give each Batch from the recording to the sink. The exporter's own metrics, such as the
+// collection time, are different on every run so they are taken out first.
+func replay(t *testing.T, sink pipeline.Sink) {
+	t.Helper()
+
+	c := pipeline.NewCollector(&cf.Config{ReplayFile: filepath.Join("testdata", "recording.json")})
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Cannot start the replay: %v", err)
+	}
+
+	collections := 0
+	for {
+		b, err := c.Collect()
+		if err == pipeline.ErrReplayFinished {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Collection %d failed: %v", collections, err)
+		}
+		var points []pipeline.Point
+		for _, p := range b.Points {
+			if p.Source != pipeline.SourceExporter {
+				points = append(points, p)
+			}
+		}
+		b.Points = points
+		if err = sink.Write(b); err != nil {
+			t.Fatalf("Cannot write collection %d: %v", collections, err)
+		}
+		collections++
+	}
+	if collections != 2 {
+		t.Errorf("Replayed %d collections, expected 2", collections)
+	}
+}
+
+func compareGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	file := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(file, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output differs from %s. Run with -update to see the changes in git diff", file)
+	}
+}
+
+func TestReplayPrometheus(t *testing.T) {
+	sink := promsink.New("ibmmq", false)
+	replay(t, sink)
+
+	// Only the values from the last collection are scraped
+	w := httptest.NewRecorder()
+	sink.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	compareGolden(t, "recording.prom", w.Body.Bytes())
+}
+
+func TestReplayJSON(t *testing.T) {
+	var buf bytes.Buffer
+	replay(t, jsonsink.New(&buf, true, 0))
+
+	// The objects are written in the order the points arrived, which comes from the
+	// mqmetric maps. So they are sorted before comparing.
+	var out bytes.Buffer
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var report struct {
+			CollectionTime json.RawMessage   `json:"collectionTime"`
+			Points         []json.RawMessage `json:"points"`
+		}
+		if err := dec.Decode(&report); err != nil {
+			t.Fatalf("Cannot decode the JSON output: %v", err)
+		}
+		// Marshalling again puts the map keys in order, so each object can then be compared as a string
+		points := make([]string, len(report.Points))
+		for i, p := range report.Points {
+			var v interface{}
+			if err := json.Unmarshal(p, &v); err != nil {
+				t.Fatal(err)
+			}
+			b, _ := json.Marshal(v)
+			points[i] = string(b)
+		}
+		sort.Strings(points)
+
+		out.Write(report.CollectionTime)
+		out.WriteString("\n")
+		for _, p := range points {
+			out.WriteString(p)
+			out.WriteString("\n")
+		}
+	}
+	compareGolden(t, "recording.jsonl", out.Bytes())
+}
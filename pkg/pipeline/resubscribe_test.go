@@ -0,0 +1,104 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+A reload that changes the queues has mqmetric rediscover them with the new patterns. That
+subscribes to the queues that now match, and closes the subscriptions for the ones that
+don't. There's no queue manager here, so the test only sees what mqmetric is asked to do.
+*/
+
+import (
+	"testing"
+	"time"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+)
+
+func TestReconfigureResubscribes(t *testing.T) {
+	type call struct {
+		queues  bool
+		objects string
+	}
+	var calls []call
+	saved := resubscribe
+	resubscribe = func(c *Collector, queues bool) {
+		calls = append(calls, call{queues, c.discoverConfig.MonitoredQueues.ObjectNames})
+	}
+	defer func() { resubscribe = saved }()
+
+	c := NewCollector(&cf.Config{QMgrName: "QM1", MonitoredQueues: "APP.*", MonitoredChannels: "SYSTEM.*"})
+	c.discoverConfig.MonitoredQueues.ObjectNames = "APP.*"
+	c.connected.Store(true)
+
+	tests := []struct {
+		name string
+		nc   cf.Config
+		want *call // Nil when there should be no rediscovery
+	}{
+		{
+			name: "queues added",
+			nc:   cf.Config{QMgrName: "QM1", MonitoredQueues: "APP.*,SYSTEM.*", MonitoredChannels: "SYSTEM.*"},
+			want: &call{true, "APP.*,SYSTEM.*"},
+		},
+		{
+			name: "nothing changed",
+			nc:   cf.Config{QMgrName: "QM1", MonitoredQueues: "APP.*,SYSTEM.*", MonitoredChannels: "SYSTEM.*"},
+		},
+		{
+			name: "other setting changed",
+			nc:   cf.Config{QMgrName: "QM1", MonitoredQueues: "APP.*,SYSTEM.*", MonitoredChannels: "SYSTEM.*", PollIntervalDuration: time.Minute},
+		},
+		{
+			name: "queues dropped",
+			nc:   cf.Config{QMgrName: "QM1", MonitoredQueues: "APP.*", MonitoredChannels: "SYSTEM.*", PollIntervalDuration: time.Minute},
+			want: &call{true, "APP.*"},
+		},
+		{
+			name: "only channels changed",
+			nc:   cf.Config{QMgrName: "QM1", MonitoredQueues: "APP.*", MonitoredChannels: "TO.*", PollIntervalDuration: time.Minute},
+			want: &call{false, "APP.*"},
+		},
+	}
+
+	for _, tt := range tests {
+		calls = nil
+		nc := tt.nc
+		c.Reconfigure(&nc)
+		switch {
+		case tt.want == nil && len(calls) != 0:
+			t.Errorf("%s: unexpected rediscovery %+v", tt.name, calls)
+		case tt.want != nil && len(calls) != 1:
+			t.Errorf("%s: %d rediscoveries, expected 1", tt.name, len(calls))
+		case tt.want != nil && calls[0] != *tt.want:
+			t.Errorf("%s: rediscovery was %+v, expected %+v", tt.name, calls[0], *tt.want)
+		}
+	}
+
+	// Without a connection, the new patterns are only used when connecting
+	calls = nil
+	c.connected.Store(false)
+	c.Reconfigure(&cf.Config{QMgrName: "QM1", MonitoredQueues: "OTHER.*", MonitoredChannels: "TO.*", PollIntervalDuration: time.Minute})
+	if len(calls) != 0 {
+		t.Errorf("Rediscovery while disconnected")
+	}
+	if c.discoverConfig.MonitoredQueues.ObjectNames != "OTHER.*" {
+		t.Errorf("New queue patterns were not saved for the next connection")
+	}
+}
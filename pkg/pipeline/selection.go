@@ -0,0 +1,149 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+A Selection narrows a collection down to some of the object types, and to the objects whose
+names match a set of patterns. It's used when a Prometheus scrape asks for only part of the
+data, so that a job that wants the queues every few seconds does not also cause the cluster
+and topic status to be polled, and a slower job can ask for those on its own.
+
+Only the status of the selected object types is polled, whether or not their tiers are due.
+The publications are only processed when one of the types that they cover (the queue
+manager, queues and NativeHA instances) is selected. Otherwise they wait for the next
+collection that does want them, so nothing is lost.
+
+The name patterns come from an unauthenticated request, so they are never given to the queue
+manager as they are. They pick out which of the monitored queues have their status inquired,
+by name, and which of the objects are reported. The other object types are always polled in
+full, as mqmetric works out the differences for all of a type's objects at once.
+*/
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+)
+
+// Selection is made for a single request, and is not safe for concurrent use
+type Selection struct {
+	objectTypes map[string]bool // Empty for all of them
+	names       string          // Patterns in the same form as the monitored queues
+	matched     map[string]bool
+}
+
+// Which label holds the name of each type of object
+var objectNameLabels = map[string]string{
+	ObjectQueue:        "queue",
+	ObjectNHA:          "nha",
+	ObjectChannel:      "channel",
+	ObjectTopic:        "topic",
+	ObjectSubscription: "subscription",
+	ObjectCluster:      "cluster",
+	ObjectBufferPool:   "bufferpool",
+	ObjectPageSet:      "pageset",
+	ObjectAMQP:         "channel",
+	ObjectMQTT:         "channel",
+}
+
+/*
+NewSelection makes a Selection from the object types, which are the names that the metrics
+start with such as "queue" or "channel", and the name patterns. Either can be empty, and then
+does not restrict anything.
+*/
+func NewSelection(objectTypes []string, names []string) (*Selection, error) {
+	s := &Selection{
+		objectTypes: make(map[string]bool),
+		matched:     make(map[string]bool),
+	}
+
+	for _, ot := range objectTypes {
+		ot = strings.ToLower(strings.TrimSpace(ot))
+		if ot == "" {
+			continue
+		}
+		if _, ok := objectNameLabels[ot]; !ok && ot != ObjectQMgr {
+			return nil, fmt.Errorf("unknown object type '%s'", ot)
+		}
+		s.objectTypes[ot] = true
+	}
+
+	var patterns []string
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			patterns = append(patterns, n)
+		}
+	}
+	s.names = strings.Join(patterns, ",")
+	return s, nil
+}
+
+func (s *Selection) includes(objectType string) bool {
+	return len(s.objectTypes) == 0 || s.objectTypes[objectType]
+}
+
+// The publications cover the queue manager, queues and NativeHA instances
+func (s *Selection) publications() bool {
+	return s.includes(ObjectQMgr) || s.includes(ObjectQueue) || s.includes(ObjectNHA)
+}
+
+// Whether a tier's object type is wanted. The buffer pools and pagesets share a tier.
+func (s *Selection) polls(tierType string) bool {
+	if tierType == objectUsage {
+		return s.includes(ObjectBufferPool) || s.includes(ObjectPageSet)
+	}
+	return s.includes(tierType)
+}
+
+// The queues are only polled by name when there are some patterns
+func (s *Selection) namedQueues() bool {
+	return s.names != "" && s.includes(ObjectQueue)
+}
+
+func (s *Selection) matchesName(name string) bool {
+	if s.names == "" || name == "" {
+		return true
+	}
+	m, ok := s.matched[name]
+	if !ok {
+		m = len(mqmetric.FilterRegExp(s.names, []string{name})) > 0
+		s.matched[name] = m
+	}
+	return m
+}
+
+/*
+Matches says whether a point should be reported. The collector's own metrics always are. The
+points for the queue manager itself have no name to compare, so the patterns don't apply to
+them. Nor do they to a point whose name label has been removed by the relabelling rules.
+*/
+func (s *Selection) Matches(p *Point) bool {
+	if p.Source == SourceExporter {
+		return true
+	}
+	if !s.includes(p.ObjectType) {
+		return false
+	}
+	label, ok := objectNameLabels[p.ObjectType]
+	if !ok {
+		return true
+	}
+	return s.matchesName(p.Labels[label])
+}
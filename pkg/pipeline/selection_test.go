@@ -0,0 +1,196 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+)
+
+func TestSelectionMatches(t *testing.T) {
+	queue := func(name string) *Point {
+		return &Point{ObjectType: ObjectQueue, Source: SourceStatus, Labels: map[string]string{"queue": name}}
+	}
+	channel := &Point{ObjectType: ObjectChannel, Source: SourceStatus, Labels: map[string]string{"channel": "TO.QM2"}}
+	amqp := &Point{ObjectType: ObjectAMQP, Source: SourceStatus, Labels: map[string]string{"channel": "AMQP.APP"}}
+	qmgr := &Point{ObjectType: ObjectQMgr, Source: SourcePublication, Labels: map[string]string{"qmgr": "QM1"}}
+	exporter := &Point{ObjectType: ObjectQMgr, Source: SourceExporter, Labels: map[string]string{}}
+	relabelled := &Point{ObjectType: ObjectQueue, Source: SourceStatus, Labels: map[string]string{}}
+
+	tests := []struct {
+		name    string
+		types   []string
+		names   []string
+		matches []*Point
+		misses  []*Point
+	}{
+		{
+			name:    "everything",
+			matches: []*Point{queue("APP.Q"), channel, amqp, qmgr, exporter},
+		},
+		{
+			name:    "object types",
+			types:   []string{" Queue", "", "qmgr"},
+			matches: []*Point{queue("APP.Q"), qmgr, exporter},
+			misses:  []*Point{channel, amqp},
+		},
+		{
+			name:    "names",
+			names:   []string{"APP.*", " ", "!APP.X,TO.*"},
+			matches: []*Point{queue("APP.Q"), channel, qmgr, exporter, relabelled},
+			misses:  []*Point{queue("APP.X"), queue("SYSTEM.Q"), amqp},
+		},
+		{
+			name:    "object types and names",
+			types:   []string{"queue"},
+			names:   []string{"APP.*"},
+			matches: []*Point{queue("APP.Q"), exporter},
+			misses:  []*Point{queue("SYSTEM.Q"), channel, qmgr},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSelection(tt.types, tt.names)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The names are remembered, so ask twice
+			for i := 0; i < 2; i++ {
+				for _, p := range tt.matches {
+					if !s.Matches(p) {
+						t.Errorf("%s %v was not selected", p.ObjectType, p.Labels)
+					}
+				}
+				for _, p := range tt.misses {
+					if s.Matches(p) {
+						t.Errorf("%s %v was selected", p.ObjectType, p.Labels)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSelectionInvalid(t *testing.T) {
+	_, err := NewSelection([]string{"queue", "listener"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown object type 'listener'") {
+		t.Errorf("Expected an unknown object type error, got %v", err)
+	}
+}
+
+func TestSelectionPolling(t *testing.T) {
+	tests := []struct {
+		types        []string
+		names        []string
+		publications bool
+		namedQueues  bool
+		polls        []string
+		notPolls     []string
+	}{
+		{
+			publications: true,
+			polls:        []string{ObjectQueue, ObjectChannel, objectUsage},
+		},
+		{
+			types:        []string{"channel"},
+			names:        []string{"TO.*"},
+			publications: false,
+			polls:        []string{ObjectChannel},
+			notPolls:     []string{ObjectQueue, ObjectTopic, objectUsage},
+		},
+		{
+			types:        []string{"nha"},
+			publications: true,
+			notPolls:     []string{ObjectQueue, ObjectQMgr},
+		},
+		{
+			types:        []string{"pageset"},
+			publications: false,
+			polls:        []string{objectUsage},
+		},
+		{
+			names:        []string{"APP.*"},
+			publications: true,
+			namedQueues:  true,
+			polls:        []string{ObjectQueue, ObjectCluster},
+		},
+	}
+
+	for _, tt := range tests {
+		s, err := NewSelection(tt.types, tt.names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.publications() != tt.publications {
+			t.Errorf("%v %v: publications is %v", tt.types, tt.names, s.publications())
+		}
+		if s.namedQueues() != tt.namedQueues {
+			t.Errorf("%v %v: namedQueues is %v", tt.types, tt.names, s.namedQueues())
+		}
+		for _, ot := range tt.polls {
+			if !s.polls(ot) {
+				t.Errorf("%v %v: %s is not polled", tt.types, tt.names, ot)
+			}
+		}
+		for _, ot := range tt.notPolls {
+			if s.polls(ot) {
+				t.Errorf("%v %v: %s is polled", tt.types, tt.names, ot)
+			}
+		}
+	}
+}
+
+// The selected types are polled whether or not their tiers are due, and nothing else is.
+// Queues that are wanted by name are polled separately from their tiers.
+func TestSelectTiers(t *testing.T) {
+	c := tierTestCollector()
+	c.updateConfig(func(cm *cf.Config) { cm.CC.UseStatus = true })
+	now := time.Now()
+	c.dueTiers(now)
+	c.first = false
+
+	s, _ := NewSelection([]string{"channel"}, nil)
+	if !c.selectTiers(s, now) {
+		t.Error("Nothing is polled for the channels")
+	}
+	for _, tier := range c.tiers {
+		if tier.due != (tier.objectType == ObjectChannel) {
+			t.Errorf("%s tier %s due is %v", tier.objectType, tier.patterns, tier.due)
+		}
+	}
+
+	s, _ = NewSelection([]string{"queue"}, []string{"PAY.*"})
+	if !c.selectTiers(s, now) {
+		t.Error("Nothing is polled for the named queues")
+	}
+	for _, tier := range c.tiers {
+		if tier.due {
+			t.Errorf("%s tier %s is due", tier.objectType, tier.patterns)
+		}
+	}
+
+	s, _ = NewSelection([]string{"nha"}, nil)
+	if c.selectTiers(s, now) {
+		t.Error("Something is polled for the NativeHA instances")
+	}
+}
@@ -0,0 +1,271 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This file keeps the statistics about the collector itself, so that they can be sent to
+the same backend as the queue manager data. That makes it possible to alert when the
+monitoring is degrading.
+
+The values are totals since the collector started, so they are reported as Gauges even
+though they only ever go up; they do not need to be added together like the per-interval
+Counters. The time taken for each phase of the collection is kept as a histogram, with
+the same "_bucket", "_sum" and "_count" series that Prometheus uses.
+*/
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The phases of a collection that are timed. The status polling for each object type
+// is a separate phase, named for the object type followed by "_status".
+const (
+	PhasePublications = "publications"
+	PhaseDiscovery    = "discovery"
+	PhaseWrite        = "write"
+)
+
+// Upper bounds, in seconds, for the histogram buckets
+var phaseBuckets = []float64{0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30}
+
+type histogram struct {
+	counts []uint64 // Not cumulative; that's done when they are reported
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(phaseBuckets))
+	}
+	for i, b := range phaseBuckets {
+		if v <= b {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Per-output counts of write problems
+type writeStats struct {
+	failures uint64
+	retries  uint64
+	failing  bool
+}
+
+/*
+selfStats belongs to a Collector. Writes to the backends are recorded by the caller after
+the Batch has been returned, so they don't happen under the mqmetric lock and need their own.
+*/
+type selfStats struct {
+	mutex        sync.Mutex
+	phases       map[string]*histogram
+	statusErrors uint64
+	reconnects   uint64
+	writes       map[string]*writeStats
+
+	// For the health checks
+	collectStart   time.Time // Zero when no collection is running
+	lastCollection time.Time
+	lastError      string
+}
+
+func newSelfStats() *selfStats {
+	return &selfStats{
+		phases: make(map[string]*histogram),
+		writes: make(map[string]*writeStats),
+	}
+}
+
+func (s *selfStats) observe(phase string, d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	h, ok := s.phases[phase]
+	if !ok {
+		h = &histogram{}
+		s.phases[phase] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// Time a phase of the collection
+func (s *selfStats) timed(phase string, f func() error) error {
+	start := time.Now()
+	err := f()
+	s.observe(phase, time.Since(start))
+	return err
+}
+
+func (s *selfStats) statusError() {
+	s.mutex.Lock()
+	s.statusErrors++
+	s.mutex.Unlock()
+}
+
+func (s *selfStats) reconnected() {
+	s.mutex.Lock()
+	s.reconnects++
+	s.mutex.Unlock()
+}
+
+func (s *selfStats) startCollection() {
+	s.mutex.Lock()
+	s.collectStart = time.Now()
+	s.mutex.Unlock()
+}
+
+// A collection that returned some data counts as having worked, even if there was an error
+// from one of the status requests. The error is still kept for reporting.
+func (s *selfStats) endCollection(ok bool, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.collectStart = time.Time{}
+	if ok {
+		s.lastCollection = time.Now()
+	}
+	if err != nil {
+		s.lastError = err.Error()
+	} else {
+		s.lastError = ""
+	}
+}
+
+// CollectorHealth is a snapshot of what the health checks need to know about a Collector
+type CollectorHealth struct {
+	QMgr           string
+	Connected      bool
+	Collecting     time.Time // When the current collection started; zero if there isn't one
+	LastCollection time.Time // When the last collection that returned data finished
+	LastError      string
+	FailingOutputs []string // Outputs where the last write failed
+}
+
+// Health returns the current state of the Collector
+func (c *Collector) Health() CollectorHealth {
+	s := c.stats
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	h := CollectorHealth{
+		QMgr:           c.QMgrName(),
+		Connected:      c.Connected(),
+		Collecting:     s.collectStart,
+		LastCollection: s.lastCollection,
+		LastError:      s.lastError,
+	}
+	for output, w := range s.writes {
+		if w.failing {
+			h.FailingOutputs = append(h.FailingOutputs, output)
+		}
+	}
+	sort.Strings(h.FailingOutputs)
+	return h
+}
+
+/*
+RecordWrite notes how long a write of this Collector's data to a backend took, and whether
+it worked. A write that follows a failed one, to the same output, is counted as a retry.
+*/
+func (c *Collector) RecordWrite(output string, d time.Duration, err error) {
+	s := c.stats
+	s.observe(PhaseWrite, d)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	w, ok := s.writes[output]
+	if !ok {
+		w = &writeStats{}
+		s.writes[output] = w
+	}
+	if w.failing {
+		w.retries++
+	}
+	if err != nil {
+		w.failures++
+	}
+	w.failing = err != nil
+}
+
+// Add the points describing the collector to the batch. This is done at the end of
+// a collection, so the count of series covers everything else in the batch.
+func (c *Collector) addSelfPoints(b *Batch) {
+	series := make(map[string]int)
+	for _, p := range b.Points {
+		if p.Source != SourceExporter {
+			series[p.ObjectType]++
+		}
+	}
+
+	s := c.stats
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	add := func(metric string, description string, extra map[string]string, v float64) {
+		labels := c.newLabels()
+		for k, lv := range extra {
+			labels[k] = lv
+		}
+		b.add(Point{
+			Metric:      metric,
+			ObjectType:  ObjectQMgr,
+			Description: description,
+			Labels:      labels,
+			Value:       v,
+			Source:      SourceExporter,
+		})
+	}
+
+	phases := make([]string, 0, len(s.phases))
+	for phase := range s.phases {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+	for _, phase := range phases {
+		h := s.phases[phase]
+		cumulative := uint64(0)
+		for i, upper := range phaseBuckets {
+			cumulative += h.counts[i]
+			add("exporter_phase_seconds_bucket", "Time taken by a phase of the collection",
+				map[string]string{"phase": phase, "le": strconv.FormatFloat(upper, 'g', -1, 64)}, float64(cumulative))
+		}
+		add("exporter_phase_seconds_bucket", "Time taken by a phase of the collection",
+			map[string]string{"phase": phase, "le": "+Inf"}, float64(h.count))
+		add("exporter_phase_seconds_sum", "Total time taken by a phase of the collection",
+			map[string]string{"phase": phase}, h.sum)
+		add("exporter_phase_seconds_count", "Number of times a phase of the collection has run",
+			map[string]string{"phase": phase}, float64(h.count))
+	}
+
+	add("exporter_status_errors_total", "Errors from polling for object status", nil, float64(s.statusErrors))
+	add("exporter_reconnects_total", "Reconnections to the queue manager", nil, float64(s.reconnects))
+
+	for output, w := range s.writes {
+		add("exporter_write_failures_total", "Failed writes to the backend", map[string]string{"output": output}, float64(w.failures))
+		add("exporter_write_retries_total", "Writes to the backend after a failed one", map[string]string{"output": output}, float64(w.retries))
+	}
+
+	for objectType, n := range series {
+		add("exporter_series", "Number of series reported for an object type", map[string]string{"object": objectType}, float64(n))
+	}
+}
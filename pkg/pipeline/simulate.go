@@ -0,0 +1,999 @@
+package pipeline
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This file makes up data for a queue manager that doesn't exist, so that dashboards can be
+built, and backends tried out, without needing a real one. It is another source for the
+Collector, in the same way as a replay, and it fills in the same mqmetric structures that a
+connection would: the published resource metrics and the object status attributes.
+
+There is a configurable number of queues, channels and NativeHA replicas. Queue depths take
+a random walk, driven by the puts and gets in each interval. Channels spend most of their
+time running, but now and again drop into retrying and work their way back. Most other values
+wander around a range that suits their units, and the counters report a random amount of
+activity for each interval. None of it is meant to be consistent beyond looking reasonable
+on a graph.
+
+The published metrics are the ones listed in mqmetric's metrics.txt, and get the same names
+as they would from a real queue manager.
+*/
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-golang/v5/ibmmq"
+	"github.com/ibm-messaging/mq-golang/v5/mqmetric"
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	simulatedQMgr       = "SIMQM1"
+	simulatedRemoteQMgr = "SIMQM2"
+	simulatedCluster    = "SIMCLUS"
+	simulatedTopic      = "sim/prices"
+	simulatedMaxDepth   = 5000
+)
+
+// Which objects a type of published metric has values for
+const (
+	simQMgr = iota
+	simQueue
+	simNHA
+)
+
+type simulatedType struct {
+	class    string
+	name     string
+	object   int
+	elements []simulatedElement
+}
+
+type simulatedElement struct {
+	description string
+	datatype    int32
+}
+
+// Most of the API statistics are simple counts for the interval
+func counts(descriptions ...string) []simulatedElement {
+	var elements []simulatedElement
+	for _, d := range descriptions {
+		elements = append(elements, simulatedElement{d, ibmmq.MQIAMO_MONITOR_DELTA})
+	}
+	return elements
+}
+
+var simulatedTypes = []simulatedType{
+	{"CPU", "QMgrSummary", simQMgr, []simulatedElement{
+		{"RAM total bytes - estimate for queue manager", ibmmq.MQIAMO_MONITOR_MB},
+		{"System CPU time - percentage estimate for queue manager", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"User CPU time - percentage estimate for queue manager", ibmmq.MQIAMO_MONITOR_PERCENT},
+	}},
+	{"CPU", "SystemSummary", simQMgr, []simulatedElement{
+		{"CPU load - fifteen minute average", ibmmq.MQIAMO_MONITOR_HUNDREDTHS},
+		{"CPU load - one minute average", ibmmq.MQIAMO_MONITOR_HUNDREDTHS},
+		{"CPU load - five minute average", ibmmq.MQIAMO_MONITOR_HUNDREDTHS},
+		{"RAM total bytes", ibmmq.MQIAMO_MONITOR_MB},
+		{"RAM free percentage", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"System CPU time percentage", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"User CPU time percentage", ibmmq.MQIAMO_MONITOR_PERCENT},
+	}},
+	{"DISK", "Log", simQMgr, []simulatedElement{
+		{"Log - bytes occupied by extents waiting to be archived", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Log - bytes in use", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Log - bytes max", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Log - quorum log sequence number", ibmmq.MQIAMO_MONITOR_LSN},
+		{"Log file system - free space", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"Log file system - bytes in use", ibmmq.MQIAMO_MONITOR_MB},
+		{"Log file system - bytes max", ibmmq.MQIAMO_MONITOR_MB},
+		{"Log - disk written log sequence number", ibmmq.MQIAMO_MONITOR_LSN},
+		{"Log - write latency", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Log - logical bytes written", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Log - bytes required for media recovery", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Log - physical bytes written", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Log - bytes occupied by reusable extents", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Log - slowest write since restart", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Log - timestamp of slowest write", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Log - current primary space in use", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"Log - workload primary space utilization", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"Log - write size", ibmmq.MQIAMO_MONITOR_UNIT},
+	}},
+	{"DISK", "QMgrSummary", simQMgr, []simulatedElement{
+		{"Queue Manager file system - free space", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"Queue Manager file system - bytes in use", ibmmq.MQIAMO_MONITOR_MB},
+	}},
+	{"DISK", "SystemSummary", simQMgr, []simulatedElement{
+		{"Appliance data - free space", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"Appliance data - bytes in use", ibmmq.MQIAMO_MONITOR_MB},
+		{"System volume - free space", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"System volume - bytes in use", ibmmq.MQIAMO_MONITOR_MB},
+		{"MQ FDC file count", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"MQ errors file system - free space", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"MQ errors file system - bytes in use", ibmmq.MQIAMO_MONITOR_MB},
+		{"MQ trace file system - free space", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"MQ trace file system - bytes in use", ibmmq.MQIAMO_MONITOR_MB},
+	}},
+	{"NHAREPLICA", "RECOVERY", simNHA, []simulatedElement{
+		{"Backlog average bytes", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Backlog bytes", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Compressed log bytes sent", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Log data average compression time", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Log bytes decompressed", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Log data average decompression time", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Average network round trip time", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Rebase count", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Recovery log sequence number", ibmmq.MQIAMO_MONITOR_LSN},
+		{"Log bytes sent", ibmmq.MQIAMO_MONITOR_DELTA},
+	}},
+	{"NHAREPLICA", "REPLICATION", simNHA, []simulatedElement{
+		{"Log write average acknowledgement latency", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Acknowledged log sequence number", ibmmq.MQIAMO_MONITOR_LSN},
+		{"Log write average acknowledgement size", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Backlog average bytes", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Backlog bytes", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Catch-up log bytes sent", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Catch-up compressed log bytes sent", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Catch-up log data average compression time", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Catch-up log bytes decompressed", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Catch-up log data average decompression time", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Catch-up uncompressed log bytes sent", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"MQ FDC file count", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Log file system - free space", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"Log file system - bytes in use", ibmmq.MQIAMO_MONITOR_MB},
+		{"Average network round trip time", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Queue Manager file system - free space", ibmmq.MQIAMO_MONITOR_PERCENT},
+		{"Queue Manager file system - bytes in use", ibmmq.MQIAMO_MONITOR_MB},
+		{"Synchronous log bytes sent", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Synchronous compressed log bytes sent", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Synchronous log data average compression time", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Synchronous log bytes decompressed", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"Synchronous log data average decompression time", ibmmq.MQIAMO_MONITOR_MICROSEC},
+		{"Synchronous uncompressed log bytes sent", ibmmq.MQIAMO_MONITOR_DELTA},
+	}},
+	{"STATMQI", "CONNDISC", simQMgr, append(counts(
+		"Failed MQCONN/MQCONNX count",
+		"MQCONN/MQCONNX count",
+		"MQDISC count"),
+		simulatedElement{"Concurrent connections - high water mark", ibmmq.MQIAMO_MONITOR_UNIT})},
+	{"STATMQI", "GET", simQMgr, counts(
+		"Non-persistent message browse - byte count",
+		"Persistent message browse - byte count",
+		"Failed browse count",
+		"Non-persistent message browse - count",
+		"Persistent message browse - count",
+		"Interval total destructive get - byte count",
+		"Got non-persistent messages - byte count",
+		"Got persistent messages - byte count",
+		"Failed MQCB count",
+		"MQCB count",
+		"MQCTL count",
+		"Failed MQGET - count",
+		"Interval total destructive get- count",
+		"Non-persistent message destructive get - count",
+		"Persistent message destructive get - count",
+		"Expired message count",
+		"Purged queue count")},
+	{"STATMQI", "INQSET", simQMgr, counts(
+		"Failed MQINQ count",
+		"MQINQ count",
+		"Failed MQSET count",
+		"MQSET count")},
+	{"STATMQI", "OPENCLOSE", simQMgr, counts(
+		"Failed MQCLOSE count",
+		"MQCLOSE count",
+		"Failed MQOPEN count",
+		"MQOPEN count")},
+	{"STATMQI", "PUBLISH", simQMgr, counts(
+		"Published to subscribers - byte count",
+		"Published to subscribers - message count",
+		"Interval total topic bytes put",
+		"Failed topic MQPUT/MQPUT1 count",
+		"Non-persistent - topic MQPUT/MQPUT1 count",
+		"Persistent - topic MQPUT/MQPUT1 count",
+		"Topic MQPUT/MQPUT1 interval total")},
+	{"STATMQI", "PUT", simQMgr, counts(
+		"Interval total MQPUT/MQPUT1 byte count",
+		"Put non-persistent messages - byte count",
+		"Put persistent messages - byte count",
+		"Failed MQPUT1 count",
+		"Non-persistent message MQPUT1 count",
+		"Persistent message MQPUT1 count",
+		"Failed MQPUT count",
+		"Interval total MQPUT/MQPUT1 count",
+		"Non-persistent message MQPUT count",
+		"Persistent message MQPUT count",
+		"MQSTAT count")},
+	{"STATMQI", "SUBSCRIBE", simQMgr, append([]simulatedElement{
+		{"Durable subscriber - high water mark", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Durable subscriber - low water mark", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Non-durable subscriber - high water mark", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Non-durable subscriber - low water mark", ibmmq.MQIAMO_MONITOR_UNIT}},
+		counts(
+			"Failed MQSUBRQ count",
+			"MQSUBRQ count",
+			"Alter durable subscription count",
+			"Create durable subscription count",
+			"Resume durable subscription count",
+			"Failed create/alter/resume subscription count",
+			"Create non-durable subscription count",
+			"Delete durable subscription count",
+			"Subscription delete failure count",
+			"Delete non-durable subscription count")...)},
+	{"STATMQI", "SYNCPOINT", simQMgr, counts(
+		"Rollback count",
+		"Commit count")},
+	{"STATQ", "EXTENDED", simQueue, counts(
+		"correlid mismatch long count",
+		"correlid mismatch short count",
+		"intran get skipped count",
+		"intran put skipped count",
+		"load msg dtl count",
+		"msg examine count",
+		"msg search count",
+		"msgid mismatch count",
+		"msg not found count",
+		"selection mismatch count")},
+	{"STATQ", "GENERAL", simQueue, []simulatedElement{
+		{"messages expired", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"open browse count", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"open input count", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"open output count", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"open publish count", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"Queue depth", ibmmq.MQIAMO_MONITOR_UNIT},
+		{"queue purged count", ibmmq.MQIAMO_MONITOR_DELTA},
+		{"average queue time", ibmmq.MQIAMO_MONITOR_MICROSEC},
+	}},
+	{"STATQ", "GET", simQueue, counts(
+		"MQGET browse non-persistent byte count",
+		"MQGET browse persistent byte count",
+		"MQGET browse fails",
+		"MQGET browse fails with MQRC_NO_MSG_AVAILABLE",
+		"MQGET browse non-persistent message count",
+		"MQGET browse persistent message count",
+		"MQGET browse fails with MQRC_TRUNCATED_MSG_FAILED",
+		"MQGET byte count",
+		"destructive MQGET non-persistent byte count",
+		"destructive MQGET persistent byte count",
+		"destructive MQGET fails",
+		"MQGET count",
+		"destructive MQGET non-persistent message count",
+		"destructive MQGET persistent message count",
+		"destructive MQGET fails with MQRC_NO_MSG_AVAILABLE",
+		"rolled back MQGET count",
+		"destructive MQGET fails with MQRC_TRUNCATED_MSG_FAILED")},
+	{"STATQ", "INQSET", simQueue, counts(
+		"MQINQ count",
+		"MQSET count")},
+	{"STATQ", "OPENCLOSE", simQueue, counts(
+		"MQCLOSE count",
+		"MQOPEN count")},
+	{"STATQ", "PUT", simQueue, append(counts(
+		"queue avoided bytes",
+		"queue avoided puts",
+		"MQPUT byte count",
+		"non-persistent byte count",
+		"persistent byte count",
+		"MQPUT1 non-persistent message count",
+		"MQPUT1 persistent message count",
+		"MQPUT/MQPUT1 count",
+		"MQPUT non-persistent message count",
+		"MQPUT persistent message count",
+		"rolled back MQPUT count"),
+		simulatedElement{"lock contention", ibmmq.MQIAMO_MONITOR_PERCENT})},
+}
+
+type simulatedQueue struct {
+	name     string
+	usage    int32
+	cluster  string
+	custom   string
+	activity int64 // Most messages put or got in an interval
+	depth    int64
+	puts     int64
+	gets     int64
+	lastPut  time.Time
+	lastGet  time.Time
+}
+
+type simulatedChannel struct {
+	name     string
+	chlType  int32
+	connName string
+	rqmName  string
+	jobName  string
+	status   int32
+	started  time.Time
+	lastMsg  time.Time
+}
+
+// The same key that mqmetric uses for a channel instance
+func (ch *simulatedChannel) key() string {
+	return ch.name + "/" + ch.connName + "/" + ch.rqmName + "/" + ch.jobName
+}
+
+type simulator struct {
+	cf       *cf.Config
+	rnd      *rand.Rand
+	qmgr     string
+	hostname string
+	started  time.Time
+
+	queues   []*simulatedQueue
+	channels []*simulatedChannel
+	replicas []string
+	topicPub time.Time
+	topicSub time.Time
+
+	walks        map[string]float64
+	lsn          int64
+	publications int
+
+	published *mqmetric.AllMetrics
+	status    map[int]*mqmetric.StatusSet
+}
+
+func newSimulator(cm *cf.Config) *simulator {
+	s := &simulator{
+		cf:    cm,
+		rnd:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		walks: make(map[string]float64),
+	}
+
+	s.hostname, _ = os.Hostname()
+	if s.hostname == "" {
+		s.hostname = "localhost"
+	}
+
+	// The last queue is used as the transmission queue for the sender channels
+	for i := 1; i <= cm.SimulateQueues; i++ {
+		q := &simulatedQueue{
+			name:     fmt.Sprintf("SIM.QUEUE.%02d", i),
+			usage:    ibmmq.MQUS_NORMAL,
+			custom:   fmt.Sprintf("APP(SIMAPP%d)", i%2+1),
+			activity: int64(10 + s.rnd.Intn(200)),
+		}
+		if i%3 == 0 {
+			q.cluster = simulatedCluster
+		}
+		if i == cm.SimulateQueues && i > 1 {
+			q.name = "SIM.XMITQ"
+			q.usage = ibmmq.MQUS_TRANSMISSION
+			q.cluster = ""
+			q.custom = ""
+		}
+		s.queues = append(s.queues, q)
+	}
+
+	// Alternate between client connections and channels to another queue manager
+	for i := 1; i <= cm.SimulateChannels; i++ {
+		ch := &simulatedChannel{
+			jobName: fmt.Sprintf("%016X", s.rnd.Int63()),
+			status:  ibmmq.MQCHS_RUNNING,
+		}
+		if i%2 == 1 {
+			ch.name = fmt.Sprintf("SIM.SVRCONN.%02d", i)
+			ch.chlType = ibmmq.MQCHT_SVRCONN
+			ch.connName = fmt.Sprintf("10.0.0.%d", i)
+		} else {
+			ch.name = fmt.Sprintf("SIM.TO.%s.%02d", simulatedRemoteQMgr, i)
+			ch.chlType = ibmmq.MQCHT_SENDER
+			ch.connName = strings.ToLower(simulatedRemoteQMgr) + ".example.com(1414)"
+			ch.rqmName = simulatedRemoteQMgr
+		}
+		s.channels = append(s.channels, ch)
+	}
+
+	for i := 1; i <= cm.SimulateReplicas; i++ {
+		s.replicas = append(s.replicas, fmt.Sprintf("sim-replica-%d", i))
+	}
+
+	return s
+}
+
+/*
+Start the simulation for a queue manager, building the structures that describe the
+metrics. Restarting, as happens when reconnecting, begins again from new values.
+*/
+func (s *simulator) start(qmgr string) {
+	s.qmgr = qmgr
+	s.started = time.Now()
+	s.walks = make(map[string]float64)
+	s.lsn = s.rnd.Int63n(1 << 40)
+	for _, q := range s.queues {
+		q.depth = s.rnd.Int63n(simulatedMaxDepth / 10)
+		q.lastPut = s.started
+		q.lastGet = s.started
+	}
+	for _, ch := range s.channels {
+		ch.status = ibmmq.MQCHS_RUNNING
+		ch.started = s.started
+		ch.lastMsg = s.started
+	}
+	s.topicPub = s.started
+	s.topicSub = s.started
+
+	s.buildPublished()
+	s.buildStatus()
+}
+
+func (s *simulator) buildPublished() {
+	all := &mqmetric.AllMetrics{Classes: make(map[int]*mqmetric.MonClass)}
+	s.published = all
+	if !s.cf.CC.UsePublications {
+		return
+	}
+
+	prefix := "$SYS/MQ/INFO/QMGR/" + s.qmgr + "/Monitor/"
+	classes := make(map[string]*mqmetric.MonClass)
+	for _, st := range simulatedTypes {
+		if (st.object == simQueue && len(s.queues) == 0) || (st.object == simNHA && len(s.replicas) == 0) {
+			continue
+		}
+		cl, ok := classes[st.class]
+		if !ok {
+			cl = &mqmetric.MonClass{Parent: all, Name: st.class, Types: make(map[int]*mqmetric.MonType)}
+			all.Classes[len(all.Classes)] = cl
+			classes[st.class] = cl
+		}
+
+		// The topics have the same shape as the real ones, which is what tells the collectors
+		// that the values are for a queue or a replica rather than the queue manager
+		topic := prefix + st.class + "/" + st.name
+		switch st.object {
+		case simQueue:
+			topic = prefix + st.class + "/%s/" + st.name
+		case simNHA:
+			topic = prefix + st.class + "/" + st.name + "/%s"
+		}
+		ty := &mqmetric.MonType{Parent: cl, Name: st.name, ObjectTopic: topic, Elements: make(map[int]*mqmetric.MonElement)}
+		cl.Types[len(cl.Types)] = ty
+
+		for _, se := range st.elements {
+			elem := &mqmetric.MonElement{
+				Parent:      ty,
+				Description: se.description,
+				Datatype:    se.datatype,
+				Values:      make(map[string]int64),
+			}
+			// As mqmetric does, keep the recovery metrics apart from the replication ones
+			elem.MetricName = mqmetric.FormatDescriptionHeuristic(elem, true)
+			if st.name == "RECOVERY" && !strings.HasPrefix(elem.MetricName, "recovery") {
+				elem.MetricName = "recovery_" + elem.MetricName
+			}
+			ty.Elements[len(ty.Elements)] = elem
+		}
+	}
+}
+
+// The status attributes follow the mqmetric InitAttributes functions for a Distributed queue manager
+func (s *simulator) buildStatus() {
+	s.status = make(map[int]*mqmetric.StatusSet)
+	for _, st := range statusTypes {
+		s.status[st.ot] = &mqmetric.StatusSet{Attributes: make(map[string]*mqmetric.StatusAttribute)}
+	}
+
+	set := s.status[mqmetric.OT_Q]
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_NAME, "Queue Name").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_SINCE_PUT, "Time Since Put")
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_SINCE_GET, "Time Since Get")
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_MSGAGE, "Oldest Message")
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_IPPROCS, "Input Handles")
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_OPPROCS, "Output Handles")
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_UNCOM, "Uncommitted Messages (Count)")
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_CURFSIZE, "Queue File Current Size")
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_CURMAXFSIZE, "Queue File Maximum Size")
+	if !s.cf.CC.UsePublications {
+		addSimulatedAttribute(set, mqmetric.ATTR_Q_DEPTH, "Queue depth")
+	}
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_MAX_DEPTH, "Queue Max Depth")
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_USAGE, "Queue Usage")
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_QTIME_SHORT, "Queue Time Short")
+	addSimulatedAttribute(set, mqmetric.ATTR_Q_QTIME_LONG, "Queue Time Long")
+
+	set = s.status[mqmetric.OT_CHANNEL]
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_NAME, "Channel Name").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_RQMNAME, "Remote Queue Manager Name").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_JOBNAME, "MCA Job Name").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_CONNNAME, "Connection Name").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_START, "Start Time (epoch ms)")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_MESSAGES, "Messages (API Calls for SVRCONN)").Delta = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_BYTES_SENT, "Bytes sent").Delta = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_BYTES_RCVD, "Bytes rcvd").Delta = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_BUFFERS_SENT, "Buffers sent").Delta = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_BUFFERS_RCVD, "Buffers rcvd").Delta = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_BATCHES, "Completed Batches").Delta = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_STATUS, "Channel Status")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_SUBSTATE, "Channel Substate")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_TYPE, "Channel Type")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_INSTANCE_TYPE, "Channel Instance Type")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_STATUS_SQUASH, "Channel Status - Simplified")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_NETTIME_SHORT, "Network Time Short")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_NETTIME_LONG, "Network Time Long")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_BATCHSZ_SHORT, "Batch Size Average Short")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_BATCHSZ_LONG, "Batch Size Average Long")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_XQTIME_SHORT, "XmitQ Time Average Short")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_XQTIME_LONG, "XmitQ Time Average Long")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_SINCE_MSG, "Time Since Msg")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_MAX_INST, "MaxInst")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_MAX_INSTC, "MaxInstC")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_CUR_INST, "Current Instances")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_SECPROT, "Negotiated TLS Protocol")
+	addSimulatedAttribute(set, mqmetric.ATTR_CHL_SSLCIPH, "Negotiated TLS Cipher").Pseudo = true
+
+	set = s.status[mqmetric.OT_TOPIC]
+	addSimulatedAttribute(set, mqmetric.ATTR_TOPIC_STRING, "Topic String").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_TOPIC_STATUS_TYPE, "Topic Status Type").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_TOPIC_PUB_MESSAGES, "Published Messages").Delta = true
+	addSimulatedAttribute(set, mqmetric.ATTR_TOPIC_SUB_MESSAGES, "Received Messages").Delta = true
+	addSimulatedAttribute(set, mqmetric.ATTR_TOPIC_PUBLISHER_COUNT, "Number of publishers")
+	addSimulatedAttribute(set, mqmetric.ATTR_TOPIC_SUBSCRIBER_COUNT, "Number of subscribers")
+	addSimulatedAttribute(set, mqmetric.ATTR_TOPIC_SINCE_PUB_MSG, "Time Since Msg")
+	addSimulatedAttribute(set, mqmetric.ATTR_TOPIC_SINCE_SUB_MSG, "Time Since Msg")
+
+	set = s.status[mqmetric.OT_SUB]
+	addSimulatedAttribute(set, mqmetric.ATTR_SUB_ID, "Subscription Id").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_SUB_NAME, "Subscription Name").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_SUB_TOPIC_STRING, "Topic String").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_SUB_TYPE, "Subscription Type")
+	addSimulatedAttribute(set, mqmetric.ATTR_SUB_SINCE_PUB_MSG, "Time Since Message Received")
+	addSimulatedAttribute(set, mqmetric.ATTR_SUB_MESSAGES, "Messages Received").Delta = true
+
+	set = s.status[mqmetric.OT_Q_MGR]
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_NAME, "Queue Manager Name").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_UPTIME, "Up time")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_CONNECTION_COUNT, "Connection Count")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_CHINIT_STATUS, "Channel Initiator Status")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_CMD_SERVER_STATUS, "Command Server Status")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_ACTIVE_LISTENERS, "Active Listener Count")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_ACTIVE_SERVICES, "Active Service Count")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_LOG_CURRENT_EXTENT, "Log Current Extent")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_LOG_MEDIA_EXTENT, "Log Media Extent")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_LOG_ARCHIVE_EXTENT, "Log Archive Extent")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_LOG_RESTART_EXTENT, "Log Restart Recovery Extent")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_LOG_MEDIA_SIZE, "Log Media Size")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_LOG_ARCHIVE_SIZE, "Log Archive Size")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_LOG_RESTART_SIZE, "Log Restart Recovery Size")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_LOG_REUSABLE_SIZE, "Log Reusable Size")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_LOG_START, "Log Start Time (epoch ms)")
+	addSimulatedAttribute(set, mqmetric.ATTR_QMGR_STATUS, "Queue Manager Status")
+
+	set = s.status[mqmetric.OT_CLUSTER]
+	addSimulatedAttribute(set, mqmetric.ATTR_CLUSTER_NAME, "Cluster Name").Pseudo = true
+	addSimulatedAttribute(set, mqmetric.ATTR_CLUSTER_STATUS, "Cluster Status")
+	addSimulatedAttribute(set, mqmetric.ATTR_CLUSTER_SUSPEND, "Cluster Suspend")
+	addSimulatedAttribute(set, mqmetric.ATTR_CLUSTER_QMTYPE, "Queue Manager Type")
+}
+
+func addSimulatedAttribute(set *mqmetric.StatusSet, name string, description string) *mqmetric.StatusAttribute {
+	attr := &mqmetric.StatusAttribute{
+		Description: description,
+		MetricName:  name,
+		Values:      make(map[string]*mqmetric.StatusValue),
+	}
+	set.Attributes[name] = attr
+	return attr
+}
+
+// Move a value by a random step, staying within the range. It starts somewhere in the range.
+func (s *simulator) walk(key string, min float64, max float64, step float64) int64 {
+	v, ok := s.walks[key]
+	if ok {
+		v += s.rnd.NormFloat64() * step
+	} else {
+		v = min + s.rnd.Float64()*(max-min)
+	}
+	v = math.Max(min, math.Min(max, v))
+	s.walks[key] = v
+	return int64(v)
+}
+
+// Up to n events in an interval
+func (s *simulator) count(n int64) int64 {
+	return s.rnd.Int63n(n + 1)
+}
+
+/*
+Move everything on by one interval. The queues and channels change state first, and then
+the values are filled in from them. The status values are all replaced each time, even though
+the collectors only report them when the poll interval says so.
+*/
+func (s *simulator) next() {
+	now := time.Now()
+
+	for _, q := range s.queues {
+		s.moveQueue(q, now)
+	}
+	for _, ch := range s.channels {
+		s.moveChannel(ch, now)
+	}
+	s.lsn += s.count(1 << 20)
+	s.publications = 0
+
+	s.fillPublished()
+	s.fillStatus(now)
+}
+
+// Puts and gets are equally likely, so the depth takes a random walk between empty and full
+func (s *simulator) moveQueue(q *simulatedQueue, now time.Time) {
+	q.puts = s.count(q.activity)
+	q.gets = s.count(q.activity)
+	if q.depth+q.puts-q.gets < 0 {
+		q.gets = q.depth + q.puts
+	}
+	if q.depth+q.puts-q.gets > simulatedMaxDepth {
+		q.puts = simulatedMaxDepth - q.depth + q.gets
+	}
+	q.depth += q.puts - q.gets
+
+	if q.puts > 0 {
+		q.lastPut = now
+	}
+	if q.gets > 0 {
+		q.lastGet = now
+	}
+}
+
+// A running channel occasionally fails, and then retries until it is running again
+func (s *simulator) moveChannel(ch *simulatedChannel, now time.Time) {
+	switch ch.status {
+	case ibmmq.MQCHS_RUNNING:
+		if s.rnd.Float64() < 0.05 {
+			ch.status = ibmmq.MQCHS_RETRYING
+		} else {
+			ch.lastMsg = now
+		}
+	case ibmmq.MQCHS_RETRYING:
+		if s.rnd.Float64() < 0.5 {
+			ch.status = ibmmq.MQCHS_BINDING
+		}
+	default:
+		ch.status = ibmmq.MQCHS_RUNNING
+		ch.started = now
+	}
+}
+
+func (s *simulator) fillPublished() {
+	for _, cl := range s.published.Classes {
+		for _, ty := range cl.Types {
+			// Which objects have values, and the queue that goes with each of them
+			keys := make(map[string]*simulatedQueue)
+			switch {
+			case strings.Contains(ty.ObjectTopic, "/NHAREPLICA/"):
+				for _, r := range s.replicas {
+					keys[mqmetric.NativeHAKeyPrefix+r] = nil
+				}
+			case strings.Contains(ty.ObjectTopic, "%s"):
+				for _, q := range s.queues {
+					keys[q.name] = q
+				}
+			default:
+				keys[mqmetric.QMgrMapKey] = nil
+			}
+			s.publications += len(keys)
+
+			for _, elem := range ty.Elements {
+				elem.Values = make(map[string]int64)
+				for key, q := range keys {
+					elem.Values[key] = s.publishedValue(elem, key, q)
+				}
+			}
+		}
+	}
+}
+
+// A value for a published element, in the units that its datatype says the queue manager uses
+func (s *simulator) publishedValue(elem *mqmetric.MonElement, key string, q *simulatedQueue) int64 {
+	if q != nil {
+		switch elem.Description {
+		case "Queue depth":
+			return q.depth
+		case "MQPUT/MQPUT1 count":
+			return q.puts
+		case "MQGET count":
+			return q.gets
+		}
+	}
+
+	id := elem.Parent.Parent.Name + "/" + elem.Parent.Name + "/" + elem.Description + "/" + key
+	switch elem.Datatype {
+	case ibmmq.MQIAMO_MONITOR_PERCENT, ibmmq.MQIAMO_MONITOR_HUNDREDTHS:
+		return s.walk(id, 0, 10000, 300)
+	case ibmmq.MQIAMO_MONITOR_MB:
+		return s.walk(id, 500, 20000, 100)
+	case ibmmq.MQIAMO_MONITOR_MICROSEC:
+		return s.walk(id, 100, 50000, 1000)
+	case ibmmq.MQIAMO_MONITOR_LSN:
+		return s.lsn
+	case ibmmq.MQIAMO_MONITOR_DELTA:
+		n := s.count(100)
+		if strings.Contains(strings.ToLower(elem.Description), "byte") {
+			n *= 1024
+		}
+		return n
+	default:
+		if strings.Contains(strings.ToLower(elem.Description), "bytes") {
+			return s.walk(id, 0, 100*1024*1024, 1024*1024)
+		}
+		return s.walk(id, 0, 100, 5)
+	}
+}
+
+func (s *simulator) fillStatus(now time.Time) {
+	for _, set := range s.status {
+		for _, attr := range set.Attributes {
+			attr.Values = make(map[string]*mqmetric.StatusValue)
+		}
+	}
+
+	// The usage and maximum depth come from discovery rather than status polling, so they
+	// are always there. That's also how the queues get their labels.
+	set := s.status[mqmetric.OT_Q]
+	for _, q := range s.queues {
+		setString(set, mqmetric.ATTR_Q_NAME, q.name, q.name)
+		setInt(set, mqmetric.ATTR_Q_USAGE, q.name, int64(q.usage))
+		setInt(set, mqmetric.ATTR_Q_MAX_DEPTH, q.name, simulatedMaxDepth)
+		setInt(set, mqmetric.ATTR_Q_DEPTH, q.name, q.depth)
+		if !s.cf.CC.UseStatus {
+			continue
+		}
+
+		msgAge := int64(0)
+		if q.depth > 0 {
+			msgAge = s.walk("msgage/"+q.name, 1, 600, 30)
+		}
+		setInt(set, mqmetric.ATTR_Q_SINCE_PUT, q.name, int64(now.Sub(q.lastPut).Seconds()))
+		setInt(set, mqmetric.ATTR_Q_SINCE_GET, q.name, int64(now.Sub(q.lastGet).Seconds()))
+		setInt(set, mqmetric.ATTR_Q_MSGAGE, q.name, msgAge)
+		setInt(set, mqmetric.ATTR_Q_IPPROCS, q.name, s.walk("ipprocs/"+q.name, 0, 5, 1))
+		setInt(set, mqmetric.ATTR_Q_OPPROCS, q.name, s.walk("opprocs/"+q.name, 0, 5, 1))
+		setInt(set, mqmetric.ATTR_Q_UNCOM, q.name, s.count(q.depth/10))
+		setInt(set, mqmetric.ATTR_Q_CURFSIZE, q.name, 1+q.depth/500)
+		setInt(set, mqmetric.ATTR_Q_CURMAXFSIZE, q.name, 2088960)
+		setInt(set, mqmetric.ATTR_Q_QTIME_SHORT, q.name, s.walk("qtimeshort/"+q.name, 100, 500000, 20000))
+		setInt(set, mqmetric.ATTR_Q_QTIME_LONG, q.name, s.walk("qtimelong/"+q.name, 100, 500000, 5000))
+	}
+
+	// Both are part of the same set of information that the live collection always gets
+	set = s.status[mqmetric.OT_Q_MGR]
+	setString(set, mqmetric.ATTR_QMGR_NAME, s.qmgr, s.qmgr)
+	setInt(set, mqmetric.ATTR_QMGR_STATUS, s.qmgr, int64(ibmmq.MQQMSTA_RUNNING))
+	setInt(set, mqmetric.ATTR_QMGR_UPTIME, s.qmgr, int64(now.Sub(s.started).Seconds()))
+	setInt(set, mqmetric.ATTR_QMGR_CONNECTION_COUNT, s.qmgr, s.walk("connections", 10, 200, 5))
+	setInt(set, mqmetric.ATTR_QMGR_CHINIT_STATUS, s.qmgr, int64(ibmmq.MQSVC_STATUS_RUNNING))
+	setInt(set, mqmetric.ATTR_QMGR_CMD_SERVER_STATUS, s.qmgr, int64(ibmmq.MQSVC_STATUS_RUNNING))
+	setInt(set, mqmetric.ATTR_QMGR_ACTIVE_LISTENERS, s.qmgr, 1)
+	setInt(set, mqmetric.ATTR_QMGR_ACTIVE_SERVICES, s.qmgr, 0)
+	extent := s.lsn >> 26
+	setInt(set, mqmetric.ATTR_QMGR_LOG_CURRENT_EXTENT, s.qmgr, extent)
+	setInt(set, mqmetric.ATTR_QMGR_LOG_MEDIA_EXTENT, s.qmgr, extent-2)
+	setInt(set, mqmetric.ATTR_QMGR_LOG_ARCHIVE_EXTENT, s.qmgr, extent-3)
+	setInt(set, mqmetric.ATTR_QMGR_LOG_RESTART_EXTENT, s.qmgr, extent-1)
+	// mqmetric converts these from MB when it reads them, so they are already in bytes here
+	const mb = 1024 * 1024
+	setInt(set, mqmetric.ATTR_QMGR_LOG_MEDIA_SIZE, s.qmgr, s.walk("logmedia", 10, 500, 10)*mb)
+	setInt(set, mqmetric.ATTR_QMGR_LOG_ARCHIVE_SIZE, s.qmgr, s.walk("logarchive", 0, 200, 10)*mb)
+	setInt(set, mqmetric.ATTR_QMGR_LOG_RESTART_SIZE, s.qmgr, s.walk("logrestart", 10, 200, 10)*mb)
+	setInt(set, mqmetric.ATTR_QMGR_LOG_REUSABLE_SIZE, s.qmgr, s.walk("logreusable", 0, 500, 10)*mb)
+	setInt(set, mqmetric.ATTR_QMGR_LOG_START, s.qmgr, s.started.Unix())
+
+	if len(s.queues) > 0 {
+		set = s.status[mqmetric.OT_CLUSTER]
+		setString(set, mqmetric.ATTR_CLUSTER_NAME, simulatedCluster, simulatedCluster)
+		setInt(set, mqmetric.ATTR_CLUSTER_STATUS, simulatedCluster, int64(ibmmq.MQCHS_RUNNING))
+		setInt(set, mqmetric.ATTR_CLUSTER_SUSPEND, simulatedCluster, int64(ibmmq.MQSUS_NO))
+		setInt(set, mqmetric.ATTR_CLUSTER_QMTYPE, simulatedCluster, int64(ibmmq.MQQMT_REPOSITORY))
+	}
+
+	if !s.cf.CC.UseStatus {
+		return
+	}
+
+	set = s.status[mqmetric.OT_CHANNEL]
+	for _, ch := range s.channels {
+		s.fillChannel(set, ch, now)
+	}
+
+	// A single topic, with a publisher and a subscriber
+	set = s.status[mqmetric.OT_TOPIC]
+	published := s.count(500)
+	if published > 0 {
+		s.topicPub = now
+		s.topicSub = now
+	}
+	for _, t := range []string{"pub", "sub"} {
+		key := mqmetric.TopicKey(simulatedTopic, t)
+		setString(set, mqmetric.ATTR_TOPIC_STRING, key, simulatedTopic)
+		setString(set, mqmetric.ATTR_TOPIC_STATUS_TYPE, key, t)
+		if t == "pub" {
+			setInt(set, mqmetric.ATTR_TOPIC_PUB_MESSAGES, key, published)
+			setInt(set, mqmetric.ATTR_TOPIC_PUBLISHER_COUNT, key, 1)
+			setInt(set, mqmetric.ATTR_TOPIC_SINCE_PUB_MSG, key, int64(now.Sub(s.topicPub).Seconds()))
+		} else {
+			setInt(set, mqmetric.ATTR_TOPIC_SUB_MESSAGES, key, published)
+			setInt(set, mqmetric.ATTR_TOPIC_SUBSCRIBER_COUNT, key, 1)
+			setInt(set, mqmetric.ATTR_TOPIC_SINCE_SUB_MSG, key, int64(now.Sub(s.topicSub).Seconds()))
+		}
+	}
+
+	set = s.status[mqmetric.OT_SUB]
+	subId := fmt.Sprintf("414D5120%040X", s.started.UnixNano())
+	setString(set, mqmetric.ATTR_SUB_ID, subId, subId)
+	setString(set, mqmetric.ATTR_SUB_NAME, subId, "SIM.SUB.01")
+	setString(set, mqmetric.ATTR_SUB_TOPIC_STRING, subId, simulatedTopic)
+	setInt(set, mqmetric.ATTR_SUB_TYPE, subId, int64(ibmmq.MQSUBTYPE_ADMIN))
+	setInt(set, mqmetric.ATTR_SUB_MESSAGES, subId, published)
+	setInt(set, mqmetric.ATTR_SUB_SINCE_PUB_MSG, subId, int64(now.Sub(s.topicSub).Seconds()))
+}
+
+func (s *simulator) fillChannel(set *mqmetric.StatusSet, ch *simulatedChannel, now time.Time) {
+	key := ch.key()
+	running := ch.status == ibmmq.MQCHS_RUNNING
+
+	setString(set, mqmetric.ATTR_CHL_NAME, key, ch.name)
+	setString(set, mqmetric.ATTR_CHL_CONNNAME, key, ch.connName)
+	setString(set, mqmetric.ATTR_CHL_JOBNAME, key, ch.jobName)
+	if ch.rqmName != "" {
+		setString(set, mqmetric.ATTR_CHL_RQMNAME, key, ch.rqmName)
+	}
+	setString(set, mqmetric.ATTR_CHL_SSLCIPH, key, "TLS_AES_256_GCM_SHA384")
+	setInt(set, mqmetric.ATTR_CHL_SECPROT, key, int64(ibmmq.MQSECPROT_TLSV13))
+
+	// The simplified status has already been squashed, as the values would be by mqmetric
+	squashed := int64(mqmetric.SQUASH_CHL_STATUS_RUNNING)
+	substate := ibmmq.MQCHSSTATE_IN_MQGET
+	if !running {
+		squashed = mqmetric.SQUASH_CHL_STATUS_TRANSITION
+		substate = ibmmq.MQCHSSTATE_OTHER
+	}
+	setInt(set, mqmetric.ATTR_CHL_STATUS, key, int64(ch.status))
+	setInt(set, mqmetric.ATTR_CHL_STATUS_SQUASH, key, squashed)
+	setInt(set, mqmetric.ATTR_CHL_SUBSTATE, key, int64(substate))
+	setInt(set, mqmetric.ATTR_CHL_TYPE, key, int64(ch.chlType))
+	setInt(set, mqmetric.ATTR_CHL_INSTANCE_TYPE, key, int64(ibmmq.MQOT_CURRENT_CHANNEL))
+	setInt(set, mqmetric.ATTR_CHL_START, key, ch.started.Unix())
+	setInt(set, mqmetric.ATTR_CHL_SINCE_MSG, key, int64(now.Sub(ch.lastMsg).Seconds()))
+	setInt(set, mqmetric.ATTR_CHL_MAX_INST, key, 999999999)
+	setInt(set, mqmetric.ATTR_CHL_MAX_INSTC, key, 999999999)
+	setInt(set, mqmetric.ATTR_CHL_CUR_INST, key, 1)
+
+	messages := int64(0)
+	if running {
+		messages = s.count(200)
+	}
+	batches := (messages + 9) / 10
+	setInt(set, mqmetric.ATTR_CHL_MESSAGES, key, messages)
+	setInt(set, mqmetric.ATTR_CHL_BYTES_SENT, key, messages*(512+s.count(2048)))
+	setInt(set, mqmetric.ATTR_CHL_BYTES_RCVD, key, messages*(128+s.count(512)))
+	setInt(set, mqmetric.ATTR_CHL_BUFFERS_SENT, key, messages+batches)
+	setInt(set, mqmetric.ATTR_CHL_BUFFERS_RCVD, key, messages+batches)
+	setInt(set, mqmetric.ATTR_CHL_BATCHES, key, batches)
+
+	setInt(set, mqmetric.ATTR_CHL_NETTIME_SHORT, key, s.walk("nettimeshort/"+key, 100, 20000, 2000))
+	setInt(set, mqmetric.ATTR_CHL_NETTIME_LONG, key, s.walk("nettimelong/"+key, 100, 20000, 500))
+	if ch.chlType == ibmmq.MQCHT_SENDER {
+		setInt(set, mqmetric.ATTR_CHL_BATCHSZ_SHORT, key, s.walk("batchszshort/"+key, 1, 50, 5))
+		setInt(set, mqmetric.ATTR_CHL_BATCHSZ_LONG, key, s.walk("batchszlong/"+key, 1, 50, 1))
+		setInt(set, mqmetric.ATTR_CHL_XQTIME_SHORT, key, s.walk("xqtimeshort/"+key, 100, 100000, 10000))
+		setInt(set, mqmetric.ATTR_CHL_XQTIME_LONG, key, s.walk("xqtimelong/"+key, 100, 100000, 2000))
+	}
+}
+
+func setInt(set *mqmetric.StatusSet, attr string, key string, v int64) {
+	if a, ok := set.Attributes[attr]; ok {
+		a.Values[key] = &mqmetric.StatusValue{IsInt64: true, ValueInt64: v}
+	}
+}
+
+func setString(set *mqmetric.StatusSet, attr string, key string, v string) {
+	if a, ok := set.Attributes[attr]; ok {
+		a.Values[key] = &mqmetric.StatusValue{ValueString: v}
+	}
+}
+
+func (s *simulator) publishedMetrics() *mqmetric.AllMetrics {
+	return s.published
+}
+
+func (s *simulator) objectStatus(ot int) *mqmetric.StatusSet {
+	if set, ok := s.status[ot]; ok {
+		return set
+	}
+	return &mqmetric.StatusSet{Attributes: make(map[string]*mqmetric.StatusAttribute)}
+}
+
+func (s *simulator) objectDescription(name string, ot int32) string {
+	switch ot {
+	case ibmmq.MQOT_Q:
+		return "Simulated queue " + name
+	case ibmmq.MQOT_CHANNEL:
+		return "Simulated channel " + name
+	}
+	return mqmetric.DUMMY_STRING
+}
+
+func (s *simulator) queueAttribute(name string, attr int32) string {
+	for _, q := range s.queues {
+		if q.name != name {
+			continue
+		}
+		switch attr {
+		case ibmmq.MQCA_CLUSTER_NAME:
+			return q.cluster
+		case ibmmq.MQCA_CUSTOM:
+			return q.custom
+		case ibmmq.MQIA_DEFINITION_TYPE:
+			return "Predefined"
+		case ibmmq.MQIA_DEF_PERSISTENCE:
+			return "NO"
+		}
+	}
+	return mqmetric.DUMMY_STRING
+}
+
+func (s *simulator) publicationCount() int {
+	return s.publications
+}
+
+// There's nothing to connect to, but the simulation starts again each time
+func (c *Collector) connectSimulate() error {
+	if c.QMgrName() == "" || strings.HasPrefix(c.Config().QMgrName, "*") {
+		c.updateConfig(func(cm *cf.Config) { cm.QMgrName = simulatedQMgr })
+	}
+	log.Infof("Simulating queue manager %s with %d queues, %d channels and %d NativeHA replicas",
+		c.QMgrName(), len(c.sim.queues), len(c.sim.channels), len(c.sim.replicas))
+
+	c.sim.start(c.QMgrName())
+	c.setPlatformInfo(ibmmq.MQPL_UNIX, true)
+	c.qmgrDescription = "Simulated queue manager"
+	c.hostname = c.sim.hostname
+
+	// Poll for status on the first collection, just as after a real connection
+	c.first = false
+	c.lastPoll = time.Time{}
+	c.connectDone(nil)
+	return nil
+}
+
+func (c *Collector) collectSimulate() (*Batch, error) {
+	c.lock()
+	defer c.unlock()
+
+	collectStartTime := time.Now()
+	pollStatus := false
+	if collectStartTime.Sub(c.lastPoll) >= c.Config().PollIntervalDuration {
+		c.lastPoll = collectStartTime
+		pollStatus = true
+	}
+
+	c.stats.timed(PhasePublications, func() error {
+		c.sim.next()
+		return nil
+	})
+
+	if c.recorder != nil {
+		return c.recordBatch(pollStatus, collectStartTime), nil
+	}
+	return c.buildBatch(time.Now(), pollStatus, false, collectStartTime), nil
+}
@@ -0,0 +1,132 @@
+package reload
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This package reloads the configuration file while a collector is running. That happens when
+the process gets a SIGHUP and, if the reloadInterval is set, when the modification time or size
+of any of the configuration files, including the ones they include, is seen to have changed.
+A patterns file named in the configuration is not checked; send a SIGHUP after editing one of
+those.
+
+A file that can't be read, or that has errors, is reported and the collector carries on with
+what it already had.
+*/
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	log "github.com/sirupsen/logrus"
+)
+
+type reloader struct {
+	cm       *cf.Config
+	g        *pipeline.Group
+	interval time.Duration
+	stamp    string
+}
+
+/*
+Start waits in the background for a reason to reload the configuration. Nothing is done if
+the configuration did not come from a file, and a SIGHUP then has its usual effect.
+*/
+func Start(cm *cf.Config, g *pipeline.Group) {
+	if cm.ConfigFile == "" {
+		return
+	}
+
+	r := &reloader{
+		cm:       cm,
+		g:        g,
+		interval: cm.ReloadIntervalDuration,
+	}
+	r.changed()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go r.run(hup)
+}
+
+func (r *reloader) run(hup chan os.Signal) {
+	for {
+		var check <-chan time.Time
+		if r.interval > 0 {
+			check = time.After(r.interval)
+		}
+
+		select {
+		case <-hup:
+			log.Infof("Reloading configuration from %s after SIGHUP", r.cm.ConfigFile)
+			r.changed()
+			r.reload()
+		case <-check:
+			if r.changed() {
+				log.Infof("Reloading configuration from %s after it has changed", r.cm.ConfigFile)
+				r.reload()
+			}
+		}
+	}
+}
+
+// Has any of the files been changed since we last looked. Editors often replace a file
+// instead of updating it, but that still gives a new modification time. The list of files
+// can itself change when the includes are edited.
+func (r *reloader) changed() bool {
+	var sb strings.Builder
+
+	for _, f := range cf.ConfigFiles() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			// The file might be in the middle of being replaced, so try again next time
+			log.Debugf("Cannot check configuration file: %v", err)
+			return false
+		}
+		fmt.Fprintf(&sb, "%s %d %d\n", f, fi.ModTime().UnixNano(), fi.Size())
+	}
+	if sb.String() == r.stamp {
+		return false
+	}
+	r.stamp = sb.String()
+	return true
+}
+
+func (r *reloader) reload() {
+	cms, err := cf.ReloadConfigFile(r.cm)
+	if err != nil {
+		log.Errorf("Cannot reload configuration: %v. Continuing with the current configuration.", err)
+		return
+	}
+
+	// The log level is shared by all the queue managers
+	level, err := log.ParseLevel(cms[0].LogLevel)
+	if err != nil {
+		level = log.InfoLevel
+	}
+	log.SetLevel(level)
+
+	r.g.Reload(cms)
+	r.interval = cms[0].ReloadIntervalDuration
+}
@@ -0,0 +1,527 @@
+package remotewrite
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This package pushes metrics to a server that accepts the Prometheus remote-write protocol,
+for when Prometheus can't reach the collector to scrape it. Anything that can be gathered
+for a scrape can be pushed, so the series are exactly the same as a scrape would see.
+
+Each push is a WriteRequest protobuf message, compressed with snappy. The message is simple
+enough to be built directly, without needing the generated code from the Prometheus project.
+
+Push only queues the request. A goroutine sends whatever is in the queue, oldest first, so the
+collection is never held up by a slow or unreachable server. When a request fails, the sender
+waits before trying it again, doubling the wait each time up to a maximum, and going back to
+the shortest wait once something has been sent. The queue has a limited size, and the oldest
+requests are dropped when it's full. If there's a WAL directory, each request is also written
+there until it has been sent, so that it survives a restart of the collector. Any files that
+were only partly written when the collector stopped are removed at startup. A request that
+the server rejects as bad is dropped straight away, as sending it again would not help.
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	defaultQueueSize  = 100
+	defaultTimeout    = 30 * time.Second
+	defaultMinBackoff = 1 * time.Second
+	defaultMaxBackoff = 1 * time.Minute
+
+	walSuffix = ".rw"
+	tmpSuffix = ".tmp"
+)
+
+// Config says where to send the metrics
+type Config struct {
+	URL          string
+	BearerToken  string // Can be a secret reference such as env:NAME
+	User         string
+	Password     string // Can be a secret reference
+	Timeout      time.Duration
+	QueueSize    int // The number of pushes that can wait to be sent
+	WALDirectory string
+	SecretTTL    time.Duration
+	MinBackoff   time.Duration // The first wait before a failed request is tried again
+	MaxBackoff   time.Duration // The longest wait
+}
+
+// Writer queues the pushes, and sends them in the background
+type Writer struct {
+	cfg    Config
+	client *http.Client
+
+	mutex   sync.Mutex
+	queue   []*request
+	seq     uint64
+	lastErr error // From the last attempt to send anything
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+type request struct {
+	body []byte // Already compressed
+	file string // The copy in the WAL, if there is one
+}
+
+// An error from the server that says the request should not be sent again
+type permanentError struct {
+	status int
+	msg    string
+}
+
+func (e *permanentError) Error() string {
+	return fmt.Sprintf("server returned HTTP status %d: %s", e.status, e.msg)
+}
+
+/*
+VerifyConfig checks the configuration without doing anything with it. The secrets are not
+looked up, and the WAL directory is not touched, so it can be used when the collector has
+only been asked to check its configuration.
+*/
+func VerifyConfig(cfg Config) error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("remote write URL %s must be an http or https address", cfg.URL)
+	}
+	if cfg.BearerToken != "" && cfg.User != "" {
+		return fmt.Errorf("remote write can use a bearer token or a user, but not both")
+	}
+	if err = cf.CheckSecretRef(cfg.BearerToken); err != nil {
+		return fmt.Errorf("remote write bearer token: %v", err)
+	}
+	if err = cf.CheckSecretRef(cfg.Password); err != nil {
+		return fmt.Errorf("remote write password: %v", err)
+	}
+	return nil
+}
+
+/*
+New checks the configuration, and loads any requests that were left in the WAL
+directory by a previous run.
+*/
+func New(cfg Config) (*Writer, error) {
+	err := VerifyConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = defaultMaxBackoff
+		if cfg.MaxBackoff < cfg.MinBackoff {
+			cfg.MaxBackoff = cfg.MinBackoff
+		}
+	}
+
+	w := &Writer{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if cfg.WALDirectory != "" {
+		err = w.loadWAL()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	go w.run()
+	if len(w.queue) > 0 {
+		w.signal()
+	}
+	return w, nil
+}
+
+/*
+Close stops the sender, once it has finished with any request that it's in the middle of.
+Whatever has not been sent stays in the WAL directory for the next run.
+*/
+func (w *Writer) Close() {
+	close(w.stop)
+	<-w.done
+}
+
+// Pick up the requests that had not been sent, oldest first
+func (w *Writer) loadWAL() error {
+	err := os.MkdirAll(w.cfg.WALDirectory, 0700)
+	if err != nil {
+		return fmt.Errorf("cannot create remote write WAL directory: %v", err)
+	}
+
+	// A file that was being written when we stopped is incomplete, and would never be loaded
+	tmpFiles, err := filepath.Glob(filepath.Join(w.cfg.WALDirectory, "*"+walSuffix+tmpSuffix))
+	if err != nil {
+		return err
+	}
+	for _, f := range tmpFiles {
+		log.Debugf("Removing incomplete remote write WAL file %s", f)
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Cannot remove remote write WAL file %s: %v", f, err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(w.cfg.WALDirectory, "*"+walSuffix))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, f := range files {
+		body, err := os.ReadFile(f)
+		if err != nil {
+			log.Warnf("Cannot read remote write WAL file %s: %v", f, err)
+			continue
+		}
+		if seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(f), walSuffix), 10, 64); err == nil && seq > w.seq {
+			w.seq = seq
+		}
+		w.add(&request{body: body, file: f})
+	}
+	if len(w.queue) > 0 {
+		log.Infof("Found %d remote write requests waiting to be sent", len(w.queue))
+	}
+	return nil
+}
+
+/*
+Push queues the current metrics from the Gatherer, to be sent after any earlier pushes that
+are still waiting. It does not wait for them to be sent. The error is from the sender's last
+attempt, so that the caller can tell whether the server is being reached.
+*/
+func (w *Writer) Push(g prometheus.Gatherer) error {
+	mfs, err := g.Gather()
+	if err != nil {
+		log.Debugf("Error gathering metrics for remote write: %v", err)
+	}
+
+	w.mutex.Lock()
+	if len(mfs) > 0 {
+		r := &request{body: snappy.Encode(nil, encodeWriteRequest(mfs, time.Now()))}
+		w.saveWAL(r)
+		w.add(r)
+	}
+	err = w.lastErr
+	w.mutex.Unlock()
+
+	w.signal()
+	return err
+}
+
+// Tell the sender that there's something new to send, without waiting for it
+func (w *Writer) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Add a request to the queue, making room for it if necessary. The mutex must be held.
+func (w *Writer) add(r *request) {
+	if len(w.queue) >= w.cfg.QueueSize {
+		log.Warnf("Remote write queue is full. Dropping the oldest request")
+		w.remove()
+	}
+	w.queue = append(w.queue, r)
+}
+
+// Take the oldest request off the queue. The mutex must be held.
+func (w *Writer) remove() {
+	r := w.queue[0]
+	w.queue = w.queue[1:]
+	if r.file != "" {
+		if err := os.Remove(r.file); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Cannot remove remote write WAL file %s: %v", r.file, err)
+		}
+	}
+}
+
+// The file is written under a temporary name first, so that a partly written one is never loaded
+func (w *Writer) saveWAL(r *request) {
+	if w.cfg.WALDirectory == "" {
+		return
+	}
+	w.seq++
+	name := filepath.Join(w.cfg.WALDirectory, fmt.Sprintf("%020d%s", w.seq, walSuffix))
+	tmp := name + tmpSuffix
+	err := os.WriteFile(tmp, r.body, 0600)
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		log.Warnf("Cannot write remote write WAL file %s: %v", name, err)
+		return
+	}
+	r.file = name
+}
+
+/*
+The sender. It waits to be told there's something new, or for the backoff to end after a
+failure. While waiting after a failure, new pushes only add to the queue; they don't cut the
+wait short, as the server probably still can't take them.
+*/
+func (w *Writer) run() {
+	defer close(w.done)
+
+	backoff := time.Duration(0)
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.wake:
+			if retry != nil {
+				continue
+			}
+		case <-retry:
+			retry = nil
+		}
+
+		err := w.flush()
+		if err == nil {
+			backoff = 0
+			continue
+		}
+		if backoff == 0 {
+			backoff = w.cfg.MinBackoff
+		} else {
+			backoff *= 2
+			if backoff > w.cfg.MaxBackoff {
+				backoff = w.cfg.MaxBackoff
+			}
+		}
+		log.Debugf("Remote write failed: %v. Trying again in %v", err, backoff)
+		retry = time.After(backoff)
+	}
+}
+
+// Send the queued requests, oldest first, until one fails or there's nothing left. The mutex
+// is not held while sending, so that the collection can carry on pushing.
+func (w *Writer) flush() error {
+	for {
+		select {
+		case <-w.stop:
+			return nil
+		default:
+		}
+
+		w.mutex.Lock()
+		if len(w.queue) == 0 {
+			w.mutex.Unlock()
+			return nil
+		}
+		r := w.queue[0]
+		w.mutex.Unlock()
+
+		err := w.send(r.body)
+		if pe, ok := err.(*permanentError); ok {
+			log.Errorf("Remote write request rejected, and dropped: %v", pe)
+			err = nil
+		}
+
+		w.mutex.Lock()
+		w.lastErr = err
+		// The queue might have filled up while we were sending, and this request already dropped
+		if err == nil && len(w.queue) > 0 && w.queue[0] == r {
+			w.remove()
+		}
+		w.mutex.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (w *Writer) send(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "mq_prometheus")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	if w.cfg.BearerToken != "" {
+		token, err := cf.ResolveSecret(w.cfg.BearerToken, w.cfg.SecretTTL)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if w.cfg.User != "" {
+		password, err := cf.ResolveSecret(w.cfg.Password, w.cfg.SecretTTL)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(w.cfg.User, password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		// The credentials may have been changed, so look them up again next time
+		cf.ForgetSecret(w.cfg.BearerToken)
+		cf.ForgetSecret(w.cfg.Password)
+		return fmt.Errorf("server returned HTTP status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	case resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests:
+		return &permanentError{status: resp.StatusCode, msg: strings.TrimSpace(string(msg))}
+	default:
+		return fmt.Errorf("server returned HTTP status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+}
+
+/*
+encodeWriteRequest builds the protobuf message from the gathered metrics. The field numbers
+come from the remote-write specification:
+
+	WriteRequest { repeated TimeSeries timeseries = 1; }
+	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+	Label        { string name = 1; string value = 2; }
+	Sample       { double value = 1; int64 timestamp = 2; }
+
+Histograms and summaries are split into their separate series, as they would be in a scrape.
+*/
+func encodeWriteRequest(mfs []*dto.MetricFamily, now time.Time) []byte {
+	var b []byte
+	ts := now.UnixMilli()
+
+	series := func(name string, m *dto.Metric, extra []*dto.LabelPair, v float64) {
+		t := ts
+		if m.TimestampMs != nil {
+			t = m.GetTimestampMs()
+		}
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, encodeTimeSeries(name, m.GetLabel(), extra, v, t))
+	}
+	label := func(name string, v float64) []*dto.LabelPair {
+		value := strconv.FormatFloat(v, 'g', -1, 64)
+		if math.IsInf(v, 1) {
+			value = "+Inf"
+		}
+		return []*dto.LabelPair{{Name: &name, Value: &value}}
+	}
+
+	for _, mf := range mfs {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				series(name, m, nil, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				series(name, m, nil, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				series(name, m, nil, m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					series(name, m, label("quantile", q.GetQuantile()), q.GetValue())
+				}
+				series(name+"_sum", m, nil, s.GetSampleSum())
+				series(name+"_count", m, nil, float64(s.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				infSeen := false
+				for _, bk := range h.GetBucket() {
+					infSeen = infSeen || math.IsInf(bk.GetUpperBound(), 1)
+					series(name+"_bucket", m, label("le", bk.GetUpperBound()), float64(bk.GetCumulativeCount()))
+				}
+				if !infSeen {
+					series(name+"_bucket", m, label("le", math.Inf(1)), float64(h.GetSampleCount()))
+				}
+				series(name+"_sum", m, nil, h.GetSampleSum())
+				series(name+"_count", m, nil, float64(h.GetSampleCount()))
+			}
+		}
+	}
+	return b
+}
+
+// The labels have to be sorted by name, including the metric name itself
+func encodeTimeSeries(name string, labels []*dto.LabelPair, extra []*dto.LabelPair, v float64, t int64) []byte {
+	type pair struct{ name, value string }
+	pairs := []pair{{"__name__", name}}
+	for _, lp := range labels {
+		pairs = append(pairs, pair{lp.GetName(), lp.GetValue()})
+	}
+	for _, lp := range extra {
+		pairs = append(pairs, pair{lp.GetName(), lp.GetValue()})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].name < pairs[j].name })
+
+	var b []byte
+	for _, p := range pairs {
+		var l []byte
+		l = protowire.AppendTag(l, 1, protowire.BytesType)
+		l = protowire.AppendString(l, p.name)
+		l = protowire.AppendTag(l, 2, protowire.BytesType)
+		l = protowire.AppendString(l, p.value)
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, l)
+	}
+
+	var s []byte
+	s = protowire.AppendTag(s, 1, protowire.Fixed64Type)
+	s = protowire.AppendFixed64(s, math.Float64bits(v))
+	s = protowire.AppendTag(s, 2, protowire.VarintType)
+	s = protowire.AppendVarint(s, uint64(t))
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendBytes(b, s)
+	return b
+}
@@ -0,0 +1,530 @@
+package remotewrite
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+These tests push to a local HTTP server that decodes the requests in the same way as a
+remote-write receiver, and can be told which status codes to give back.
+*/
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type testSeries struct {
+	labels    map[string]string
+	order     []string // The label names in the order they were sent
+	value     float64
+	timestamp int64
+}
+
+// The server answers with each of the statuses in turn, and then with 200
+type testServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+	times    []time.Time
+}
+
+func newTestServer(statuses ...int) *testServer {
+	s := &testServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mutex.Lock()
+		s.bodies = append(s.bodies, body)
+		s.headers = append(s.headers, r.Header.Clone())
+		s.times = append(s.times, time.Now())
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status = s.statuses[0]
+			s.statuses = s.statuses[1:]
+		}
+		s.mutex.Unlock()
+
+		w.WriteHeader(status)
+		if status/100 != 2 {
+			io.WriteString(w, http.StatusText(status))
+		}
+	}))
+	return s
+}
+
+func (s *testServer) requests() [][]byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([][]byte(nil), s.bodies...)
+}
+
+// The requests are sent in the background, so wait for the server to have seen them
+func (s *testServer) waitForRequests(t *testing.T, n int) [][]byte {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(5 * time.Millisecond) {
+		if b := s.requests(); len(b) >= n {
+			return b
+		}
+	}
+	t.Fatalf("Server got %d requests, expected %d", len(s.requests()), n)
+	return nil
+}
+
+// And wait for the sender to have dealt with the answers
+func waitForQueue(t *testing.T, w *Writer, n int) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(5 * time.Millisecond) {
+		w.mutex.Lock()
+		l := len(w.queue)
+		w.mutex.Unlock()
+		if l == n {
+			return
+		}
+	}
+	t.Fatalf("Queue did not get to %d requests", n)
+}
+
+// Retries are quick for the tests
+func testConfig(url string, dir string) Config {
+	return Config{URL: url, WALDirectory: dir, MinBackoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}
+}
+
+// A registry with a couple of series, and a histogram to check that it's split up
+func testGatherer(depth float64) prometheus.Gatherer {
+	reg := prometheus.NewRegistry()
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "ibmmq_queue_depth", Help: "Queue depth"}, []string{"qmgr", "queue"})
+	g.WithLabelValues("QM1", "APP.1").Set(depth)
+	g.WithLabelValues("QM1", "APP.2").Set(depth + 1)
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "ibmmq_test_seconds", Help: "Test", Buckets: []float64{0.5, 1}})
+	h.Observe(0.25)
+	h.Observe(2)
+	reg.MustRegister(g, h)
+	return reg
+}
+
+// One field of a protobuf message. Only the wire types used by remote write are needed.
+type testField struct {
+	num   protowire.Number
+	bytes []byte
+	value uint64
+}
+
+func decodeFields(t *testing.T, b []byte) []testField {
+	t.Helper()
+
+	var fields []testField
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("Bad protobuf tag: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		f := testField{num: num}
+		switch typ {
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		case protowire.Fixed64Type:
+			f.value, n = protowire.ConsumeFixed64(b)
+		case protowire.VarintType:
+			f.value, n = protowire.ConsumeVarint(b)
+		default:
+			t.Fatalf("Unexpected wire type %d for field %d", typ, num)
+		}
+		if n < 0 {
+			t.Fatalf("Bad value for field %d: %v", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// Undo the snappy compression and the WriteRequest encoding
+func decodeRequest(t *testing.T, body []byte) []testSeries {
+	t.Helper()
+
+	pb, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatalf("Cannot snappy-decode the request: %v", err)
+	}
+
+	var series []testSeries
+	for _, ts := range decodeFields(t, pb) {
+		if ts.num != 1 {
+			t.Fatalf("Unexpected field %d in WriteRequest", ts.num)
+		}
+		s := testSeries{labels: make(map[string]string)}
+		for _, f := range decodeFields(t, ts.bytes) {
+			switch f.num {
+			case 1:
+				var name, value string
+				for _, lf := range decodeFields(t, f.bytes) {
+					if lf.num == 1 {
+						name = string(lf.bytes)
+					} else if lf.num == 2 {
+						value = string(lf.bytes)
+					}
+				}
+				s.labels[name] = value
+				s.order = append(s.order, name)
+			case 2:
+				for _, sf := range decodeFields(t, f.bytes) {
+					if sf.num == 1 {
+						s.value = math.Float64frombits(sf.value)
+					} else if sf.num == 2 {
+						s.timestamp = int64(sf.value)
+					}
+				}
+			default:
+				t.Fatalf("Unexpected field %d in TimeSeries", f.num)
+			}
+		}
+		series = append(series, s)
+	}
+	return series
+}
+
+func findSeries(series []testSeries, name string, labels map[string]string) *testSeries {
+	for i := range series {
+		if series[i].labels["__name__"] != name {
+			continue
+		}
+		match := true
+		for k, v := range labels {
+			if series[i].labels[k] != v {
+				match = false
+			}
+		}
+		if match {
+			return &series[i]
+		}
+	}
+	return nil
+}
+
+func TestPushEncoding(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	w, err := New(testConfig(srv.URL, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err = w.Push(testGatherer(5)); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	bodies := srv.waitForRequests(t, 1)
+	srv.mutex.Lock()
+	h := srv.headers[0]
+	srv.mutex.Unlock()
+	if h.Get("Content-Encoding") != "snappy" || h.Get("Content-Type") != "application/x-protobuf" || h.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+		t.Errorf("Wrong headers: %v", h)
+	}
+
+	series := decodeRequest(t, bodies[0])
+	// Two queues, and three buckets with _sum and _count for the histogram
+	if len(series) != 7 {
+		t.Errorf("Got %d series, expected 7", len(series))
+	}
+	for _, s := range series {
+		for i := 1; i < len(s.order); i++ {
+			if s.order[i-1] >= s.order[i] {
+				t.Errorf("Labels are not sorted: %v", s.order)
+			}
+		}
+		if s.timestamp <= 0 {
+			t.Errorf("No timestamp for %v", s.labels)
+		}
+	}
+
+	checks := []struct {
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{"ibmmq_queue_depth", map[string]string{"qmgr": "QM1", "queue": "APP.1"}, 5},
+		{"ibmmq_queue_depth", map[string]string{"qmgr": "QM1", "queue": "APP.2"}, 6},
+		{"ibmmq_test_seconds_bucket", map[string]string{"le": "0.5"}, 1},
+		{"ibmmq_test_seconds_bucket", map[string]string{"le": "1"}, 1},
+		{"ibmmq_test_seconds_bucket", map[string]string{"le": "+Inf"}, 2},
+		{"ibmmq_test_seconds_sum", nil, 2.25},
+		{"ibmmq_test_seconds_count", nil, 2},
+	}
+	for _, c := range checks {
+		s := findSeries(series, c.name, c.labels)
+		if s == nil {
+			t.Errorf("No series for %s %v", c.name, c.labels)
+		} else if s.value != c.value {
+			t.Errorf("Series %s %v has value %v, expected %v", c.name, c.labels, s.value, c.value)
+		}
+	}
+}
+
+// A server error or a request to slow down keeps the request, and it's sent again after a wait
+func TestPushRetry(t *testing.T) {
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		srv := newTestServer(status)
+
+		w, err := New(testConfig(srv.URL, ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Push(testGatherer(1)); err != nil {
+			t.Errorf("First push after status %d returned %v", status, err)
+		}
+		bodies := srv.waitForRequests(t, 2)
+		waitForQueue(t, w, 0)
+		if !bytes.Equal(bodies[0], bodies[1]) {
+			t.Errorf("The request was not sent again after status %d", status)
+		}
+
+		if err = w.Push(testGatherer(2)); err != nil {
+			t.Errorf("Push after status %d failed: %v", status, err)
+		}
+		bodies = srv.waitForRequests(t, 3)
+		if s := findSeries(decodeRequest(t, bodies[2]), "ibmmq_queue_depth", map[string]string{"queue": "APP.1"}); s == nil || s.value != 2 {
+			t.Errorf("The last request does not have the new value")
+		}
+		w.Close()
+		srv.Close()
+	}
+}
+
+// The waits between the retries double each time, up to the maximum, and the error is reported
+// to the caller until something gets through
+func TestPushBackoff(t *testing.T) {
+	srv := newTestServer(500, 500, 500, 500, 500)
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL, "")
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Push(testGatherer(1))
+	srv.waitForRequests(t, 2)
+	if err = w.Push(testGatherer(2)); err == nil {
+		t.Errorf("Push did not report the failure")
+	}
+	srv.waitForRequests(t, 7)
+	waitForQueue(t, w, 0)
+	if err = w.Push(prometheus.NewRegistry()); err != nil {
+		t.Errorf("Push still reports an error once the server is working: %v", err)
+	}
+
+	srv.mutex.Lock()
+	times := append([]time.Time(nil), srv.times...)
+	srv.mutex.Unlock()
+	want := []time.Duration{10, 20, 40, 40, 40}
+	for i, d := range want {
+		gap := times[i+1].Sub(times[i])
+		if gap < d*time.Millisecond {
+			t.Errorf("Retry %d came after %v, expected at least %v", i+1, gap, d*time.Millisecond)
+		}
+		if gap > time.Second {
+			t.Errorf("Retry %d came after %v, which is more than the maximum backoff allows", i+1, gap)
+		}
+	}
+}
+
+// Pushing doesn't wait for the server
+func TestPushNotBlocked(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+
+	w, err := New(testConfig(srv.URL, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	defer close(release)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			w.Push(testGatherer(float64(i)))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Push waited for the server")
+	}
+}
+
+// What could not be sent is kept in the WAL directory, and a new Writer sends it
+func TestPushWALReplay(t *testing.T) {
+	dir := t.TempDir()
+	failing := newTestServer(500, 500, 500, 500, 500, 500, 500, 500, 500, 500)
+	defer failing.Close()
+
+	w, err := New(testConfig(failing.URL, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Push(testGatherer(3))
+	failing.waitForRequests(t, 1)
+	w.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+walSuffix))
+	if len(files) != 1 {
+		t.Fatalf("WAL directory has %d files, expected 1", len(files))
+	}
+
+	// As if the collector had been restarted, and the server was back. The WAL is sent
+	// without waiting for the next push.
+	srv := newTestServer()
+	defer srv.Close()
+	w, err = New(testConfig(srv.URL, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	bodies := srv.waitForRequests(t, 1)
+	waitForQueue(t, w, 0)
+	if !bytes.Equal(failing.requests()[0], bodies[0]) {
+		t.Fatalf("The request from the WAL was not sent again")
+	}
+	if s := findSeries(decodeRequest(t, bodies[0]), "ibmmq_queue_depth", map[string]string{"queue": "APP.1"}); s == nil || s.value != 3 {
+		t.Errorf("The request from the WAL does not have the original value")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("WAL directory still has %d files", len(entries))
+	}
+}
+
+// A file that was being written when the collector stopped is removed, and not sent
+func TestWALTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	tmp := filepath.Join(dir, "00000000000000000007"+walSuffix+tmpSuffix)
+	other := filepath.Join(dir, "notes.txt")
+	for _, f := range []string{tmp, other} {
+		if err := os.WriteFile(f, []byte("partial"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := newTestServer()
+	defer srv.Close()
+	w, err := New(testConfig(srv.URL, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err = os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("Temporary WAL file was not removed")
+	}
+	if _, err = os.Stat(other); err != nil {
+		t.Errorf("Unrelated file was removed: %v", err)
+	}
+	if len(w.queue) != 0 {
+		t.Errorf("Temporary WAL file was loaded")
+	}
+}
+
+// A request that the server says is bad is dropped, and not sent again
+func TestPushPermanentError(t *testing.T) {
+	dir := t.TempDir()
+	srv := newTestServer(http.StatusBadRequest)
+	defer srv.Close()
+
+	w, err := New(testConfig(srv.URL, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err = w.Push(testGatherer(4)); err != nil {
+		t.Errorf("Push with a permanent error returned %v", err)
+	}
+	srv.waitForRequests(t, 1)
+	waitForQueue(t, w, 0)
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("WAL directory still has %d files", len(entries))
+	}
+
+	if err = w.Push(testGatherer(5)); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	bodies := srv.waitForRequests(t, 2)
+	if s := findSeries(decodeRequest(t, bodies[1]), "ibmmq_queue_depth", map[string]string{"queue": "APP.1"}); s == nil || s.value != 5 {
+		t.Errorf("The dropped request was sent again")
+	}
+}
+
+// Checking the configuration mustn't change anything, or look up the secrets
+func TestVerifyConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "wal")
+	marker := filepath.Join(t.TempDir(), "ran")
+
+	tests := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		{name: "http", cfg: Config{URL: "http://localhost:9090/api/v1/write", WALDirectory: dir}, ok: true},
+		{name: "https with token", cfg: Config{URL: "https://prom.example.com/write", BearerToken: "exec:/usr/bin/touch " + marker}, ok: true},
+		{name: "user with password", cfg: Config{URL: "https://prom.example.com/write", User: "u", Password: "env:RW_PASSWORD"}, ok: true},
+		{name: "not http", cfg: Config{URL: "ftp://prom.example.com/write"}},
+		{name: "no host", cfg: Config{URL: "http:///write"}},
+		{name: "token and user", cfg: Config{URL: "http://localhost", BearerToken: "x", User: "u"}},
+		{name: "empty token reference", cfg: Config{URL: "http://localhost", BearerToken: "env:"}},
+		{name: "empty password command", cfg: Config{URL: "http://localhost", User: "u", Password: "exec:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyConfig(tt.cfg)
+			if tt.ok && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			} else if !tt.ok && err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("WAL directory was created while checking")
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("Secret command was run while checking")
+	}
+}
@@ -0,0 +1,230 @@
+package jsonsink
+
+/*
+  Copyright (c) IBM Corporation 2016, 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This package writes each Batch as one or more JSON objects. It is used by the mq_json
+collector to write to stdout, and by mq_multi to write to a file.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+)
+
+type collectionTimeStruct struct {
+	TimeStamp string `json:"timeStamp"`
+	Epoch     int64  `json:"epoch"`
+}
+
+type pointsStruct struct {
+	ObjectType string             `json:"objectType"`
+	Tags       map[string]string  `json:"tags"`
+	Metric     map[string]float64 `json:"metrics"`
+}
+
+type jsonReportStruct struct {
+	CollectionTime collectionTimeStruct `json:"collectionTime"`
+	Points         []pointsStruct       `json:"points"`
+}
+
+// Sink writes JSON to the given Writer. The recordMax value says how many objects
+// can go into each JSON array before it is split; 0 or less means no limit.
+type Sink struct {
+	w           io.Writer
+	oneline     bool
+	recordMax   int
+	fixupString map[string]string
+}
+
+// New creates a Sink that writes to w
+func New(w io.Writer, oneline bool, recordMax int) *Sink {
+	return &Sink{
+		w:           w,
+		oneline:     oneline,
+		recordMax:   recordMax,
+		fixupString: make(map[string]string),
+	}
+}
+
+func (s *Sink) Name() string {
+	return "json"
+}
+
+/*
+All of the metrics for a given set of tags are printed in a single
+JSON object. That means the published metrics and status metrics for a queue
+are merged. The exporter's own metrics are added to the queue manager object
+where their labels allow it.
+*/
+func (s *Sink) Write(b *pipeline.Batch) error {
+	var j jsonReportStruct
+	var allPoints []pointsStruct
+	var exporterPoints []pipeline.Point
+	var err error
+
+	j.CollectionTime.TimeStamp = b.Timestamp.Format(time.RFC3339)
+	j.CollectionTime.Epoch = b.Timestamp.Unix()
+
+	ptMap := make(map[string]int)
+	for _, p := range b.Points {
+		if p.Source == pipeline.SourceExporter {
+			exporterPoints = append(exporterPoints, p)
+			continue
+		}
+		key := tagsKey(p.ObjectType, p.Labels)
+		idx, ok := ptMap[key]
+		if !ok {
+			allPoints = append(allPoints, newPointsStruct(p))
+			idx = len(allPoints) - 1
+			ptMap[key] = idx
+		}
+		allPoints[idx].Metric[s.fixup(p.Metric)] = p.Value
+	}
+
+	// Exporter metrics with extra labels, such as the phase of a collection, can't be
+	// merged into the qmgr object, so they get objects of their own
+	for _, p := range exporterPoints {
+		idx := -1
+		for i := range allPoints {
+			if allPoints[i].ObjectType == pipeline.ObjectQMgr && hasTags(allPoints[i].Tags, p.Labels) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			key := tagsKey(p.ObjectType, p.Labels)
+			var ok bool
+			idx, ok = ptMap[key]
+			if !ok {
+				allPoints = append(allPoints, newPointsStruct(p))
+				idx = len(allPoints) - 1
+				ptMap[key] = idx
+			}
+		}
+		allPoints[idx].Metric[s.fixup(p.Metric)] = p.Value
+	}
+
+	// Finally split the records, if requested, so that each block is not TOO long
+	for _, chunk := range chunk(allPoints, s.recordMax) {
+		var data []byte
+		j.Points = chunk
+		if s.oneline {
+			data, _ = json.Marshal(j)
+		} else {
+			data, _ = json.MarshalIndent(j, "", "  ")
+		}
+		if _, err = fmt.Fprintf(s.w, "%s\n", data); err != nil {
+			break
+		}
+	}
+	return err
+}
+
+func newPointsStruct(p pipeline.Point) pointsStruct {
+	pt := pointsStruct{ObjectType: p.ObjectType}
+	pt.Tags = make(map[string]string)
+	pt.Metric = make(map[string]float64)
+	for k, v := range p.Labels {
+		pt.Tags[k] = v
+	}
+	return pt
+}
+
+// Are all of the labels already in the tags
+func hasTags(tags map[string]string, labels map[string]string) bool {
+	for k, v := range labels {
+		if tv, ok := tags[k]; !ok || tv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Build a string that uniquely identifies the object a point refers to
+func tagsKey(objectType string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(objectType)
+	for _, k := range keys {
+		sb.WriteString("/" + k + "=" + tags[k])
+	}
+	return sb.String()
+}
+
+func (s *Sink) fixup(s1 string) string {
+	// Another reformatting of the metric name - this one converts
+	// something like queue_avoided_bytes into queueAvoidedBytes
+
+	// The new name is cached, so next time round we can find it immediately
+	if s2, ok := s.fixupString[s1]; ok {
+		return s2
+	}
+
+	s2 := ""
+	c := ""
+	nextCaseUpper := false
+
+	for i := 0; i < len(s1); i++ {
+		if s1[i] != '_' {
+			if nextCaseUpper {
+				c = strings.ToUpper(s1[i : i+1])
+				nextCaseUpper = false
+			} else {
+				c = strings.ToLower(s1[i : i+1])
+			}
+			s2 += c
+		} else {
+			nextCaseUpper = true
+		}
+
+	}
+
+	s.fixupString[s1] = s2
+	return s2
+}
+
+// Split an array/slice into several chunks so that not all points are
+// dumped in the same JSON array.
+func chunk(slice []pointsStruct, chunkSize int) [][]pointsStruct {
+	var chunks [][]pointsStruct
+
+	if chunkSize <= 0 { // Allow the size to be unlimited: 0 & -1 both achieve that
+		chunkSize = len(slice)
+	}
+	for i := 0; i < len(slice); i += chunkSize {
+		end := i + chunkSize
+		if end > len(slice) {
+			end = len(slice)
+		}
+		chunks = append(chunks, slice[i:end])
+	}
+	return chunks
+}
@@ -0,0 +1,297 @@
+package otelsink
+
+/*
+  Copyright (c) IBM Corporation 2024, 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This package pushes collected data to an OpenTelemetry system over GRPC or HTTP, or
+to stdout. It is used by the mq_otel collector, and as one of the outputs from mq_multi.
+
+Each Write turns the points into Counters and Gauges, and then drives the MetricReader
+and Exporter to send them on.
+*/
+
+import (
+	"context"
+	"strings"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+
+	attribute "go.opentelemetry.io/otel/attribute"
+	metric "go.opentelemetry.io/otel/metric"
+
+	exportGrpc "go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	exportHttp "go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	exportStdout "go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+
+	metricsdk "go.opentelemetry.io/otel/sdk/metric"
+	metricdata "go.opentelemetry.io/otel/sdk/metric/metricdata"
+	metricres "go.opentelemetry.io/otel/sdk/resource"
+
+	// Doesn't keep the version on the same schedule as the main vendored packages so we explicitly name it here
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Each Gauge is handled as a list (array) of structures, to deal with
+// multiple objects reporting the same metric eg channel_messages. We don't
+// need an equivalent for Counters because those are reported synchronously
+// The OTel specification does define synchronous Gauges, but those have not
+// yet made their way into the Go libraries as they are currently marked "experimental".
+// See https://github.com/open-telemetry/opentelemetry-go/issues/3984
+// and https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/metrics/api.md#gauge
+type gaugeStruct struct {
+	value float64
+	tags  attribute.Set
+}
+
+// Sink holds the OTel components for one destination
+type Sink struct {
+	ctx           context.Context
+	overrideCType bool
+
+	exporter      metricsdk.Exporter
+	reader        metricsdk.Reader
+	meterProvider *metricsdk.MeterProvider
+	meter         metric.Meter
+
+	counterMap map[string]metric.Float64Counter
+	gaugeMap   map[string][]gaugeStruct
+}
+
+/*
+New creates the exporter for the endpoint, and the reader and meter that go with it.
+When overrideCType is set, all metrics are reported as Gauges for compatibility with
+the mq_prometheus collector.
+*/
+func New(ctx context.Context, res *metricres.Resource, endpoint string, insecure bool, overrideCType bool) (*Sink, error) {
+	var err error
+
+	s := &Sink{
+		ctx:           ctx,
+		overrideCType: overrideCType,
+		counterMap:    make(map[string]metric.Float64Counter),
+		gaugeMap:      make(map[string][]gaugeStruct),
+	}
+
+	s.exporter, err = newExporter(ctx, endpoint, insecure)
+	if err == nil {
+		// Some MQ metrics come out as truly cumulative (eg channel message count). But we convert all counter metrics to deltas.
+		deltaTemporalitySelector := func(metricsdk.InstrumentKind) metricdata.Temporality { return metricdata.DeltaTemporality }
+
+		s.reader = metricsdk.NewManualReader(metricsdk.WithTemporalitySelector(deltaTemporalitySelector))
+		s.meterProvider = metricsdk.NewMeterProvider(metricsdk.WithResource(res), metricsdk.WithReader(s.reader))
+		s.meter = s.meterProvider.Meter("ibmmq")
+
+		log.Debugf("MetricReader: %+v", s.reader)
+		log.Debugf("Exporter is %+v", s.exporter)
+		log.Debugf("MeterProvider: %+v", s.meterProvider)
+	}
+
+	return s, err
+}
+
+// DefaultResource describes this program to the OTel backend
+func DefaultResource() *metricres.Resource {
+	return metricres.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("ibmmq"),
+		semconv.ServiceVersion(cf.MqGolangVersion()),
+	)
+}
+
+func newExporter(ctx context.Context, endpoint string, insecure bool) (metricsdk.Exporter, error) {
+	// Returns an exporter for a couple of known exporter types.
+	// - Stdout is the default (endpoint is not defined)
+	// - OTLP/GRPC is an alternative when you define an endpoint
+	//   endpoint is a simple hostname:port string
+	// - OTEL/HTTP can be used if you set the endpoint to start with the protocol
+	//   endpoint is http[s]://hostname:port
+	// Additional config options for TLS could be provided for the GRPC protocol, but
+	// they can also come via OTEL-defined env vars
+	if endpoint == "" {
+		return exportStdout.New()
+	} else if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		opts := []exportHttp.Option{
+			exportHttp.WithEndpointURL(endpoint),
+		}
+
+		if insecure {
+			log.Debugf("Enabling insecure mode for HTTP")
+			opts = append(opts, exportHttp.WithInsecure())
+		}
+
+		return exportHttp.New(ctx, opts...)
+	} else {
+		opts := []exportGrpc.Option{
+			exportGrpc.WithEndpoint(endpoint),
+		}
+
+		if insecure {
+			log.Debugf("Enabling insecure mode for GRPC")
+			opts = append(opts, exportGrpc.WithInsecure())
+		}
+		return exportGrpc.New(ctx, opts...)
+	}
+}
+
+// MeterProvider gives access to the provider so it can be made the global one
+func (s *Sink) MeterProvider() *metricsdk.MeterProvider {
+	return s.meterProvider
+}
+
+// Shutdown flushes and stops the OTel components
+func (s *Sink) Shutdown(ctx context.Context) error {
+	return s.meterProvider.Shutdown(ctx)
+}
+
+func (s *Sink) Name() string {
+	return "otel"
+}
+
+/*
+Write stashes the points as Counters and Gauges, and then pushes them to the
+configured endpoint.
+*/
+func (s *Sink) Write(b *pipeline.Batch) error {
+	var err error
+
+	// Clear out everything we know so far.
+	for gaugeName := range s.gaugeMap {
+		s.gaugeMap[gaugeName] = nil
+	}
+
+	for _, p := range b.Points {
+		s.addMetric(p.ObjectType, p.Metric, p.Description, p.Unit, p.Kind == pipeline.Counter, p.Value, p.Labels)
+	}
+
+	rm := metricdata.ResourceMetrics{}
+	// This will drive the callbacks for gauges that are stashed during the addMetric calls
+	err = s.reader.Collect(s.ctx, &rm)
+	if err == nil {
+		// We should now have everything available in a structure. So push it to the collector
+		err = s.exporter.Export(s.ctx, &rm)
+	}
+	return err
+}
+
+// The value is passed in as a float64 (to match other exporters/collectors in this repo).
+// Can we set the timestamp for when it was collected rather than "now"? Does not appear so.
+
+// This is where we add the metric's value to the set that will be reported on this pass.
+// Two different sorts of metric report are used:
+// - a Counter is for those that can be accumulated over time (the delta flag is enabled)
+// - a Gauge is used for "absolute" numbers eg number of current connections
+func (s *Sink) addMetric(series string, metricName string, desc string, unit string, cumul bool, value float64, tags map[string]string) {
+	var err error
+	var pt metric.Float64Counter
+	var guAr []gaugeStruct
+
+	// Use the UCUM names for the units that we know about
+	metricUnit := "1"
+	switch unit {
+	case pipeline.UnitSeconds:
+		metricUnit = "s"
+	case pipeline.UnitBytes:
+		metricUnit = "By"
+	case pipeline.UnitPercent:
+		metricUnit = "%"
+	}
+	ok := true
+
+	// Some metrics look a bit silly with the name by default coming out looking like queue_queue_depth.
+	// So we strip the 2nd "queue".
+	if series == "queue" && strings.HasPrefix(metricName, "queue_") {
+		metricName = metricName[len("queue_"):]
+	}
+
+	// Optionally force all metrics to be reported as Gauges for compatibility with the mq_prometheus collector in this repo
+	if s.overrideCType {
+		cumul = false
+	}
+
+	counterKey := "ibmmq." + series + "." + metricName
+	if cumul {
+		if pt, ok = s.counterMap[counterKey]; !ok {
+			pt, err = s.meter.Float64Counter(counterKey, metric.WithDescription(desc), metric.WithUnit(metricUnit))
+
+			if err == nil {
+				log.Debugf("Created counter for %s", counterKey)
+				s.counterMap[counterKey] = pt
+
+			} else {
+				log.Debugf("Error creating counter for %s: %v", counterKey, err)
+				return
+			}
+		}
+	} else {
+		if guAr, ok = s.gaugeMap[counterKey]; !ok {
+			// The callback function needs to be passed some correlator to say which
+			// metric it's for. So we're going to end up with a long list of very similar
+			// functions.
+			k := counterKey
+			_, err = s.meter.Float64ObservableGauge(counterKey, metric.WithDescription(desc),
+				metric.WithUnit(metricUnit),
+				metric.WithFloat64Callback(func(ctx context.Context, o metric.Float64Observer) error {
+					return s.observe(ctx, o, k)
+				}))
+
+			if err == nil {
+				log.Debugf("Created gauge   for %s", counterKey)
+				guAr = nil
+				s.gaugeMap[counterKey] = guAr
+
+			} else {
+				log.Debugf("Error creating gauge for %s: %v", counterKey, err)
+				return
+			}
+		}
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(tags))
+	for ak, av := range tags {
+		attrs = append(attrs, attribute.String(ak, av))
+	}
+
+	tagSet := attribute.NewSet(attrs...)
+
+	// Counters can be added immediately to the metric list; Gauges can only be added by a Callback function
+	// so we stash the value as an array element.
+	if cumul {
+		pt.Add(s.ctx, value, metric.WithAttributeSet(tagSet))
+	} else {
+		m := gaugeStruct{value: value, tags: tagSet}
+		guAr = append(guAr, m)
+		s.gaugeMap[counterKey] = guAr
+	}
+}
+
+// This function is called during the Collect phase as a callback for all gauges. Each
+// metric might have multiple values, one for each object reporting the metric. They have
+// been stashed in arrays which in turn are in a map keyed by the metric name
+func (s *Sink) observe(_ context.Context, o metric.Float64Observer, counterKey string) error {
+	if guAr, ok := s.gaugeMap[counterKey]; ok {
+		for i := 0; i < len(guAr); i++ {
+			g := guAr[i]
+			o.Observe(g.value, metric.WithAttributeSet(g.tags))
+		}
+	}
+	return nil
+}
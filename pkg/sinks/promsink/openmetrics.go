@@ -0,0 +1,185 @@
+package promsink
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+The names that have always been used for the metrics don't quite follow the OpenMetrics
+conventions, and changing them would break existing dashboards. So a Sink made by
+NewOpenMetrics uses a different scheme:
+
+  - The unit is the last part of the name, such as "_seconds" or "_bytes". Percentages
+    become ratios, between 0 and 1, with a "_ratio" suffix.
+  - Anything that counts is a Counter whose name ends in "_total". The queue manager gives
+    us the changes since the previous collection, so those are added up here into totals
+    that only go up. Each total has a "_created" time for when we started counting it.
+  - The UNIT metadata is given for the metrics that have one.
+
+A scrape that asks for "application/openmetrics-text" gets that format, including the
+UNIT and "_created" lines. Other scrapes get the usual text format, with the same names.
+*/
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	log "github.com/sirupsen/logrus"
+)
+
+// The units that go into the OpenMetrics names, and into the UNIT metadata
+var openMetricsUnits = []string{"seconds", "bytes", "ratio"}
+
+type counterTotal struct {
+	value   float64
+	created time.Time
+}
+
+/*
+openMetricsName gives the name for a point, and what its value has to be multiplied by
+to be in the base unit. It also says whether the point is reported as a Counter. The
+collector's own metrics whose names end in "_total" are already totals, so they are
+Counters too.
+*/
+func openMetricsName(p *pipeline.Point) (string, float64, bool) {
+	name := p.Name()
+	scale := 1.0
+
+	switch p.Unit {
+	case pipeline.UnitSeconds:
+		name = withSuffix(name, "_seconds")
+	case pipeline.UnitBytes:
+		name = withSuffix(name, "_bytes")
+	case pipeline.UnitPercent:
+		name = strings.TrimSuffix(strings.TrimSuffix(name, "_percentage"), "_percent")
+		name = withSuffix(name, "_ratio")
+		scale = 0.01
+	}
+
+	counter := p.Kind == pipeline.Counter || (p.Source == pipeline.SourceExporter && strings.HasSuffix(name, "_total"))
+	if counter {
+		name = withSuffix(name, "_total")
+	}
+	return name, scale, counter
+}
+
+func withSuffix(name string, suffix string) string {
+	if strings.HasSuffix(name, suffix) {
+		return name
+	}
+	return name + suffix
+}
+
+/*
+accumulate adds the changes in the latest points to the totals for the queue manager.
+The status points only have new values when there has been a poll, and the published ones
+when the publications have been processed; otherwise they are the same points as before,
+and must not be counted again. A series that is no longer reported
+is forgotten, and starts again from 0 if it comes back. A negative change, such as after
+the queue manager has restarted, is ignored.
+*/
+func (s *Sink) accumulate(q *qmgrPoints, published bool, statusPolled bool, created time.Time) {
+	totals := make(map[string]*counterTotal)
+
+	add := func(pts []pipeline.Point, changed bool) {
+		for i := range pts {
+			p := &pts[i]
+			if p.Kind != pipeline.Counter {
+				continue
+			}
+			name, scale, _ := openMetricsName(p)
+			labelNames, labelValues := sortedLabels(p)
+			key := seriesKey(prometheus.BuildFQName(s.namespace, "", name), labelNames, labelValues)
+
+			t, ok := q.totals[key]
+			if !ok {
+				t = &counterTotal{created: created}
+			}
+			if changed && p.Value > 0 {
+				t.value += p.Value * scale
+			}
+			totals[key] = t
+		}
+	}
+	add(q.published, published)
+	add(q.status, statusPolled)
+	q.totals = totals
+}
+
+// The unit for a family of metrics, from the end of its name
+func unitFor(mf *dto.MetricFamily) string {
+	name := mf.GetName()
+	if mf.GetType() == dto.MetricType_COUNTER {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	for _, u := range openMetricsUnits {
+		if strings.HasSuffix(name, "_"+u) {
+			return u
+		}
+	}
+	return ""
+}
+
+/*
+OpenMetricsHandler serves the metrics from a Gatherer in whichever format the scrape asks
+for, including OpenMetrics. It does the same job as the promhttp handler, which has no
+way to add the units to the output.
+*/
+func OpenMetricsHandler(g prometheus.Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mfs, err := g.Gather()
+		if err != nil {
+			log.Debugf("Error gathering metrics: %v", err)
+			if len(mfs) == 0 {
+				http.Error(w, "An error has occurred while gathering metrics: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		format := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+		w.Header().Set("Content-Type", string(format))
+
+		var out io.Writer = w
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			out = gz
+		}
+
+		enc := expfmt.NewEncoder(out, format, expfmt.WithUnit(), expfmt.WithCreatedLines())
+		for _, mf := range mfs {
+			if u := unitFor(mf); u != "" {
+				mf.Unit = &u
+			}
+			if err = enc.Encode(mf); err != nil {
+				log.Debugf("Error encoding %s: %v", mf.GetName(), err)
+				return
+			}
+		}
+		if c, ok := enc.(expfmt.Closer); ok {
+			_ = c.Close()
+		}
+	})
+}
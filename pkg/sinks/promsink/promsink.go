@@ -0,0 +1,341 @@
+package promsink
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This package makes the points from each collection available to a Prometheus scrape.
+Each scrape gets whatever was last written. In the mq_prometheus collector, the scrape
+drives the collection so that is the data from the same request. Other collectors
+collect on a timer.
+
+The metric names and labels are the same as mq_prometheus gives by default. Values are
+reported as Gauges unless the Sink has been asked to use Counters for the metrics that
+count things over an interval. Each queue manager's points are kept separately, so a
+Batch for one of them does not replace the values for the others.
+
+The Sink can instead follow the OpenMetrics conventions for names, units and types. That
+is described in openmetrics.go.
+
+All the series with the same name are one family for Prometheus, and must have the same
+help text and type. The relabelling rules can give two different metrics the same name, and
+the registry would then fail the whole scrape. So when a Batch is written, any series that
+would not fit in with the family that's already there is dropped, and the problem logged.
+*/
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// Sink holds the most recent points so they can be given to a scrape
+type Sink struct {
+	namespace   string
+	counters    bool
+	openMetrics bool
+	started     time.Time
+
+	mutex     sync.Mutex
+	qmgrs     map[string]*qmgrPoints
+	descs     map[string]*prometheus.Desc
+	conflicts map[string]bool // Families that have already been reported as having conflicts
+}
+
+type qmgrPoints struct {
+	published []pipeline.Point
+	status    []pipeline.Point
+	exporter  []pipeline.Point
+	totals    map[string]*counterTotal // Only used for OpenMetrics
+	families  map[string]family        // What this queue manager's points are reported as
+}
+
+// What Prometheus needs to be the same for all the series with the same name
+type family struct {
+	help    string
+	counter bool
+}
+
+// New creates a Sink whose metrics have the namespace as a prefix. If counters is set,
+// then the points that are counts for the interval are reported as Counters.
+func New(namespace string, counters bool) *Sink {
+	return &Sink{
+		namespace: namespace,
+		counters:  counters,
+		qmgrs:     make(map[string]*qmgrPoints),
+		descs:     make(map[string]*prometheus.Desc),
+		conflicts: make(map[string]bool),
+		started:   time.Now(),
+	}
+}
+
+// NewOpenMetrics creates a Sink whose metrics follow the OpenMetrics naming conventions,
+// with Counters for everything that counts
+func NewOpenMetrics(namespace string) *Sink {
+	s := New(namespace, true)
+	s.openMetrics = true
+	return s
+}
+
+func (s *Sink) Name() string {
+	return "prometheus"
+}
+
+/*
+Write replaces the points that will be reported. The status values are kept
+until there is a new poll for status, and the published values until there is
+a collection that has processed the publications.
+*/
+func (s *Sink) Write(b *pipeline.Batch) error {
+	var published, status, exporter []pipeline.Point
+
+	for _, p := range b.Points {
+		switch p.Source {
+		case pipeline.SourcePublication:
+			published = append(published, p)
+		case pipeline.SourceStatus:
+			status = append(status, p)
+		case pipeline.SourceExporter:
+			exporter = append(exporter, p)
+		}
+	}
+
+	s.mutex.Lock()
+	q, ok := s.qmgrs[b.QMgr]
+	if !ok {
+		q = &qmgrPoints{}
+		s.qmgrs[b.QMgr] = q
+	}
+	if !b.PublicationsSkipped {
+		q.published = published
+	}
+	if b.StatusPolled {
+		q.status = status
+	}
+	q.exporter = exporter
+	s.checkFamilies(b.QMgr, q)
+	if s.openMetrics {
+		s.accumulate(q, !b.PublicationsSkipped, b.StatusPolled, b.Timestamp)
+	}
+	s.mutex.Unlock()
+
+	return nil
+}
+
+/*
+checkFamilies drops this queue manager's points that don't fit in with the families that
+are already being reported, either by the other queue managers or by its own earlier points.
+The other queue managers have already been checked in the same way, so what they report stays
+as it was.
+*/
+func (s *Sink) checkFamilies(qmgr string, q *qmgrPoints) {
+	others := make(map[string]family)
+	for name, oq := range s.qmgrs {
+		if name == qmgr {
+			continue
+		}
+		for fqName, f := range oq.families {
+			if _, ok := others[fqName]; !ok {
+				others[fqName] = f
+			}
+		}
+	}
+
+	q.families = make(map[string]family)
+	keep := func(pts []pipeline.Point) []pipeline.Point {
+		kept := pts[:0]
+		for i := range pts {
+			fqName, counter := s.family(&pts[i])
+			f := family{help: pts[i].Description, counter: counter}
+			existing, ok := others[fqName]
+			if !ok {
+				existing, ok = q.families[fqName]
+			}
+			if ok && existing != f {
+				s.reportConflict(qmgr, fqName, existing, f)
+				continue
+			}
+			q.families[fqName] = f
+			kept = append(kept, pts[i])
+		}
+		return kept
+	}
+	q.published = keep(q.published)
+	q.status = keep(q.status)
+	q.exporter = keep(q.exporter)
+}
+
+// Each conflict is only worth a warning the first time, as it will happen on every collection
+func (s *Sink) reportConflict(qmgr string, fqName string, existing family, f family) {
+	what := "help text"
+	if existing.counter != f.counter {
+		what = "type"
+	}
+	if !s.conflicts[fqName] {
+		s.conflicts[fqName] = true
+		log.Warnf("Series for %s from %s are not reported as they have a different %s from others with the same name. Check the relabelConfigs", fqName, qmgr, what)
+	} else {
+		log.Debugf("Series for %s from %s are not reported as they have a different %s", fqName, qmgr, what)
+	}
+}
+
+// The name of the family that a point is reported in, and whether it's a Counter. This has
+// to agree with newConstMetric.
+func (s *Sink) family(p *pipeline.Point) (string, bool) {
+	name := p.Name()
+	counter := s.counters && p.Kind == pipeline.Counter
+	if s.openMetrics {
+		name, _, counter = openMetricsName(p)
+	}
+	return prometheus.BuildFQName(s.namespace, "", name), counter
+}
+
+// Handler gives an HTTP handler that only reports this Sink's metrics
+func (s *Sink) Handler() http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(s)
+	if s.openMetrics {
+		return OpenMetricsHandler(reg)
+	}
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+// Describe does not send anything, which makes this an "unchecked" collector. The set of
+// metrics is not known until the queue manager has told us what it publishes.
+func (s *Sink) Describe(ch chan<- *prometheus.Desc) {
+}
+
+/*
+Collect is called by the Prometheus registry for each scrape. The relabelling rules
+might have made two points look the same, and the registry would then reject the whole
+scrape, so only the first of them is reported. Points that would not fit in their family
+have already been dropped by Write.
+*/
+func (s *Sink) Collect(ch chan<- prometheus.Metric) {
+	s.collect(ch, nil)
+}
+
+// Selected gives a Collector that only reports the points that match the Selection
+func (s *Sink) Selected(sel *pipeline.Selection) prometheus.Collector {
+	return &selectedSink{sink: s, sel: sel}
+}
+
+type selectedSink struct {
+	sink *Sink
+	sel  *pipeline.Selection
+}
+
+func (ss *selectedSink) Describe(ch chan<- *prometheus.Desc) {
+}
+
+func (ss *selectedSink) Collect(ch chan<- prometheus.Metric) {
+	ss.sink.collect(ch, ss.sel)
+}
+
+func (s *Sink) collect(ch chan<- prometheus.Metric, sel *pipeline.Selection) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	seen := make(map[string]bool)
+	for _, q := range s.qmgrs {
+		for _, pts := range [][]pipeline.Point{q.published, q.status, q.exporter} {
+			for i := range pts {
+				if sel != nil && !sel.Matches(&pts[i]) {
+					continue
+				}
+				m, key, err := s.newConstMetric(q, &pts[i])
+				if err == nil && seen[key] {
+					err = fmt.Errorf("duplicate series")
+				}
+				if err == nil {
+					seen[key] = true
+					ch <- m
+				} else {
+					log.Debugf("Cannot report %s: %v", pts[i].Name(), err)
+				}
+			}
+		}
+	}
+}
+
+// The metric is returned along with a key that identifies its series
+func (s *Sink) newConstMetric(q *qmgrPoints, p *pipeline.Point) (prometheus.Metric, string, error) {
+	name := p.Name()
+	value := p.Value
+	counter := false
+	if s.openMetrics {
+		var scale float64
+		name, scale, counter = openMetricsName(p)
+		value *= scale
+	}
+
+	labelNames, labelValues := sortedLabels(p)
+	fqName := prometheus.BuildFQName(s.namespace, "", name)
+	descKey := fqName + "/" + strings.Join(labelNames, ",")
+	desc, ok := s.descs[descKey]
+	if !ok {
+		desc = prometheus.NewDesc(fqName, p.Description, labelNames, nil)
+		s.descs[descKey] = desc
+	}
+	key := seriesKey(fqName, labelNames, labelValues)
+
+	if counter {
+		// The collector's own counts are already totals, since it started
+		created := s.started
+		if t, ok := q.totals[key]; ok {
+			value = t.value
+			created = t.created
+		}
+		m, err := prometheus.NewConstMetricWithCreatedTimestamp(desc, prometheus.CounterValue, value, created, labelValues...)
+		return m, key, err
+	}
+
+	valueType := prometheus.GaugeValue
+	if s.counters && p.Kind == pipeline.Counter {
+		valueType = prometheus.CounterValue
+	}
+	m, err := prometheus.NewConstMetric(desc, valueType, value, labelValues...)
+	return m, key, err
+}
+
+// Identifies a single series
+func seriesKey(fqName string, labelNames []string, labelValues []string) string {
+	return fqName + "/" + strings.Join(labelNames, ",") + "/" + strings.Join(labelValues, "\xff")
+}
+
+// The label names in order, and their values
+func sortedLabels(p *pipeline.Point) ([]string, []string) {
+	labelNames := make([]string, 0, len(p.Labels))
+	for k := range p.Labels {
+		labelNames = append(labelNames, k)
+	}
+	sort.Strings(labelNames)
+	labelValues := make([]string, len(labelNames))
+	for i, k := range labelNames {
+		labelValues[i] = p.Labels[k]
+	}
+	return labelNames, labelValues
+}
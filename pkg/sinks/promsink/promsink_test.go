@@ -0,0 +1,117 @@
+package promsink_test
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"testing"
+
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/pipeline"
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/sinks/promsink"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func point(metric string, help string, kind pipeline.Kind, qmgr string, obj string) pipeline.Point {
+	return pipeline.Point{
+		Metric:      metric,
+		ObjectType:  pipeline.ObjectQueue,
+		Description: help,
+		Labels:      map[string]string{"qmgr": qmgr, "queue": obj},
+		Value:       1,
+		Kind:        kind,
+		Source:      pipeline.SourceStatus,
+	}
+}
+
+// Two metrics that have been relabelled to the same name can't be in the same family, but
+// the scrape mustn't fail because of them
+func TestWriteFamilyConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		batches [][]pipeline.Point // One batch for each of QM1, QM2, ...
+		series  map[string]int     // How many series each family should have
+	}{
+		{
+			name: "same help and type",
+			batches: [][]pipeline.Point{{
+				point("depth", "Queue depth", pipeline.Gauge, "QM1", "A"),
+				point("depth", "Queue depth", pipeline.Gauge, "QM1", "B"),
+			}},
+			series: map[string]int{"ibmmq_queue_depth": 2},
+		},
+		{
+			name: "different help",
+			batches: [][]pipeline.Point{{
+				point("depth", "Queue depth", pipeline.Gauge, "QM1", "A"),
+				point("depth", "Something else", pipeline.Gauge, "QM1", "B"),
+			}},
+			series: map[string]int{"ibmmq_queue_depth": 1},
+		},
+		{
+			name: "different type",
+			batches: [][]pipeline.Point{{
+				point("depth", "Queue depth", pipeline.Gauge, "QM1", "A"),
+				point("depth", "Queue depth", pipeline.Counter, "QM1", "B"),
+			}},
+			series: map[string]int{"ibmmq_queue_depth": 1},
+		},
+		{
+			name: "conflict with another queue manager",
+			batches: [][]pipeline.Point{
+				{point("depth", "Queue depth", pipeline.Gauge, "QM1", "A")},
+				{point("depth", "Something else", pipeline.Gauge, "QM2", "A"), point("opens", "Opens", pipeline.Gauge, "QM2", "A")},
+			},
+			series: map[string]int{"ibmmq_queue_depth": 1, "ibmmq_queue_opens": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := promsink.New("ibmmq", true)
+			for i, pts := range tt.batches {
+				qmgr := "QM" + string(rune('1'+i))
+				s.Write(&pipeline.Batch{QMgr: qmgr, StatusPolled: true, Points: pts})
+			}
+			// The first queue manager writes again, and keeps what it had
+			if len(tt.batches) > 1 {
+				s.Write(&pipeline.Batch{QMgr: "QM1", StatusPolled: true, Points: tt.batches[0]})
+			}
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(s)
+			mfs, err := reg.Gather()
+			if err != nil {
+				t.Fatalf("Scrape failed: %v", err)
+			}
+			got := make(map[string]int)
+			for _, mf := range mfs {
+				got[mf.GetName()] = len(mf.GetMetric())
+			}
+			for name, n := range tt.series {
+				if got[name] != n {
+					t.Errorf("%s has %d series, expected %d", name, got[name], n)
+				}
+			}
+			for _, mf := range mfs {
+				if mf.GetName() == "ibmmq_queue_depth" && mf.GetHelp() != "Queue depth" {
+					t.Errorf("Wrong series kept, with help %q", mf.GetHelp())
+				}
+			}
+		})
+	}
+}
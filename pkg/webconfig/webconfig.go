@@ -0,0 +1,486 @@
+package webconfig
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+/*
+This package protects an HTTP server that gives out metrics. It reads a "web config" file
+in the same layout as the one used by the Prometheus exporter-toolkit, so an existing file
+can be shared with other exporters:
+
+	tls_server_config:
+	  cert_file: server.crt
+	  key_file: server.key
+	  client_ca_file: ca.crt
+	  client_auth_type: RequireAndVerifyClientCert
+	  min_version: TLS12
+	basic_auth_users:
+	  prometheus: $2y$10$...
+	bearer_tokens:
+	- env:METRICS_TOKEN
+
+The basic_auth_users passwords are bcrypt hashes, as made by "htpasswd -nBC 10 user". The
+bearer_tokens are not in the exporter-toolkit format; they can be the tokens themselves,
+or env:, file: or exec: references. When there are users or tokens, every request has to
+give one of them.
+
+The certificate, key and CA files are read again whenever they change, and so is the web
+config file itself. That allows certificates to be rotated without a restart. A file that
+can't be read, or has errors, is reported and the previous settings are kept. Whether TLS is
+used at all can't be changed without a restart.
+*/
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	cf "github.com/ibm-messaging/mq-metric-samples/v5/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v2"
+)
+
+type fileConfig struct {
+	TLSServerConfig *tlsServerConfig  `yaml:"tls_server_config"`
+	BasicAuthUsers  map[string]string `yaml:"basic_auth_users"`
+	BearerTokens    []string          `yaml:"bearer_tokens"`
+}
+
+type tlsServerConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+	ClientAuthType string `yaml:"client_auth_type"`
+	MinVersion     string `yaml:"min_version"`
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+// Checked against the password given for a user that doesn't exist
+const unknownUserHash = "$2a$10$inWeVJDLRH9pXcwBcFWkie0xl6LSCcXRkqq.qDtvq8khlWNSiRQ5y"
+
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+// A file that we read, and when it was last changed
+type watchedFile struct {
+	name    string
+	modTime time.Time
+	size    int64
+}
+
+// Whether the file is different from when it was last looked at
+func (f *watchedFile) changed() bool {
+	fi, err := os.Stat(f.name)
+	if err != nil {
+		return false
+	}
+	return !fi.ModTime().Equal(f.modTime) || fi.Size() != f.size
+}
+
+// Note the current state of the file, before reading it. If the contents can't be used,
+// we then wait for the next change instead of trying again on every request.
+func (f *watchedFile) seen() {
+	if fi, err := os.Stat(f.name); err == nil {
+		f.modTime = fi.ModTime()
+		f.size = fi.Size()
+	}
+}
+
+// Server holds the current settings, and is shared by all of the connections to the
+// HTTP server
+type Server struct {
+	file      *watchedFile
+	secretTTL time.Duration
+	flagTLS   *tlsServerConfig // From the httpsCertFile and httpsKeyFile options
+	checking  bool             // Only checking the configuration, so the secrets are not looked up
+
+	refreshMutex sync.Mutex // Only one request at a time looks at the files
+
+	mutex     sync.Mutex
+	cfg       *fileConfig
+	tls       bool
+	cert      *tls.Certificate
+	certFiles []*watchedFile // The certificate and its key
+	clientCAs *x509.CertPool
+	caFile    *watchedFile
+	passed    map[string]bool // Basic auth checks that have succeeded, as bcrypt is deliberately slow
+}
+
+/*
+New reads the web config file, if there is one. The certFile and keyFile are the collector's
+own options for a TLS server; they can't be used along with a tls_server_config in the file.
+Everything is checked now so that problems are reported at startup.
+*/
+func New(file string, certFile string, keyFile string, secretTTL time.Duration) (*Server, error) {
+	return newServer(file, certFile, keyFile, secretTTL, false)
+}
+
+/*
+VerifyConfig makes the same checks as New, for when the collector has only been asked to
+check its configuration. The bearer tokens are not looked up, as that might run a command,
+but the references are checked.
+*/
+func VerifyConfig(file string, certFile string, keyFile string) error {
+	_, err := newServer(file, certFile, keyFile, 0, true)
+	return err
+}
+
+func newServer(file string, certFile string, keyFile string, secretTTL time.Duration, checking bool) (*Server, error) {
+	var err error
+
+	s := &Server{secretTTL: secretTTL, checking: checking}
+	cfg := &fileConfig{}
+	if file != "" {
+		s.file = &watchedFile{name: file}
+		cfg, err = s.readFile()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("httpsCertFile and httpsKeyFile must both be set")
+		}
+		s.flagTLS = &tlsServerConfig{CertFile: certFile, KeyFile: keyFile}
+	}
+
+	err = s.addFlagTLS(cfg)
+	if err == nil {
+		s.tls = cfg.TLSServerConfig != nil
+		err = s.apply(cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// The TLS settings can come from the collector's options instead of the file, but not both
+func (s *Server) addFlagTLS(cfg *fileConfig) error {
+	if s.flagTLS == nil {
+		return nil
+	}
+	if cfg.TLSServerConfig != nil {
+		return fmt.Errorf("%s has a tls_server_config, so the httpsCertFile and httpsKeyFile options cannot also be used", s.file.name)
+	}
+	cfg.TLSServerConfig = s.flagTLS
+	return nil
+}
+
+// UsingTLS says whether the server should be started with TLS
+func (s *Server) UsingTLS() bool {
+	return s.tls
+}
+
+// Read and check the web config file. The file is strict about unknown keys, as a
+// mistyped name would otherwise leave the endpoint unprotected.
+func (s *Server) readFile() (*fileConfig, error) {
+	s.file.seen()
+	data, err := os.ReadFile(s.file.name)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &fileConfig{}
+	err = yaml.UnmarshalStrict(data, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", s.file.name, err)
+	}
+
+	for user, hash := range cfg.BasicAuthUsers {
+		if _, err = bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("%s: the password for user %s is not a bcrypt hash: %v", s.file.name, user, err)
+		}
+	}
+	for i, t := range cfg.BearerTokens {
+		v := t
+		if s.checking {
+			err = cf.CheckSecretRef(t)
+		} else {
+			v, err = cf.ResolveSecret(t, s.secretTTL)
+		}
+		if err != nil || v == "" {
+			return nil, fmt.Errorf("%s: bearer_tokens entry %d cannot be used: %v", s.file.name, i, err)
+		}
+	}
+	if t := cfg.TLSServerConfig; t != nil {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, fmt.Errorf("%s: the tls_server_config needs a cert_file and a key_file", s.file.name)
+		}
+		if _, ok := clientAuthTypes[t.ClientAuthType]; !ok && t.ClientAuthType != "" {
+			return nil, fmt.Errorf("%s: unknown client_auth_type %s", s.file.name, t.ClientAuthType)
+		}
+		if _, ok := tlsVersions[t.MinVersion]; !ok && t.MinVersion != "" {
+			return nil, fmt.Errorf("%s: unknown min_version %s", s.file.name, t.MinVersion)
+		}
+		if t.ClientCAFile == "" && strings.Contains(t.ClientAuthType, "Verify") {
+			return nil, fmt.Errorf("%s: client_auth_type %s needs a client_ca_file", s.file.name, t.ClientAuthType)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Load the certificates named by a new configuration, and switch over to it
+func (s *Server) apply(cfg *fileConfig) error {
+	var cert *tls.Certificate
+	var certFiles []*watchedFile
+	var clientCAs *x509.CertPool
+	var caFile *watchedFile
+	var err error
+
+	if t := cfg.TLSServerConfig; t != nil {
+		certFiles = []*watchedFile{{name: t.CertFile}, {name: t.KeyFile}}
+		cert, err = loadCertificate(certFiles)
+		if err == nil && t.ClientCAFile != "" {
+			caFile = &watchedFile{name: t.ClientCAFile}
+			clientCAs, err = loadCAs(caFile)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.cfg = cfg
+	s.cert = cert
+	s.certFiles = certFiles
+	s.clientCAs = clientCAs
+	s.caFile = caFile
+	s.passed = make(map[string]bool)
+	s.mutex.Unlock()
+	return nil
+}
+
+func loadCertificate(files []*watchedFile) (*tls.Certificate, error) {
+	for _, f := range files {
+		f.seen()
+	}
+	cert, err := tls.LoadX509KeyPair(files[0].name, files[1].name)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+func loadCAs(f *watchedFile) (*x509.CertPool, error) {
+	f.seen()
+	data, err := os.ReadFile(f.name)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", f.name)
+	}
+	return pool, nil
+}
+
+/*
+refresh picks up any changes to the files. It's called for each request and each TLS
+handshake, which costs no more than a few stat calls when nothing has changed. Failures
+are logged and the previous settings kept, as it's better to carry on with the old
+certificate than to stop serving altogether.
+*/
+func (s *Server) refresh() {
+	s.refreshMutex.Lock()
+	defer s.refreshMutex.Unlock()
+
+	if s.file != nil {
+		if s.file.changed() {
+			log.Infof("Reloading web configuration from %s", s.file.name)
+			cfg, err := s.readFile()
+			if err == nil {
+				err = s.addFlagTLS(cfg)
+			}
+			if err == nil {
+				if (cfg.TLSServerConfig != nil) != s.tls {
+					log.Warnf("%s: TLS cannot be switched on or off without a restart", s.file.name)
+					cfg.TLSServerConfig = s.currentConfig().TLSServerConfig
+				}
+				err = s.apply(cfg)
+			}
+			if err != nil {
+				log.Errorf("Cannot reload web configuration: %v", err)
+			}
+			return
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	certChanged := false
+	for _, f := range s.certFiles {
+		if f.changed() {
+			certChanged = true
+		}
+	}
+	if certChanged {
+		log.Infof("Reloading server certificate from %s", s.certFiles[0].name)
+		if cert, err := loadCertificate(s.certFiles); err == nil {
+			s.cert = cert
+		} else {
+			log.Errorf("Cannot reload server certificate: %v", err)
+		}
+	}
+	if s.caFile != nil {
+		if s.caFile.changed() {
+			log.Infof("Reloading client CA certificates from %s", s.caFile.name)
+			if pool, err := loadCAs(s.caFile); err == nil {
+				s.clientCAs = pool
+			} else {
+				log.Errorf("Cannot reload client CA certificates: %v", err)
+			}
+		}
+	}
+}
+
+func (s *Server) currentConfig() *fileConfig {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.cfg
+}
+
+/*
+TLSConfig gives the configuration for the http.Server. The settings are worked out again for
+each new connection, so that they follow any changes to the files.
+*/
+func (s *Server) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Not normally used, but it tells the http.Server that there is a certificate
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			return s.cert, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			s.refresh()
+
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+
+			t := s.cfg.TLSServerConfig
+			c := &tls.Config{
+				Certificates: []tls.Certificate{*s.cert},
+				MinVersion:   tls.VersionTLS12,
+				ClientCAs:    s.clientCAs,
+			}
+			if v, ok := tlsVersions[t.MinVersion]; ok {
+				c.MinVersion = v
+			}
+			if a, ok := clientAuthTypes[t.ClientAuthType]; ok {
+				c.ClientAuth = a
+			} else if s.clientCAs != nil {
+				c.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return c, nil
+		},
+	}
+}
+
+/*
+Wrap gives a handler that checks the credentials on each request before passing it on. When
+there are no users or tokens configured, every request is passed on.
+*/
+func (s *Server) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.refresh()
+
+		cfg := s.currentConfig()
+		if len(cfg.BasicAuthUsers) == 0 && len(cfg.BearerTokens) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if s.authorized(cfg, r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		log.Debugf("Unauthorized request for %s from %s", r.URL.Path, r.RemoteAddr)
+		if len(cfg.BasicAuthUsers) > 0 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="IBM MQ metrics"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+func (s *Server) authorized(cfg *fileConfig, r *http.Request) bool {
+	if user, pass, ok := r.BasicAuth(); ok {
+		hash, ok := cfg.BasicAuthUsers[user]
+		if !ok {
+			// Do the same amount of work for an unknown user, so that the time taken
+			// doesn't say which users exist
+			_ = bcrypt.CompareHashAndPassword([]byte(unknownUserHash), []byte(pass))
+			return false
+		}
+
+		sum := sha256.Sum256([]byte(user + ":" + hash + ":" + pass))
+		key := hex.EncodeToString(sum[:])
+		s.mutex.Lock()
+		passed := s.passed[key]
+		s.mutex.Unlock()
+		if passed {
+			return true
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) != nil {
+			return false
+		}
+		s.mutex.Lock()
+		s.passed[key] = true
+		s.mutex.Unlock()
+		return true
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && token != "" {
+		for _, t := range cfg.BearerTokens {
+			v, err := cf.ResolveSecret(t, s.secretTTL)
+			if err != nil {
+				log.Warnf("Cannot look up bearer token: %v", err)
+				continue
+			}
+			if v != "" && subtle.ConstantTimeCompare([]byte(v), []byte(token)) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
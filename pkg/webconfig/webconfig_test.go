@@ -0,0 +1,330 @@
+package webconfig_test
+
+/*
+  Copyright (c) IBM Corporation 2026
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific
+
+   Contributors:
+     Mark Taylor - Initial Contribution
+*/
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-metric-samples/v5/pkg/webconfig"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeFile(t *testing.T, dir string, name string, data string) string {
+	t.Helper()
+	f := filepath.Join(dir, name)
+	if err := os.WriteFile(f, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func hash(t *testing.T, pass string) string {
+	t.Helper()
+	h, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(h)
+}
+
+// Write a self-signed certificate and its key, which can also be used as its own CA
+func writeCert(t *testing.T, dir string, name string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := writeFile(t, dir, name+".crt", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	keyFile := writeFile(t, dir, name+".key", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})))
+	return certFile, keyFile
+}
+
+func TestConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := writeCert(t, dir, "server")
+
+	tests := []struct {
+		name     string
+		config   string
+		certFile string
+		keyFile  string
+		err      string
+	}{
+		{name: "empty", config: ""},
+		{name: "unknown key", config: "basic_auth_user:\n  a: b\n", err: "cannot parse"},
+		{name: "plain password", config: "basic_auth_users:\n  prometheus: secret\n", err: "password for user prometheus is not a bcrypt hash"},
+		{name: "unset token", config: "bearer_tokens:\n- env:MQ_TEST_NO_TOKEN\n", err: "bearer_tokens entry 0 cannot be used"},
+		{name: "empty token", config: "bearer_tokens:\n- \"\"\n", err: "bearer_tokens entry 0 cannot be used"},
+		{name: "no key file", config: "tls_server_config:\n  cert_file: " + cert + "\n", err: "needs a cert_file and a key_file"},
+		{
+			name:   "unknown client auth",
+			config: "tls_server_config:\n  cert_file: " + cert + "\n  key_file: " + key + "\n  client_auth_type: Sometimes\n",
+			err:    "unknown client_auth_type Sometimes",
+		},
+		{
+			name:   "unknown version",
+			config: "tls_server_config:\n  cert_file: " + cert + "\n  key_file: " + key + "\n  min_version: TLS14\n",
+			err:    "unknown min_version TLS14",
+		},
+		{
+			name:   "verify without a CA",
+			config: "tls_server_config:\n  cert_file: " + cert + "\n  key_file: " + key + "\n  client_auth_type: RequireAndVerifyClientCert\n",
+			err:    "needs a client_ca_file",
+		},
+		{
+			name:   "missing certificate",
+			config: "tls_server_config:\n  cert_file: " + filepath.Join(dir, "none.crt") + "\n  key_file: " + key + "\n",
+			err:    "no such file",
+		},
+		{
+			name:     "TLS in file and flags",
+			config:   "tls_server_config:\n  cert_file: " + cert + "\n  key_file: " + key + "\n",
+			certFile: cert,
+			keyFile:  key,
+			err:      "cannot also be used",
+		},
+		{name: "only a cert flag", certFile: cert, err: "must both be set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := writeFile(t, t.TempDir(), "web.yaml", tt.config)
+			_, err := webconfig.New(f, tt.certFile, tt.keyFile, 0)
+			if tt.err == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("Expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// Checking the configuration mustn't run a command to get a token
+func TestVerifyConfig(t *testing.T) {
+	dir := t.TempDir()
+	ran := filepath.Join(dir, "ran")
+	script := writeFile(t, dir, "token.sh", "#!/bin/sh\ntouch "+ran+"\necho token\n")
+	os.Chmod(script, 0700)
+
+	f := writeFile(t, dir, "web.yaml", "bearer_tokens:\n- exec:"+script+"\n")
+	if err := webconfig.VerifyConfig(f, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(ran); err == nil {
+		t.Error("Token command was run")
+	}
+
+	f = writeFile(t, dir, "bad.yaml", "bearer_tokens:\n- \"exec: \"\n")
+	if err := webconfig.VerifyConfig(f, "", ""); err == nil {
+		t.Error("Bad token reference was accepted")
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	t.Setenv("MQ_TEST_TOKEN", "tok3n")
+	dir := t.TempDir()
+	open := writeFile(t, dir, "open.yaml", "")
+	secured := writeFile(t, dir, "web.yaml",
+		"basic_auth_users:\n  prometheus: "+hash(t, "pass1")+"\nbearer_tokens:\n- env:MQ_TEST_TOKEN\n- plaintoken\n")
+	tokens := writeFile(t, dir, "tokens.yaml", "bearer_tokens:\n- plaintoken\n")
+
+	tests := []struct {
+		name      string
+		file      string
+		user      string
+		pass      string
+		bearer    string
+		status    int
+		challenge bool
+	}{
+		{name: "nothing configured", file: open, status: http.StatusOK},
+		{name: "no credentials", file: secured, status: http.StatusUnauthorized, challenge: true},
+		{name: "good password", file: secured, user: "prometheus", pass: "pass1", status: http.StatusOK},
+		{name: "good password again", file: secured, user: "prometheus", pass: "pass1", status: http.StatusOK},
+		{name: "bad password", file: secured, user: "prometheus", pass: "pass2", status: http.StatusUnauthorized, challenge: true},
+		{name: "unknown user", file: secured, user: "grafana", pass: "pass1", status: http.StatusUnauthorized, challenge: true},
+		{name: "token reference", file: secured, bearer: "tok3n", status: http.StatusOK},
+		{name: "plain token", file: secured, bearer: "plaintoken", status: http.StatusOK},
+		{name: "bad token", file: secured, bearer: "tok3", status: http.StatusUnauthorized, challenge: true},
+		{name: "token is not a password", file: secured, user: "prometheus", pass: "tok3n", status: http.StatusUnauthorized, challenge: true},
+		{name: "only tokens", file: tokens, status: http.StatusUnauthorized},
+	}
+
+	servers := make(map[string]http.Handler)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, ok := servers[tt.file]
+			if !ok {
+				s, err := webconfig.New(tt.file, "", "", 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				h = s.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("metrics"))
+				}))
+				servers[tt.file] = h
+			}
+
+			r := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.user != "" {
+				r.SetBasicAuth(tt.user, tt.pass)
+			}
+			if tt.bearer != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("Status is %d, expected %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("WWW-Authenticate") != ""; got != tt.challenge {
+				t.Errorf("WWW-Authenticate header is %q", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+// A change to the file is picked up by the next request, and a broken file leaves the old settings in place
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	f := writeFile(t, dir, "web.yaml", "bearer_tokens:\n- one\n")
+	s, err := webconfig.New(f, "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := s.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	status := func(token string) int {
+		r := httptest.NewRequest("GET", "/metrics", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	rewrite := func(data string, age time.Duration) {
+		writeFile(t, dir, "web.yaml", data)
+		mt := time.Now().Add(age)
+		os.Chtimes(f, mt, mt)
+	}
+
+	if status("one") != http.StatusOK || status("two") != http.StatusUnauthorized {
+		t.Fatal("Wrong token accepted at the start")
+	}
+	rewrite("bearer_tokens:\n- two\n", time.Second)
+	if status("two") != http.StatusOK || status("one") != http.StatusUnauthorized {
+		t.Error("New token not used after reload")
+	}
+	rewrite("bearer_tokens: [", 2*time.Second)
+	if status("two") != http.StatusOK {
+		t.Error("Previous token not kept when the file is broken")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey := writeCert(t, dir, "server")
+	clientCert, clientKey := writeCert(t, dir, "client")
+	otherCert, otherKey := writeCert(t, dir, "other")
+
+	f := writeFile(t, dir, "web.yaml", "tls_server_config:\n  cert_file: "+serverCert+"\n  key_file: "+serverKey+
+		"\n  client_ca_file: "+clientCert+"\n  client_auth_type: RequireAndVerifyClientCert\n  min_version: TLS13\n")
+	s, err := webconfig.New(f, "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.UsingTLS() {
+		t.Fatal("Not using TLS")
+	}
+
+	ts := httptest.NewUnstartedServer(s.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	ts.TLS = s.TLSConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	caData, _ := os.ReadFile(serverCert)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caData)
+
+	get := func(certFile string, keyFile string, maxVersion uint16) error {
+		cfg := &tls.Config{RootCAs: roots, MaxVersion: maxVersion}
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.Certificates = []tls.Certificate{cert}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		resp, err := client.Get(ts.URL + "/metrics")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Status is %d", resp.StatusCode)
+			}
+		}
+		return err
+	}
+
+	if err := get(clientCert, clientKey, 0); err != nil {
+		t.Errorf("Trusted client certificate was rejected: %v", err)
+	}
+	if err := get("", "", 0); err == nil {
+		t.Error("Request without a client certificate was accepted")
+	}
+	if err := get(otherCert, otherKey, 0); err == nil {
+		t.Error("Untrusted client certificate was accepted")
+	}
+	if err := get(clientCert, clientKey, tls.VersionTLS12); err == nil {
+		t.Error("TLS 1.2 was accepted")
+	}
+}
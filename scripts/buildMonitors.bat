@@ -48,4 +48,10 @@ go build -mod=vendor -o bin/%%M.exe %D%\%%M\config.go %D%\%%M\main.go %D%\%%M\re
 type %R%\config.common.yaml %D%\%%M\config.collector.yaml > bin\%%M.yaml 2>NUL:
 )
 
+for %%M in (mq_multi) do (
+echo Building %%M
+go build -mod=vendor -o bin/%%M.exe %D%\%%M\config.go %D%\%%M\main.go
+type %R%\config.common.yaml %D%\%%M\config.collector.yaml > bin\%%M.yaml 2>NUL:
+)
+
 endlocal